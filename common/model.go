@@ -23,7 +23,6 @@ type Model struct {
 	Form               *huh.Form
 	ConfirmationDialog ConfirmationDialog
 	Focused            string
-	IsPlaying          bool
 }
 
 func (m Model) Init() tea.Cmd {
@@ -69,3 +69,15 @@ func (d *ConfirmationDialog) View() string {
 	}
 	return "Do you really want to exit? (y/n)"
 }
+
+// QuizPlayerClosedMsg is a message used to signal that the player left the quiz results screen.
+type QuizPlayerClosedMsg struct{}
+
+// PendingOperationsClosedMsg is a message used to signal that the pending changes screen was left.
+type PendingOperationsClosedMsg struct{}
+
+// SetupClosedMsg is a message used to signal that the setup screen was left.
+type SetupClosedMsg struct{}
+
+// ImportQuizClosedMsg is a message used to signal that the quiz import screen was left.
+type ImportQuizClosedMsg struct{}
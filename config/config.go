@@ -1,56 +1,234 @@
 package config
 
 import (
-	"github.com/spf13/viper"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
-type appConfig struct {
-	LoggingEnabled bool   `mapstructure:"logging_enabled"`
-	LogFile        string `mapstructure:"log_file"`
-	OktaIssuer     string `mapstructure:"OKTA_ISSUER"`
-	OktaClientID   string `mapstructure:"OKTA_CLIENT_ID"`
-	EnableOktaAuth bool   `mapstructure:"ENABLE_OKTA_AUTH"`
-	RateLimit      int    `mapstructure:"RATE_LIMIT"`
-	BackendURL     string `mapstructure:"BACKEND_URL"`
-	MainMp3Track   string `mapstructure:"MAIN_MP3_TRACK"`
-}
+// Kind selects which binary's configuration is loaded
+type Kind int
 
-type dbConfig struct {
+const (
+	// App is the TUI client configuration, read from appconfig.json into AppConfig
+	App Kind = iota
+	// Server is the backend server configuration, read from dbconfig.json into DbConfig
+	Server
+)
+
+// EnvPrefix is the prefix of environment variables overriding configuration keys, e.g. LETSQUIZ_BACKEND_URL
+const EnvPrefix = "LETSQUIZ"
+
+// commonConfig holds the settings shared by both binaries
+type commonConfig struct {
 	LoggingEnabled bool   `mapstructure:"logging_enabled"`
 	LogFile        string `mapstructure:"log_file"`
-	DbType         string `mapstructure:"db_type"`
-	DbDsn          string `mapstructure:"db_dsn"`
 	OktaIssuer     string `mapstructure:"okta_issuer"`
 	OktaClientID   string `mapstructure:"okta_client_id"`
 	EnableOktaAuth bool   `mapstructure:"enable_okta_auth"`
 	RateLimit      int    `mapstructure:"rate_limit"`
+	TraceExporter  string `mapstructure:"trace_exporter"`
+	TraceFile      string `mapstructure:"trace_file"`
+	OtlpEndpoint   string `mapstructure:"otlp_endpoint"`
+	LogLevel       string `mapstructure:"log_level"`
+	Environment    string `mapstructure:"environment"`
+
+	// Log output settings, see the logger package
+	LogFormat     string   `mapstructure:"log_format"`
+	LogMaxSizeMB  int      `mapstructure:"log_max_size_mb"`
+	LogMaxAgeDays int      `mapstructure:"log_max_age_days"`
+	LogMaxBackups int      `mapstructure:"log_max_backups"`
+	LogCompress   bool     `mapstructure:"log_compress"`
+	LogRedactKeys []string `mapstructure:"log_redact_keys"`
+}
+
+type appConfig struct {
+	commonConfig `mapstructure:",squash"`
+	BackendURL   string `mapstructure:"backend_url"`
+	MainMp3Track string `mapstructure:"main_mp3_track"`
+	AudioBackend string `mapstructure:"audio_backend"`
+
+	// Background music, see the music package
+	MusicPlaylist   []string `mapstructure:"music_playlist"`
+	MusicShuffle    bool     `mapstructure:"music_shuffle"`
+	SoundEffects    bool     `mapstructure:"sound_effects"`
+	SoundEffectsDir string   `mapstructure:"sound_effects_dir"`
+
+	// Question audio clips, 0 allows unlimited plays
+	AudioClipMaxPlays int `mapstructure:"audio_clip_max_plays"`
+
+	// Question images, see the termimage package
+	ImageProtocol string `mapstructure:"image_protocol"`
+
+	// Quiz editor drafts, empty for letsquiz/drafts in the user's data directory
+	DraftsDir string `mapstructure:"drafts_dir"`
+
+	// Changes waiting for the backend, empty for letsquiz/outbox.json in the user's data directory, see the outbox package
+	OutboxFile string `mapstructure:"outbox_file"`
+
+	// Offline mode runs the backend in process, also chosen by an empty backend_url, see the offline package.
+	// The SQLite database defaults to letsquiz/offline.db in the user's data directory.
+	Offline   bool   `mapstructure:"offline"`
+	OfflineDB string `mapstructure:"offline_db"`
+}
+
+type dbConfig struct {
+	commonConfig `mapstructure:",squash"`
+	DbType       string `mapstructure:"db_type"`
+	DbDsn        string `mapstructure:"db_dsn" secret:"true"`
+
+	// HTTP server settings, see ValidateServer
+	ListenAddr         string        `mapstructure:"listen_addr"`
+	TLSCertFile        string        `mapstructure:"tls_cert_file"`
+	TLSKeyFile         string        `mapstructure:"tls_key_file"`
+	ReadTimeout        time.Duration `mapstructure:"read_timeout"`
+	ReadHeaderTimeout  time.Duration `mapstructure:"read_header_timeout"`
+	WriteTimeout       time.Duration `mapstructure:"write_timeout"`
+	IdleTimeout        time.Duration `mapstructure:"idle_timeout"`
+	MaxHeaderBytes     int           `mapstructure:"max_header_bytes"`
+	MaxBodyBytes       int64         `mapstructure:"max_body_bytes"`
+	CorsAllowedOrigins []string      `mapstructure:"cors_allowed_origins"`
+	Compression        []string      `mapstructure:"compression"`
+	IdempotencyTTL     time.Duration `mapstructure:"idempotency_ttl"` // how long responses are kept for Idempotency-Key retries
+
+	// Media uploads, see the storage package and ValidateMedia
+	MediaStorage      string   `mapstructure:"media_storage"`
+	MediaDir          string   `mapstructure:"media_dir"`
+	MaxMediaBytes     int64    `mapstructure:"max_media_bytes"`
+	MediaAllowedTypes []string `mapstructure:"media_allowed_types"`
+	S3Endpoint        string   `mapstructure:"s3_endpoint"`
+	S3Region          string   `mapstructure:"s3_region"`
+	S3Bucket          string   `mapstructure:"s3_bucket"`
+	S3AccessKeyID     string   `mapstructure:"s3_access_key_id"`
+	S3SecretAccessKey string   `mapstructure:"s3_secret_access_key" secret:"true"`
+	S3PathStyle       bool     `mapstructure:"s3_path_style"`
 }
 
 var AppConfig appConfig
 
 var DbConfig dbConfig
 
-func LoadConfig(filePath string, db bool) error {
-	log.Println("Loading config file...", "db:", db, " and filePath:", filePath)
-	viper.SetConfigFile(filePath)
-	err := viper.ReadInConfig()
-	if err != nil {
-		log.Println("err loading config file:", err)
-		return err
+// UsedConfigFile is the configuration file that was loaded, empty when none was found
+var UsedConfigFile string
+
+// target returns the configuration struct, file name and defaults for a binary
+func (k Kind) target() (cfg interface{ Validate() error }, fileName string, defaults map[string]interface{}) {
+	if k == Server {
+		return &DbConfig, "dbconfig", serverDefaults()
+	}
+	return &AppConfig, "appconfig", appDefaults()
+}
+
+func appDefaults() map[string]interface{} {
+	return map[string]interface{}{
+		"logging_enabled":   true,
+		"log_file":          "app.log",
+		"environment":       "development",
+		"log_max_size_mb":   100,
+		"log_max_age_days":  28,
+		"log_max_backups":   5,
+		"rate_limit":        100,
+		"backend_url":       "http://localhost:8086",
+		"main_mp3_track":    "sir-karl-jenkins-palladio-motquiz",
+		"audio_backend":     "oto",
+		"sound_effects":     true,
+		"sound_effects_dir": "sounds",
+
+		"audio_clip_max_plays": 3,
+		"image_protocol":       "auto",
+	}
+}
+
+// searchPaths returns the directories searched for a configuration file, in order
+func searchPaths() []string {
+	paths := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "letsquiz"))
+	}
+	return append(paths, "/etc/letsquiz")
+}
+
+// Load reads the configuration of the given binary with the precedence
+// defaults < config file < LETSQUIZ_* environment variables < command line flags,
+// validates it and returns the remaining positional arguments.
+func Load(kind Kind, args []string) ([]string, error) {
+	cfg, fileName, defaults := kind.target()
+
+	v := viper.New()
+	keys := settings(cfg)
+	for _, s := range keys {
+		v.SetDefault(s.key, reflect.Zero(s.field.Type).Interface()) // every key must be known to viper for env overrides to apply
+	}
+	for key, value := range defaults {
+		v.SetDefault(key, value)
 	}
 
-	if !db {
-		err = viper.Unmarshal(&AppConfig)
-		log.Println("AppConfig:", AppConfig)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	flags := pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(EnvPrefix+"_CONFIG"), "path to the configuration file")
+	registerFlags(flags, cfg)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	for _, s := range keys {
+		if err := v.BindPFlag(s.key, flags.Lookup(flagName(s.key))); err != nil {
+			return nil, err
+		}
+	}
+
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
 	} else {
-		err = viper.Unmarshal(&DbConfig)
-		log.Println("DbConfig:", DbConfig)
+		v.SetConfigName(fileName)
+		v.SetConfigType("json")
+		for _, path := range searchPaths() {
+			v.AddConfigPath(path)
+		}
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			log.Println("err loading config file:", err)
+			return nil, err
+		}
+		log.Println("No config file found, using defaults, environment and flags", "file", fileName+".json", "searched", searchPaths())
 	}
+	UsedConfigFile = v.ConfigFileUsed()
 
-	if err != nil {
+	if err := v.Unmarshal(cfg); err != nil {
 		log.Println("err unmarshalling config file:", err)
-		return err
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Println("invalid configuration:", err)
+		return nil, fmt.Errorf("invalid configuration in %s: %w", describeSource(), err)
+	}
+
+	log.Println("Loaded config", "file", UsedConfigFile, "settings", Redacted(cfg))
+	loaderMu.Lock()
+	loaders[kind] = v
+	loaderMu.Unlock()
+	if kind == Server {
+		live.Store(copyDbConfig(DbConfig))
+	}
+	return flags.Args(), nil
+}
+
+func describeSource() string {
+	if UsedConfigFile == "" {
+		return "defaults, environment and flags"
 	}
-	return nil
+	return UsedConfigFile
 }
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// redactedValue replaces the value of settings tagged secret:"true" when logged or printed
+const redactedValue = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting describes one configuration key of a config struct
+type setting struct {
+	key   string
+	field reflect.StructField
+	index []int
+}
+
+// settings returns the configuration keys of cfg in declaration order, flattening squashed embedded structs
+func settings(cfg interface{}) []setting {
+	t := reflect.TypeOf(cfg)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return collectSettings(t, nil)
+}
+
+func collectSettings(t reflect.Type, parent []int) []setting {
+	var result []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag := field.Tag.Get("mapstructure")
+		if field.Anonymous && strings.Contains(tag, "squash") {
+			result = append(result, collectSettings(field.Type, index)...)
+			continue
+		}
+		if tag != "" {
+			result = append(result, setting{key: tag, field: field, index: index})
+		}
+	}
+	return result
+}
+
+// flagName converts a configuration key to its command line flag, e.g. backend_url -> --backend-url
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// registerFlags adds one flag per configuration key, typed after the struct field
+func registerFlags(flags *pflag.FlagSet, cfg interface{}) {
+	for _, s := range settings(cfg) {
+		name := flagName(s.key)
+		usage := "overrides the " + s.key + " setting"
+		switch t := s.field.Type; {
+		case t == durationType:
+			flags.Duration(name, 0, usage)
+		case t.Kind() == reflect.String:
+			flags.String(name, "", usage)
+		case t.Kind() == reflect.Bool:
+			flags.Bool(name, false, usage)
+		case t.Kind() == reflect.Int:
+			flags.Int(name, 0, usage)
+		case t.Kind() == reflect.Int64:
+			flags.Int64(name, 0, usage)
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+			flags.StringSlice(name, nil, usage)
+		}
+	}
+}
+
+// Redacted returns the settings of cfg keyed by configuration key, with secrets replaced
+func Redacted(cfg interface{}) map[string]interface{} {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	result := make(map[string]interface{})
+	for _, s := range settings(cfg) {
+		field := v.FieldByIndex(s.index)
+		value := field.Interface()
+		if s.field.Tag.Get("secret") == "true" && !field.IsZero() {
+			value = redactedValue
+		} else if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		result[s.key] = value
+	}
+	return result
+}
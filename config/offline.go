@@ -0,0 +1,35 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// OfflineMode reports whether the application runs without a backend server
+func (c appConfig) OfflineMode() bool {
+	return c.Offline || c.BackendURL == ""
+}
+
+// UseOfflineServer sets DbConfig for the backend run in process in offline mode: the server defaults
+// with the SQLite database dbFile, and media kept in dir
+func UseOfflineServer(dbFile, dir string) error {
+	v := viper.New()
+	for key, value := range serverDefaults() {
+		v.SetDefault(key, value)
+	}
+	v.Set("db_type", "sqlite")
+	v.Set("db_dsn", dbFile)
+	v.Set("media_dir", filepath.Join(dir, "media"))
+
+	var cfg dbConfig
+	if err := v.Unmarshal(&cfg); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	DbConfig = cfg
+	live.Store(copyDbConfig(DbConfig))
+	return nil
+}
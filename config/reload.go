@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadableKeys lists the server settings that can change without a restart.
+// Any other changed key makes Reload refuse the new configuration.
+var reloadableKeys = map[string]bool{
+	"rate_limit":           true,
+	"log_level":            true,
+	"log_redact_keys":      true,
+	"cors_allowed_origins": true,
+	"idempotency_ttl":      true,
+	"enable_okta_auth":     true,
+	"okta_issuer":          true,
+	"okta_client_id":       true,
+}
+
+var (
+	loaderMu sync.Mutex
+	loaders  = map[Kind]*viper.Viper{} // viper instances set up by Load, reused to reload
+	live     atomic.Pointer[dbConfig]
+)
+
+// LiveDbConfig returns the current server configuration, including hot-reloaded settings.
+// DbConfig keeps the configuration the server was started with.
+func LiveDbConfig() *dbConfig {
+	if cfg := live.Load(); cfg != nil {
+		return cfg
+	}
+	return &DbConfig
+}
+
+// ReloadServer re-reads the server configuration and atomically swaps it in.
+// It returns the changed keys, or an error when the new configuration is invalid
+// or changes settings that need a restart, in which case nothing is applied.
+// Reloads are serialized by loaderMu, the file watcher and SIGHUP both call it.
+func ReloadServer() ([]string, error) {
+	loaderMu.Lock()
+	defer loaderMu.Unlock()
+
+	v, ok := loaders[Server]
+	if !ok {
+		return nil, fmt.Errorf("server configuration has not been loaded")
+	}
+	if v.ConfigFileUsed() != "" {
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	next := &dbConfig{}
+	if err := v.Unmarshal(next); err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	current := LiveDbConfig()
+	changed := changedKeys(current, next)
+	var restartOnly []string
+	for _, key := range changed {
+		if !reloadableKeys[key] {
+			restartOnly = append(restartOnly, key)
+		}
+	}
+	if len(restartOnly) > 0 {
+		return changed, fmt.Errorf("refusing reload, these settings need a restart: %s", strings.Join(restartOnly, ", "))
+	}
+
+	live.Store(next)
+	return changed, nil
+}
+
+// WatchServer reloads the server configuration whenever its file changes and
+// reports the outcome to onReload. It does not use viper's WatchConfig, which re-reads
+// the file on its own goroutine outside loaderMu and so races with SIGHUP reloads;
+// every read goes through ReloadServer instead.
+func WatchServer(onReload func(changed []string, err error)) {
+	loaderMu.Lock()
+	v, ok := loaders[Server]
+	file := ""
+	if ok {
+		file = v.ConfigFileUsed()
+	}
+	loaderMu.Unlock()
+	if file == "" {
+		log.Println("No server config file loaded, not watching for changes")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("Cannot watch the server config file:", err)
+		return
+	}
+	// Watch the directory, editors often replace the file rather than write to it
+	file = filepath.Clean(file)
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		log.Println("Cannot watch the server config file:", err)
+		watcher.Close()
+		return
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				changed, err := ReloadServer()
+				onReload(changed, err)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("Error watching the server config file:", err)
+			}
+		}
+	}()
+}
+
+// changedKeys returns the sorted configuration keys whose values differ between a and b
+func changedKeys(a, b *dbConfig) []string {
+	before, after := Redacted(a), Redacted(b)
+	// Compare secrets by value, not by their redacted placeholder
+	for _, s := range settings(a) {
+		if s.field.Tag.Get("secret") == "true" {
+			before[s.key] = reflect.ValueOf(a).Elem().FieldByIndex(s.index).Interface()
+			after[s.key] = reflect.ValueOf(b).Elem().FieldByIndex(s.index).Interface()
+		}
+	}
+
+	var changed []string
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func copyDbConfig(c dbConfig) *dbConfig {
+	return &c
+}
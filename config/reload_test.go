@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeServerConfig writes a valid server configuration file with the given overrides
+func writeServerConfig(t *testing.T, file string, overrides map[string]interface{}) {
+	t.Helper()
+	settings := map[string]interface{}{"db_type": "sqlite", "db_dsn": "letsquiz.db", "rate_limit": 10}
+	for key, value := range overrides {
+		settings[key] = value
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadServerConfig(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "dbconfig.json")
+	writeServerConfig(t, file, nil)
+	if _, err := Load(Server, []string{"--config", file}); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return file
+}
+
+func TestReloadServer(t *testing.T) {
+	file := loadServerConfig(t)
+
+	writeServerConfig(t, file, map[string]interface{}{"rate_limit": 20})
+	changed, err := ReloadServer()
+	if err != nil {
+		t.Fatalf("ReloadServer: %v", err)
+	}
+	if want := []string{"rate_limit"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if got := LiveDbConfig().RateLimit; got != 20 {
+		t.Errorf("live rate_limit = %d, want 20", got)
+	}
+	if DbConfig.RateLimit != 10 {
+		t.Errorf("DbConfig.RateLimit = %d, want the startup value 10", DbConfig.RateLimit)
+	}
+
+	// Settings that need a restart are refused along with the rest of the file
+	writeServerConfig(t, file, map[string]interface{}{"rate_limit": 30, "db_dsn": "other.db"})
+	if _, err := ReloadServer(); err == nil {
+		t.Error("ReloadServer accepted a changed db_dsn")
+	}
+	if got := LiveDbConfig().RateLimit; got != 20 {
+		t.Errorf("live rate_limit = %d after a refused reload, want 20", got)
+	}
+
+	writeServerConfig(t, file, map[string]interface{}{"rate_limit": -1})
+	if _, err := ReloadServer(); err == nil {
+		t.Error("ReloadServer accepted an invalid rate_limit")
+	}
+}
+
+// TestReloadServerConcurrently reloads from the file watcher and directly, as on SIGHUP, at the same time.
+// Run with -race: both must only read the configuration through ReloadServer.
+func TestReloadServerConcurrently(t *testing.T) {
+	file := loadServerConfig(t)
+
+	var mu sync.Mutex
+	watched := 0
+	WatchServer(func(changed []string, err error) {
+		mu.Lock()
+		watched++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			writeServerConfig(t, file, map[string]interface{}{"rate_limit": 11 + i})
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			ReloadServer()
+			time.Sleep(3 * time.Millisecond)
+		}
+	}()
+	wg.Wait()
+
+	// The watcher reports the last write eventually
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := ReloadServer(); err == nil && LiveDbConfig().RateLimit == 30 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("live rate_limit = %d, want 30", LiveDbConfig().RateLimit)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if watched == 0 {
+		t.Error("the file watcher never reloaded the configuration")
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported response compression encodings
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Supported media storage backends
+const (
+	MediaStorageLocal = "local"
+	MediaStorageS3    = "s3"
+)
+
+// serverDefaults returns the defaults for dbconfig.json
+func serverDefaults() map[string]interface{} {
+	return map[string]interface{}{
+		"logging_enabled":     true,
+		"log_file":            "db.log",
+		"environment":         "development",
+		"log_max_size_mb":     100,
+		"log_max_age_days":    28,
+		"log_max_backups":     5,
+		"db_type":             "mysql",
+		"rate_limit":          100,
+		"listen_addr":         ":8086",
+		"read_timeout":        15 * time.Second,
+		"read_header_timeout": 5 * time.Second,
+		"write_timeout":       30 * time.Second,
+		"idle_timeout":        120 * time.Second,
+		"max_header_bytes":    1 << 20, // 1 MiB
+		"max_body_bytes":      1 << 20, // 1 MiB
+		"compression":         []string{CompressionZstd, CompressionGzip},
+		"idempotency_ttl":     24 * time.Hour,
+		"media_storage":       MediaStorageLocal,
+		"media_dir":           "media",
+		"max_media_bytes":     20 << 20, // 20 MiB
+		"media_allowed_types": []string{"image/png", "image/jpeg", "image/gif", "image/webp", "audio/mpeg", "audio/ogg", "audio/wav", "video/mp4", "application/pdf"},
+		"s3_region":           "us-east-1",
+		"s3_path_style":       true,
+	}
+}
+
+// ValidateServer checks the HTTP server settings and reports every invalid field at once
+func (c dbConfig) ValidateServer() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("listen_addr %q: %w", c.ListenAddr, err))
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("tls_cert_file and tls_key_file must be set together"))
+	}
+	for key, file := range map[string]string{"tls_cert_file": c.TLSCertFile, "tls_key_file": c.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"idempotency_ttl", c.IdempotencyTTL},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration such as \"15s\", got %v", t.key, t.value))
+		}
+	}
+
+	if c.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_header_bytes must be positive, got %d", c.MaxHeaderBytes))
+	}
+	if c.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_body_bytes must be positive, got %d", c.MaxBodyBytes))
+	}
+
+	for _, origin := range c.CorsAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("cors_allowed_origins: %q is not an origin such as \"https://example.com\" or \"*\"", origin))
+		}
+	}
+
+	for _, encoding := range c.Compression {
+		switch strings.ToLower(encoding) {
+		case CompressionGzip, CompressionZstd:
+		default:
+			errs = append(errs, fmt.Errorf("compression: unsupported encoding %q, expected %q or %q", encoding, CompressionGzip, CompressionZstd))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateMedia checks the media upload and storage settings and reports every invalid field at once
+func (c dbConfig) ValidateMedia() error {
+	var errs []error
+
+	if c.MaxMediaBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_media_bytes must be positive, got %d", c.MaxMediaBytes))
+	}
+	for _, contentType := range c.MediaAllowedTypes {
+		if !strings.Contains(contentType, "/") {
+			errs = append(errs, fmt.Errorf("media_allowed_types: %q is not a MIME type such as \"image/png\"", contentType))
+		}
+	}
+
+	switch c.MediaStorage {
+	case MediaStorageLocal:
+		if c.MediaDir == "" {
+			errs = append(errs, errors.New("media_dir is required when media_storage is \"local\""))
+		}
+	case MediaStorageS3:
+		if err := validateHTTPURL(c.S3Endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("s3_endpoint is required when media_storage is \"s3\": %w", err))
+		}
+		for key, value := range map[string]string{"s3_region": c.S3Region, "s3_bucket": c.S3Bucket, "s3_access_key_id": c.S3AccessKeyID, "s3_secret_access_key": c.S3SecretAccessKey} {
+			if value == "" {
+				errs = append(errs, fmt.Errorf("%s is required when media_storage is \"s3\"", key))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("media_storage: unsupported storage %q, expected %q or %q", c.MediaStorage, MediaStorageLocal, MediaStorageS3))
+	}
+
+	return errors.Join(errs...)
+}
+
+// TLSEnabled reports whether the server should serve HTTPS
+func (c dbConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
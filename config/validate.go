@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Supported values for the trace_exporter setting, mirrored from the tracing package
+var traceExporters = []string{"", "stdout", "file", "otlp"}
+
+// Supported values for the image_protocol setting, mirrored from the termimage package
+var imageProtocols = []string{"auto", "kitty", "iterm2", "sixel", "halfblocks", "none"}
+
+// Validate checks the client settings and reports every invalid field at once
+func (c *appConfig) Validate() error {
+	var errs []error
+	errs = append(errs, c.commonConfig.validate()...)
+
+	if c.BackendURL != "" {
+		if err := validateHTTPURL(c.BackendURL); err != nil {
+			errs = append(errs, fmt.Errorf("backend_url: %w", err))
+		}
+	}
+	switch c.AudioBackend {
+	case "oto", "none":
+	default:
+		errs = append(errs, fmt.Errorf("audio_backend: unsupported backend %q, expected \"oto\" or \"none\"", c.AudioBackend))
+	}
+	if c.AudioClipMaxPlays < 0 {
+		errs = append(errs, fmt.Errorf("audio_clip_max_plays must not be negative, got %d", c.AudioClipMaxPlays))
+	}
+	validProtocol := false
+	for _, protocol := range imageProtocols {
+		validProtocol = validProtocol || c.ImageProtocol == protocol
+	}
+	if !validProtocol {
+		errs = append(errs, fmt.Errorf("image_protocol: unsupported protocol %q, expected one of \"auto\", \"kitty\", \"iterm2\", \"sixel\", \"halfblocks\" or \"none\"", c.ImageProtocol))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the server settings and reports every invalid field at once
+func (c *dbConfig) Validate() error {
+	var errs []error
+	errs = append(errs, c.commonConfig.validate()...)
+
+	if c.DbType != "mysql" && c.DbType != "sqlite" {
+		errs = append(errs, fmt.Errorf("db_type: unsupported database type %q, expected \"mysql\" or \"sqlite\"", c.DbType))
+	}
+	if c.DbDsn == "" {
+		errs = append(errs, errors.New("db_dsn is required"))
+	}
+	if err := c.ValidateServer(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.ValidateMedia(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (c commonConfig) validate() []error {
+	var errs []error
+	if c.LoggingEnabled && c.LogFile == "" {
+		errs = append(errs, errors.New("log_file is required when logging_enabled is true"))
+	}
+	if c.EnableOktaAuth {
+		if err := validateHTTPURL(c.OktaIssuer); err != nil {
+			errs = append(errs, fmt.Errorf("okta_issuer is required when enable_okta_auth is true: %w", err))
+		}
+		if c.OktaClientID == "" {
+			errs = append(errs, errors.New("okta_client_id is required when enable_okta_auth is true"))
+		}
+	}
+	switch c.Environment {
+	case "development", "production":
+	default:
+		errs = append(errs, fmt.Errorf("environment: unsupported environment %q, expected \"development\" or \"production\"", c.Environment))
+	}
+	switch c.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level: unsupported level %q, expected one of \"debug\", \"info\", \"warn\" or \"error\"", c.LogLevel))
+	}
+	switch c.LogFormat {
+	case "", "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log_format: unsupported format %q, expected \"text\" or \"json\"", c.LogFormat))
+	}
+	if c.LogMaxSizeMB < 0 || c.LogMaxAgeDays < 0 || c.LogMaxBackups < 0 {
+		errs = append(errs, errors.New("log_max_size_mb, log_max_age_days and log_max_backups must not be negative"))
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rate_limit must not be negative, got %d", c.RateLimit))
+	}
+
+	validExporter := false
+	for _, exporter := range traceExporters {
+		validExporter = validExporter || c.TraceExporter == exporter
+	}
+	if !validExporter {
+		errs = append(errs, fmt.Errorf("trace_exporter: unsupported exporter %q, expected one of \"stdout\", \"file\" or \"otlp\"", c.TraceExporter))
+	}
+	if c.TraceExporter == "file" && c.TraceFile == "" {
+		errs = append(errs, errors.New("trace_file is required when trace_exporter is \"file\""))
+	}
+	if c.TraceExporter == "otlp" && c.OtlpEndpoint == "" {
+		errs = append(errs, errors.New("otlp_endpoint is required when trace_exporter is \"otlp\""))
+	}
+	if c.OtlpEndpoint != "" {
+		if err := validateHTTPURL(c.OtlpEndpoint); err != nil {
+			errs = append(errs, fmt.Errorf("otlp_endpoint: %w", err))
+		}
+	}
+	return errs
+}
+
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL such as \"http://localhost:8086\"", raw)
+	}
+	return nil
+}
+
+// IsPrintCommand reports whether the positional arguments request "config print"
+func IsPrintCommand(args []string) bool {
+	return len(args) == 2 && args[0] == "config" && args[1] == "print"
+}
+
+// Print writes the effective configuration of the given binary as JSON, with secrets redacted
+func Print(w io.Writer, kind Kind) error {
+	cfg, _, _ := kind.target()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"config_file": UsedConfigFile,
+		"settings":    Redacted(cfg),
+	})
+}
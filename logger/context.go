@@ -0,0 +1,45 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+// DebugContext logs a debug message tagged with the request ID from ctx
+func DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Debug(msg, withRequestID(ctx, keysAndValues)...)
+}
+
+// InfoContext logs an informational message tagged with the request ID from ctx
+func InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Info(msg, withRequestID(ctx, keysAndValues)...)
+}
+
+// WarnContext logs a warning message tagged with the request ID from ctx
+func WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Warn(msg, withRequestID(ctx, keysAndValues)...)
+}
+
+// ErrorContext logs an error message tagged with the request ID from ctx
+func ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	Error(msg, withRequestID(ctx, keysAndValues)...)
+}
+
+func withRequestID(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	if requestID := RequestID(ctx); requestID != "" {
+		return append([]interface{}{"request_id", requestID}, keysAndValues...)
+	}
+	return keysAndValues
+}
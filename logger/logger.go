@@ -2,27 +2,89 @@ package logger
 
 import (
 	"golang.org/x/exp/slog" // Import the slog package
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
 	"letsquiz/config"
 	"os"
 )
 
 var log *slog.Logger
 var enabled bool
+var level = new(slog.LevelVar) // Minimum level logged, adjustable at runtime
+var environment string         // "development" or "production", selects the default level and format
+
+// Options configures the log output
+type Options struct {
+	Enabled     bool
+	Environment string   // "development" logs text at debug level, "production" logs JSON at info level
+	Level       string   // overrides the environment's level: "debug", "info", "warn" or "error"
+	Format      string   // overrides the environment's format: "text" or "json"
+	File        string   // log file, standard output when empty
+	MaxSizeMB   int      // rotate the file once it reaches this size
+	MaxAgeDays  int      // delete rotated files older than this
+	MaxBackups  int      // keep at most this many rotated files
+	Compress    bool     // gzip rotated files
+	RedactKeys  []string // extra keys to redact, see RedactKeys
+}
 
 // InitLogger initializes the logger based on the configuration
 func InitLogger(db bool) {
-	log = slog.New(slog.NewTextHandler(os.Stdout, nil)) // Default to standard output
-
 	if !db {
-		enabled = config.AppConfig.LoggingEnabled
+		c := config.AppConfig
+		Init(Options{
+			Enabled: c.LoggingEnabled, Environment: c.Environment, Level: c.LogLevel, Format: c.LogFormat,
+			File: c.LogFile, MaxSizeMB: c.LogMaxSizeMB, MaxAgeDays: c.LogMaxAgeDays, MaxBackups: c.LogMaxBackups,
+			Compress: c.LogCompress, RedactKeys: c.LogRedactKeys,
+		}) // Log file for application logs
 	} else {
-		enabled = config.DbConfig.LoggingEnabled
+		c := config.DbConfig
+		Init(Options{
+			Enabled: c.LoggingEnabled, Environment: c.Environment, Level: c.LogLevel, Format: c.LogFormat,
+			File: c.LogFile, MaxSizeMB: c.LogMaxSizeMB, MaxAgeDays: c.LogMaxAgeDays, MaxBackups: c.LogMaxBackups,
+			Compress: c.LogCompress, RedactKeys: c.LogRedactKeys,
+		}) // Log file for database logs
+	}
+}
+
+// Init initializes the logger with explicit options
+func Init(opts Options) {
+	enabled = opts.Enabled
+	environment = opts.Environment
+	RedactKeys(opts.RedactKeys...)
+	if err := SetLevel(opts.Level); err != nil {
+		SetLevel("")
 	}
 
-	if enabled && !db {
-		SetLogFile(config.AppConfig.LogFile) // Set log file for application logs
-	} else if enabled && db {
-		SetLogFile(config.DbConfig.LogFile) // Set log file for database logs
+	var out io.Writer = os.Stdout // Default to standard output
+	if opts.File != "" {
+		// lumberjack creates new log files with mode 0600 and rotates them by size and age
+		out = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxAge:     opts.MaxAgeDays,
+			MaxBackups: opts.MaxBackups,
+			Compress:   opts.Compress,
+		}
+	}
+	log = slog.New(newHandler(out, opts.Format))
+}
+
+// newHandler returns a text or JSON handler honouring the current level and redaction rules
+func newHandler(out io.Writer, format string) slog.Handler {
+	handlerOptions := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	if format == "" && environment == "production" {
+		format = "json"
+	}
+	if format == "json" {
+		return slog.NewJSONHandler(out, handlerOptions)
+	}
+	return slog.NewTextHandler(out, handlerOptions)
+}
+
+// Debug logs a debug message with optional fields, e.g. full request and record payloads
+func Debug(msg string, keysAndValues ...interface{}) {
+	if enabled {
+		log.Debug(msg, keysAndValues...)
 	}
 }
 
@@ -33,6 +95,13 @@ func Info(msg string, keysAndValues ...interface{}) {
 	}
 }
 
+// Warn logs a warning message with optional fields
+func Warn(msg string, keysAndValues ...interface{}) {
+	if enabled {
+		log.Warn(msg, keysAndValues...)
+	}
+}
+
 // Error logs an error message with optional fields
 func Error(msg string, keysAndValues ...interface{}) {
 	if enabled {
@@ -40,12 +109,24 @@ func Error(msg string, keysAndValues ...interface{}) {
 	}
 }
 
-// SetLogFile sets the log output to a file specified by 'filepath'
-func SetLogFile(filepath string) {
-	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		log.Error("Failed to open log file", "error", err) // Log an error if file opening fails
-		return
+// SetLevel sets the minimum level logged: "debug", "info", "warn" or "error".
+// An empty name selects the environment's default, debug in development and info in production.
+func SetLevel(name string) error {
+	if name == "" {
+		name = "debug"
+		if environment == "production" {
+			name = "info"
+		}
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return err
 	}
-	log = slog.New(slog.NewTextHandler(file, nil)) // Update logger to write to the file
+	level.Set(l)
+	return nil
+}
+
+func init() {
+	// Log to standard output until InitLogger is called
+	log = slog.New(newHandler(os.Stdout, "text"))
 }
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"encoding/json"
+	"golang.org/x/exp/slog"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces the value of redacted keys
+const redactedValue = "[REDACTED]"
+
+var (
+	redactMu sync.RWMutex
+	// redactedKeys holds normalized keys whose values are never logged, at any depth
+	redactedKeys = map[string]bool{
+		"email":         true,
+		"iscorrect":     true,
+		"correctanswer": true,
+		"authorization": true,
+		"password":      true,
+		"dbdsn":         true,
+	}
+	// redactedSubstrings redacts any key containing one of these, e.g. access_token or client_secret
+	redactedSubstrings = []string{"token", "secret", "password"}
+)
+
+// RedactKeys adds keys whose values must never be logged. Keys are matched
+// case-insensitively, ignoring underscores and dashes, so "is_correct" also
+// redacts "IsCorrect" and struct fields serialized as "is_correct".
+func RedactKeys(keys ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, key := range keys {
+		redactedKeys[normalizeKey(key)] = true
+	}
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "", " ", "", ":", "").Replace(key)
+}
+
+func isRedacted(key string) bool {
+	normalized := normalizeKey(key)
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	if redactedKeys[normalized] {
+		return true
+	}
+	for _, substring := range redactedSubstrings {
+		if strings.Contains(normalized, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr is the slog ReplaceAttr hook applying the redaction rules to every attribute
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if isRedacted(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+	if a.Value.Kind() == slog.KindString {
+		return redactJSONString(a)
+	}
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+
+	// Structs, maps and slices are redacted field by field through their JSON form
+	switch a.Value.Any().(type) {
+	case error, json.Marshaler, interface{ String() string }:
+		return a
+	}
+	data, err := json.Marshal(a.Value.Any())
+	if err != nil {
+		return a
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return a
+	}
+	if _, isObject := decoded.(map[string]interface{}); !isObject {
+		if _, isArray := decoded.([]interface{}); !isArray {
+			return a
+		}
+	}
+	return slog.Any(a.Key, redactValue(decoded))
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isRedacted(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactJSONString redacts string values holding a JSON document, such as logged request or response bodies
+func redactJSONString(a slog.Attr) slog.Attr {
+	text := strings.TrimSpace(a.Value.String())
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return a
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		return a
+	}
+	data, err := json.Marshal(redactValue(decoded))
+	if err != nil {
+		return a
+	}
+	return slog.String(a.Key, string(data))
+}
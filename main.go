@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"letsquiz/models"
+	"letsquiz/markdown"
 	"letsquiz/music"
+	"letsquiz/offline"
+	"letsquiz/outbox"
+	"letsquiz/requestid"
+	"letsquiz/tracing"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
 	tea "github.com/charmbracelet/bubbletea" // Import Bubble Tea framework
 	"letsquiz/config"
@@ -15,13 +24,22 @@ import (
 )
 
 func main() {
-	// Load the application configuration from a JSON file
-	err := config.LoadConfig("appconfig.json", false)
+	// Load the application configuration from appconfig.json, LETSQUIZ_* environment variables and flags
+	args, err := config.Load(config.App, os.Args[1:])
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err) // Print error and exit if configuration loading fails
 		os.Exit(1)
 	}
 
+	// "config print" shows the effective configuration and exits
+	if config.IsPrintCommand(args) {
+		if err := config.Print(os.Stdout, config.App); err != nil {
+			fmt.Printf("Error printing config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize the logger
 	logger.InitLogger(false)
 	logger.Info("Application started") // Log the application startup
@@ -30,19 +48,77 @@ func main() {
 	shutdownChan := make(chan struct{})
 	music.SetShutdownChannel(shutdownChan) // Set the shutdown channel for the music package
 
-	// Initialize the model for login screen
-	model := models.InitialLoginModel()
-	music.SetModel(&model.Model) // Set the model for the music package
+	// Initialize tracing so backend calls carry W3C trace context to the server
+	shutdownTracing, err := tracing.Init(tracing.Options{
+		ServiceName:  "letsquiz-tui",
+		Exporter:     config.AppConfig.TraceExporter,
+		File:         config.AppConfig.TraceFile,
+		OTLPEndpoint: config.AppConfig.OtlpEndpoint,
+	})
+	if err != nil {
+		logger.Error("Error initializing tracing", "error", err)
+		fmt.Printf("Error initializing tracing: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Tag every backend call with the request ID of the user action that triggered it,
+	// and trace it as a client span
+	http.DefaultClient.Transport = otelhttp.NewTransport(&requestid.Transport{})
+
+	// "sync" pushes what was authored and played in offline mode to the server at backend_url and exits
+	if offline.IsSyncCommand(args) {
+		if err := syncOffline(); err != nil {
+			logger.Error("Error syncing the offline database", "error", err)
+			fmt.Printf("Error syncing the offline database: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// In offline mode the backend runs in process against an SQLite database, chosen with --offline or an empty backend_url
+	if config.AppConfig.OfflineMode() {
+		dbFile, err := offline.Path()
+		if err == nil {
+			err = offline.Start(dbFile)
+		}
+		if err != nil {
+			logger.Error("Error starting offline mode", "error", err)
+			fmt.Printf("Error starting offline mode: %v\n", err)
+			os.Exit(1)
+		}
+	} else if outboxFile, err := outbox.Path(); err != nil {
+		// Load the changes a previous session could not send to the backend and keep replaying them.
+		// They are kept for the server when running offline, where nothing needs to be queued.
+		logger.Error("Cannot locate the outbox, changes made offline are not kept", "error", err)
+	} else if err := outbox.Open(outboxFile); err != nil {
+		logger.Error("Error loading the outbox", "file", outboxFile, "error", err)
+	}
+	go outbox.Run(shutdownChan)
+
+	// Start playing background music in a separate goroutine; without a sound device the app runs silently.
+	// The playlist falls back to the single main track when none is configured.
+	tracks := config.AppConfig.MusicPlaylist
+	if len(tracks) == 0 {
+		tracks = []string{music.ResolveTrack(config.AppConfig.MainMp3Track)}
+	}
+	go music.PlayBackgroundMusic(music.Options{
+		Backend: config.AppConfig.AudioBackend,
+		Tracks:  tracks,
+		Shuffle: config.AppConfig.MusicShuffle,
 
-	// Start playing background music in a separate goroutine
-	go music.PlayBackgroundMusic(fmt.Sprintf("%s.mp3", config.AppConfig.MainMp3Track))
+		Effects:    config.AppConfig.SoundEffects,
+		EffectsDir: config.AppConfig.SoundEffectsDir,
+	})
 
 	// Set up a channel to catch OS signals for graceful shutdown
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM) // Notify on interrupt or termination signals
 
+	// Markdown is styled for the terminal background, asked for before the program reads the terminal's input
+	markdown.DetectBackground()
+
 	// Create a new Bubble Tea program with initial model and settings
-	p := tea.NewProgram(screens.InitialModel(), tea.WithAltScreen(), tea.WithMouseAllMotion())
+	p := tea.NewProgram(screens.InitialModel(), tea.WithAltScreen(), tea.WithMouseAllMotion(), tea.WithFilter(beginUserAction))
 
 	// Start a goroutine to listen for shutdown signals and quit the program
 	go func() {
@@ -61,11 +137,18 @@ func main() {
 	// Close the shutdown channel to signal the music package to stop
 	close(shutdownChan)
 
+	// Flush any spans that are still buffered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Error shutting down tracing", "error", err)
+	}
+
 	// Type switch to handle different screen models and print information
 	switch model := m.(type) {
 	case *screens.Login:
-		logger.Info(fmt.Sprintf("Selected option (LoginModel): %s\n", model))
-		fmt.Printf("Selected option (LoginModel): %s\n", model)
+		logger.Info(fmt.Sprintf("Selected option (LoginModel): %v\n", model))
+		fmt.Printf("Selected option (LoginModel): %v\n", model)
 	case *screens.Menu:
 		logger.Info(fmt.Sprintf("Selected option (Menu): %v\n", model))
 		fmt.Printf("Entering Menu with model: %v\n", model)
@@ -85,3 +168,39 @@ func main() {
 	// Exiting program, Application ended
 	logger.Info("Application ended")
 }
+
+// syncOffline pushes the offline database to the server at backend_url, printing what was synced
+func syncOffline() error {
+	if config.AppConfig.BackendURL == "" {
+		return fmt.Errorf("set backend_url, e.g. with --backend-url, to the server to sync to")
+	}
+	dbFile, err := offline.Path()
+	if err != nil {
+		return err
+	}
+	if err := offline.Open(dbFile); err != nil {
+		return err
+	}
+	summary, err := offline.Sync(config.AppConfig.BackendURL)
+	fmt.Printf("Synced %s to %s: %s\n", dbFile, config.AppConfig.BackendURL, summary)
+	return err
+}
+
+// beginUserAction starts a new request ID for each key press or mouse click,
+// so the backend calls it triggers can be correlated with the server logs.
+// Typed text is not logged, it may be a password; named keys are, at debug level.
+func beginUserAction(_ tea.Model, msg tea.Msg) tea.Msg {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		id := requestid.BeginAction()
+		logger.Info("User action", "request_id", id, "event", "key")
+		if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace {
+			logger.Debug("Key pressed", "request_id", id, "key", msg.String())
+		}
+	case tea.MouseMsg:
+		if msg.Type == tea.MouseLeft {
+			logger.Info("User action", "request_id", requestid.BeginAction(), "event", "click")
+		}
+	}
+	return msg
+}
@@ -0,0 +1,102 @@
+// Package markdown renders the Markdown of question, answer and hint texts for the terminal,
+// highlighting fenced code blocks with chroma.
+package markdown
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"letsquiz/logger"
+)
+
+// maxCached bounds the rendered texts kept, views render the same texts on every frame
+const maxCached = 256
+
+type cacheKey struct {
+	source string
+	width  int
+}
+
+var (
+	mu            sync.Mutex
+	dark          = true // the terminal background, see DetectBackground
+	renderer      *glamour.TermRenderer
+	rendererWidth int // the wrap width of renderer, views share the width of the window
+	rendered      = make(map[cacheKey]string)
+)
+
+// DetectBackground picks the dark or light style for the terminal's background, dark until it is called.
+// It queries the terminal, so it is called before the Bubble Tea program reads the terminal's input.
+func DetectBackground() {
+	isDark := lipgloss.HasDarkBackground()
+	mu.Lock()
+	defer mu.Unlock()
+	if isDark != dark {
+		dark = isDark
+		renderer = nil
+		rendered = make(map[cacheKey]string)
+	}
+}
+
+// Render renders source wrapped to width columns, or unwrapped when width is not positive.
+// The document margin glamour adds is left out so the result lines up with the surrounding text.
+// Should rendering fail, source is returned as plain wrapped text.
+func Render(source string, width int) string {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return ""
+	}
+	if width < 0 {
+		width = 0
+	}
+	key := cacheKey{source: source, width: width}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if out, ok := rendered[key]; ok {
+		return out
+	}
+	out, err := render(source, width)
+	if err != nil {
+		logger.Warn("Failed to render Markdown, showing it as plain text", "error", err)
+		out = lipgloss.NewStyle().Width(width).Render(source)
+	}
+	if len(rendered) >= maxCached {
+		rendered = make(map[cacheKey]string)
+	}
+	rendered[key] = out
+	return out
+}
+
+// render converts source with the renderer for width, replacing the renderer when the width changed
+func render(source string, width int) (string, error) {
+	if renderer == nil || rendererWidth != width {
+		r, err := glamour.NewTermRenderer(glamour.WithStyles(style()), glamour.WithWordWrap(width))
+		if err != nil {
+			return "", err
+		}
+		renderer, rendererWidth = r, width
+	}
+	out, err := renderer.Render(source)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(out, "\n"), nil
+}
+
+// style is glamour's dark or light style, matching the terminal background, without the document margin
+func style() ansi.StyleConfig {
+	config := styles.LightStyleConfig
+	if dark {
+		config = styles.DarkStyleConfig
+	}
+	var noMargin uint
+	config.Document.Margin = &noMargin
+	config.Document.BlockPrefix = ""
+	config.Document.BlockSuffix = ""
+	return config
+}
@@ -0,0 +1,356 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// answerListKeyMap are the keys editing the rows of an AnswerList, besides typing the answer text
+type answerListKeyMap struct {
+	Up            key.Binding
+	Down          key.Binding
+	Add           key.Binding
+	Remove        key.Binding
+	ToggleCorrect key.Binding
+	MoveUp        key.Binding
+	MoveDown      key.Binding
+	huh.InputKeyMap
+}
+
+func newAnswerListKeyMap() answerListKeyMap {
+	return answerListKeyMap{
+		Up:            key.NewBinding(key.WithKeys("up"), key.WithHelp("↑/↓", "answer")),
+		Down:          key.NewBinding(key.WithKeys("down")),
+		Add:           key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "add")),
+		Remove:        key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "remove")),
+		ToggleCorrect: key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("ctrl+x", "correct")),
+		MoveUp:        key.NewBinding(key.WithKeys("ctrl+up", "shift+up"), key.WithHelp("ctrl+↑/↓", "move")),
+		MoveDown:      key.NewBinding(key.WithKeys("ctrl+down", "shift+down")),
+		InputKeyMap:   huh.NewDefaultKeyMap().Input,
+	}
+}
+
+// AnswerList is a form field editing the answers of a question as a list of rows, each with a checkbox
+// marking it correct. Rows keep the Answer they were loaded from, so their IDs survive edits and reordering.
+type AnswerList struct {
+	key         string
+	title       string
+	description string
+	answers     *[]Answer
+	rows        []textinput.Model // the text of each answer, in step with *answers
+	cursor      int
+	validate    func([]Answer) error
+	err         error
+
+	focused bool
+	width   int
+	height  int
+	theme   *huh.Theme
+	keymap  answerListKeyMap
+}
+
+// NewAnswerList returns an answer list field, call Value to bind it to the answers it edits
+func NewAnswerList() *AnswerList {
+	return &AnswerList{
+		answers:  &[]Answer{},
+		validate: func([]Answer) error { return nil },
+		keymap:   newAnswerListKeyMap(),
+	}
+}
+
+// Key sets the key the form stores the answers under
+func (a *AnswerList) Key(key string) *AnswerList {
+	a.key = key
+	return a
+}
+
+// Title sets the title of the field
+func (a *AnswerList) Title(title string) *AnswerList {
+	a.title = title
+	return a
+}
+
+// Description sets the description of the field
+func (a *AnswerList) Description(description string) *AnswerList {
+	a.description = description
+	return a
+}
+
+// Value binds the field to the answers it edits, starting with a blank row when there are none
+func (a *AnswerList) Value(answers *[]Answer) *AnswerList {
+	a.answers = answers
+	a.rows = nil
+	for _, answer := range *answers {
+		a.rows = append(a.rows, a.newRow(answer.Text))
+	}
+	if len(a.rows) == 0 {
+		a.insert(0)
+	}
+	return a
+}
+
+// Validate sets the check run when leaving the field
+func (a *AnswerList) Validate(validate func([]Answer) error) *AnswerList {
+	a.validate = validate
+	return a
+}
+
+func (a *AnswerList) newRow(text string) textinput.Model {
+	row := textinput.New()
+	row.Prompt = ""
+	row.Placeholder = "Answer text"
+	row.SetValue(text)
+	row.Width = a.rowWidth()
+	return row
+}
+
+// rowWidth is the room left for the text after the selector, the checkbox and the number
+func (a *AnswerList) rowWidth() int {
+	if a.width <= 0 {
+		return 0
+	}
+	width := a.width - a.activeStyles().Base.GetHorizontalFrameSize() - 10
+	if width < 10 {
+		width = 10
+	}
+	return width
+}
+
+// insert adds a blank row at index i and moves the cursor to it
+func (a *AnswerList) insert(i int) {
+	*a.answers = append(*a.answers, Answer{})
+	copy((*a.answers)[i+1:], (*a.answers)[i:])
+	(*a.answers)[i] = Answer{}
+	a.rows = append(a.rows, textinput.Model{})
+	copy(a.rows[i+1:], a.rows[i:])
+	a.rows[i] = a.newRow("")
+	a.moveCursor(i)
+}
+
+// remove deletes the row under the cursor, leaving a blank row rather than none
+func (a *AnswerList) remove() {
+	i := a.cursor
+	*a.answers = append((*a.answers)[:i], (*a.answers)[i+1:]...)
+	a.rows = append(a.rows[:i], a.rows[i+1:]...)
+	if len(a.rows) == 0 {
+		a.insert(0)
+		return
+	}
+	if i >= len(a.rows) {
+		i = len(a.rows) - 1
+	}
+	a.moveCursor(i)
+}
+
+// swap exchanges the row under the cursor with the row at index j, keeping the cursor on the moved row
+func (a *AnswerList) swap(j int) {
+	if j < 0 || j >= len(a.rows) {
+		return
+	}
+	i := a.cursor
+	(*a.answers)[i], (*a.answers)[j] = (*a.answers)[j], (*a.answers)[i]
+	a.rows[i], a.rows[j] = a.rows[j], a.rows[i]
+	a.cursor = j
+}
+
+// moveCursor focuses the row at index i, the rows are few enough to blur them all rather than track the last one
+func (a *AnswerList) moveCursor(i int) {
+	if i < 0 || i >= len(a.rows) {
+		return
+	}
+	for n := range a.rows {
+		a.rows[n].Blur()
+	}
+	a.cursor = i
+	if a.focused {
+		a.rows[a.cursor].Focus()
+	}
+}
+
+// Init initializes the field
+func (a *AnswerList) Init() tea.Cmd {
+	return nil
+}
+
+// Update edits the row under the cursor or the list itself
+func (a *AnswerList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !a.focused {
+		return a, nil
+	}
+	a.err = nil
+
+	switch {
+	case key.Matches(keyMsg, a.keymap.Prev):
+		return a, huh.PrevField
+	case key.Matches(keyMsg, a.keymap.Next, a.keymap.Submit):
+		if a.err = a.validate(a.GetValue().([]Answer)); a.err != nil {
+			return a, nil
+		}
+		return a, huh.NextField
+	case key.Matches(keyMsg, a.keymap.Up):
+		a.moveCursor(a.cursor - 1)
+	case key.Matches(keyMsg, a.keymap.Down):
+		a.moveCursor(a.cursor + 1)
+	case key.Matches(keyMsg, a.keymap.Add):
+		a.insert(a.cursor + 1)
+	case key.Matches(keyMsg, a.keymap.Remove):
+		a.remove()
+	case key.Matches(keyMsg, a.keymap.ToggleCorrect):
+		(*a.answers)[a.cursor].IsCorrect = !(*a.answers)[a.cursor].IsCorrect
+	case key.Matches(keyMsg, a.keymap.MoveUp):
+		a.swap(a.cursor - 1)
+	case key.Matches(keyMsg, a.keymap.MoveDown):
+		a.swap(a.cursor + 1)
+	default:
+		var cmd tea.Cmd
+		a.rows[a.cursor], cmd = a.rows[a.cursor].Update(msg)
+		(*a.answers)[a.cursor].Text = a.rows[a.cursor].Value()
+		return a, cmd
+	}
+	return a, nil
+}
+
+func (a *AnswerList) activeStyles() *huh.FieldStyles {
+	theme := a.theme
+	if theme == nil {
+		theme = huh.ThemeCharm()
+	}
+	if a.focused {
+		return &theme.Focused
+	}
+	return &theme.Blurred
+}
+
+// View renders the title, the description and a row per answer
+func (a *AnswerList) View() string {
+	styles := a.activeStyles()
+	var sb strings.Builder
+	sb.WriteString(styles.Title.Render(a.title))
+	if a.err != nil {
+		sb.WriteString(styles.ErrorIndicator.String())
+	}
+	sb.WriteString("\n")
+	if a.description != "" {
+		sb.WriteString(styles.Description.Render(a.description) + "\n")
+	}
+	for i := range a.rows {
+		row := &a.rows[i]
+		row.PromptStyle = styles.TextInput.Prompt
+		row.PlaceholderStyle = styles.TextInput.Placeholder
+		row.TextStyle = styles.TextInput.Text
+		row.Cursor.Style = styles.TextInput.Cursor
+
+		selector := "  "
+		if a.focused && i == a.cursor {
+			selector = styles.SelectSelector.String()
+		}
+		checkbox := styles.UnselectedPrefix.String()
+		if (*a.answers)[i].IsCorrect {
+			checkbox = styles.SelectedPrefix.String()
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(selector + checkbox + fmt.Sprintf("%d. ", i+1) + row.View())
+	}
+	return styles.Base.Width(a.width).Height(a.height).Render(sb.String())
+}
+
+// Focus focuses the row under the cursor
+func (a *AnswerList) Focus() tea.Cmd {
+	a.focused = true
+	return a.rows[a.cursor].Focus()
+}
+
+// Blur leaves the field, validating the answers
+func (a *AnswerList) Blur() tea.Cmd {
+	a.focused = false
+	a.rows[a.cursor].Blur()
+	a.err = a.validate(a.GetValue().([]Answer))
+	return nil
+}
+
+// Error returns the validation error of the answers
+func (a *AnswerList) Error() error { return a.err }
+
+// Run runs the field on its own
+func (a *AnswerList) Run() error { return huh.Run(a) }
+
+// RunAccessible is not supported, the rows need a full terminal to be edited
+func (a *AnswerList) RunAccessible(io.Writer, io.Reader) error {
+	return errors.New("the answer list cannot be edited in accessible mode")
+}
+
+// Skip returns false, the field takes input
+func (*AnswerList) Skip() bool { return false }
+
+// Zoom returns false, the field keeps its height
+func (*AnswerList) Zoom() bool { return false }
+
+// KeyBinds returns the help for the keys editing the list
+func (a *AnswerList) KeyBinds() []key.Binding {
+	return []key.Binding{a.keymap.Up, a.keymap.Add, a.keymap.Remove, a.keymap.ToggleCorrect, a.keymap.MoveUp, a.keymap.Prev, a.keymap.Submit, a.keymap.Next}
+}
+
+// WithTheme sets the theme unless the field has one
+func (a *AnswerList) WithTheme(theme *huh.Theme) huh.Field {
+	if a.theme == nil {
+		a.theme = theme
+	}
+	return a
+}
+
+// WithAccessible is ignored, see RunAccessible
+func (a *AnswerList) WithAccessible(bool) huh.Field { return a }
+
+// WithKeyMap takes the field navigation keys of the form's text inputs
+func (a *AnswerList) WithKeyMap(k *huh.KeyMap) huh.Field {
+	a.keymap.InputKeyMap = k.Input
+	return a
+}
+
+// WithWidth sets the width of the field, long answers scroll inside their row
+func (a *AnswerList) WithWidth(width int) huh.Field {
+	a.width = width
+	for i := range a.rows {
+		a.rows[i].Width = a.rowWidth()
+	}
+	return a
+}
+
+// WithHeight sets the height of the field
+func (a *AnswerList) WithHeight(height int) huh.Field {
+	a.height = height
+	return a
+}
+
+// WithPosition enables the keys leaving the field depending on where it is in the form
+func (a *AnswerList) WithPosition(p huh.FieldPosition) huh.Field {
+	a.keymap.Prev.SetEnabled(!p.IsFirst())
+	a.keymap.Next.SetEnabled(!p.IsLast())
+	a.keymap.Submit.SetEnabled(p.IsLast())
+	return a
+}
+
+// GetKey returns the key of the field
+func (a *AnswerList) GetKey() string { return a.key }
+
+// GetValue returns a copy of the answers with their text trimmed
+func (a *AnswerList) GetValue() any {
+	answers := make([]Answer, len(*a.answers))
+	for i, answer := range *a.answers {
+		answer.Text = strings.TrimSpace(answer.Text)
+		answers[i] = answer
+	}
+	return answers
+}
+
+var _ huh.Field = (*AnswerList)(nil)
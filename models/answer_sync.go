@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// answerChanges are the differences between the answers of a question as loaded from the backend and as edited
+type answerChanges struct {
+	Created      []Answer // answers without an ID
+	Updated      []Answer // answers whose text or correctness changed
+	Repositioned []Answer // answers whose stored position is not their index, after edits or when saved before positions existed
+	Deleted      []Answer // loaded answers that were removed
+	Reordered    bool     // the answers kept are in a different order
+}
+
+// diffAnswers compares the edited answers with the loaded ones. Edited answers are taken in order,
+// each answer's position becoming its index.
+func diffAnswers(loaded, edited []Answer) answerChanges {
+	var changes answerChanges
+	loadedByID := make(map[int]Answer, len(loaded))
+	for _, answer := range loaded {
+		loadedByID[answer.ID] = answer
+	}
+	kept := make(map[int]bool, len(edited))
+	var editedOrder []int
+	for n, answer := range edited {
+		answer.Text = strings.TrimSpace(answer.Text)
+		answer.Position = n
+		if answer.ID == 0 {
+			changes.Created = append(changes.Created, answer)
+			continue
+		}
+		kept[answer.ID] = true
+		editedOrder = append(editedOrder, answer.ID)
+		before, ok := loadedByID[answer.ID]
+		switch {
+		case !ok || before.Text != answer.Text || before.IsCorrect != answer.IsCorrect:
+			changes.Updated = append(changes.Updated, answer)
+		case before.Position != n:
+			changes.Repositioned = append(changes.Repositioned, answer)
+		}
+	}
+	var loadedOrder []int
+	for _, answer := range loaded {
+		if !kept[answer.ID] {
+			changes.Deleted = append(changes.Deleted, answer)
+		} else {
+			loadedOrder = append(loadedOrder, answer.ID)
+		}
+	}
+	for n := range loadedOrder {
+		changes.Reordered = changes.Reordered || n >= len(editedOrder) || loadedOrder[n] != editedOrder[n]
+	}
+	return changes
+}
+
+// empty reports whether nothing needs to be saved
+func (c answerChanges) empty() bool {
+	return len(c.Created)+len(c.Updated)+len(c.Repositioned)+len(c.Deleted) == 0
+}
+
+// visible reports whether the author changed anything, as opposed to positions only being renumbered
+func (c answerChanges) visible() bool {
+	return len(c.Created)+len(c.Updated)+len(c.Deleted) > 0 || c.Reordered
+}
+
+// saves reports whether the answer with the given ID must be sent to the backend
+func (c answerChanges) saves(id int) bool {
+	for _, answers := range [][]Answer{c.Updated, c.Repositioned} {
+		for _, answer := range answers {
+			if answer.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// summary describes the changes, e.g. `1 added, 2 changed, 1 removed ("Nice")`
+func (c answerChanges) summary() string {
+	var parts []string
+	if n := len(c.Created); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", n))
+	}
+	if n := len(c.Updated); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", n))
+	}
+	if c.Reordered {
+		parts = append(parts, "reordered")
+	}
+	if n := len(c.Deleted); n > 0 {
+		var texts []string
+		for _, answer := range c.Deleted {
+			texts = append(texts, fmt.Sprintf("%q", answer.Text))
+		}
+		parts = append(parts, fmt.Sprintf("%d removed (%s)", n, strings.Join(texts, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// changesSummary lists the questions deleted and the answer changes of every question as Markdown,
+// empty when there are none
+func (m *DynamicQuizModel) changesSummary() string {
+	var lines []string
+	for _, question := range m.DeletedQuestions {
+		lines = append(lines, fmt.Sprintf("- Deleted: %s, with its answers", changeDescription("question", question.Text)))
+	}
+	for i, q := range m.QuestionForms {
+		if changes := diffAnswers(q.LoadedAnswers, q.Answers); changes.visible() {
+			lines = append(lines, fmt.Sprintf("- Question %d: %s", i+1, changes.summary()))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "**Save these changes?**\n\n" + strings.Join(lines, "\n")
+}
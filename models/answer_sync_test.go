@@ -0,0 +1,138 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ids lists the IDs of answers, or their texts for answers without one
+func ids(answers []Answer) []interface{} {
+	list := []interface{}{}
+	for _, answer := range answers {
+		if answer.ID == 0 {
+			list = append(list, answer.Text)
+		} else {
+			list = append(list, answer.ID)
+		}
+	}
+	return list
+}
+
+func TestDiffAnswers(t *testing.T) {
+	loaded := []Answer{
+		{ID: 1, Text: "Lisbon", IsCorrect: true, Position: 0},
+		{ID: 2, Text: "Porto", Position: 1},
+		{ID: 3, Text: "Faro", Position: 2},
+	}
+	tests := []struct {
+		name                                    string
+		loaded, edited                          []Answer
+		created, updated, repositioned, deleted []interface{}
+		reordered                               bool
+		summary                                 string
+	}{
+		{
+			name:   "unchanged",
+			loaded: loaded, edited: loaded,
+		},
+		{
+			name:   "created",
+			loaded: loaded, edited: append(append([]Answer(nil), loaded...), Answer{Text: " Braga "}),
+			created: []interface{}{"Braga"},
+			summary: "1 added",
+		},
+		{
+			name:    "updated text and correctness",
+			loaded:  loaded,
+			edited:  []Answer{loaded[0], {ID: 2, Text: "Porto", IsCorrect: true, Position: 1}, {ID: 3, Text: "Faro ", Position: 2}},
+			updated: []interface{}{2},
+			summary: "1 changed",
+		},
+		{
+			name:   "deleted",
+			loaded: loaded, edited: []Answer{loaded[0], loaded[2]},
+			repositioned: []interface{}{3},
+			deleted:      []interface{}{2},
+			summary:      `1 removed ("Porto")`,
+		},
+		{
+			name:   "reordered",
+			loaded: loaded, edited: []Answer{loaded[1], loaded[0], loaded[2]},
+			repositioned: []interface{}{2, 1},
+			reordered:    true,
+			summary:      "reordered",
+		},
+		{
+			name:         "positions saved before they existed",
+			loaded:       []Answer{{ID: 1, Text: "Lisbon"}, {ID: 2, Text: "Porto"}},
+			edited:       []Answer{{ID: 1, Text: "Lisbon"}, {ID: 2, Text: "Porto"}},
+			repositioned: []interface{}{2},
+		},
+		{
+			name:         "everything at once",
+			loaded:       loaded,
+			edited:       []Answer{{Text: "Braga"}, loaded[2], {ID: 1, Text: "Lisboa", IsCorrect: true}},
+			created:      []interface{}{"Braga"},
+			updated:      []interface{}{1},
+			repositioned: []interface{}{3},
+			deleted:      []interface{}{2},
+			reordered:    true,
+			summary:      `1 added, 1 changed, reordered, 1 removed ("Porto")`,
+		},
+		{
+			name:    "new question",
+			edited:  []Answer{{Text: "Lisbon", IsCorrect: true}, {Text: "Porto"}},
+			created: []interface{}{"Lisbon", "Porto"},
+			summary: "2 added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := diffAnswers(tt.loaded, tt.edited)
+			for _, c := range []struct {
+				kind      string
+				got, want []interface{}
+			}{
+				{"created", ids(changes.Created), tt.created},
+				{"updated", ids(changes.Updated), tt.updated},
+				{"repositioned", ids(changes.Repositioned), tt.repositioned},
+				{"deleted", ids(changes.Deleted), tt.deleted},
+			} {
+				if c.want == nil {
+					c.want = []interface{}{}
+				}
+				if !reflect.DeepEqual(c.got, c.want) {
+					t.Errorf("%s = %v, want %v", c.kind, c.got, c.want)
+				}
+			}
+			if changes.Reordered != tt.reordered {
+				t.Errorf("reordered = %v, want %v", changes.Reordered, tt.reordered)
+			}
+			if got := changes.summary(); got != tt.summary {
+				t.Errorf("summary() = %q, want %q", got, tt.summary)
+			}
+			if changes.visible() != (tt.summary != "") {
+				t.Errorf("visible() = %v, want %v", changes.visible(), tt.summary != "")
+			}
+			wantEmpty := tt.created == nil && tt.updated == nil && tt.repositioned == nil && tt.deleted == nil
+			if changes.empty() != wantEmpty {
+				t.Errorf("empty() = %v, want %v", changes.empty(), wantEmpty)
+			}
+		})
+	}
+}
+
+func TestDiffAnswersPositions(t *testing.T) {
+	changes := diffAnswers(nil, []Answer{{Text: " Lisbon "}, {Text: "Porto"}})
+	for n, answer := range changes.Created {
+		if answer.Position != n {
+			t.Errorf("created answer %q at position %d, want %d", answer.Text, answer.Position, n)
+		}
+	}
+	if changes.Created[0].Text != "Lisbon" {
+		t.Errorf("created answer text = %q, want it trimmed", changes.Created[0].Text)
+	}
+	if changes.saves(0) {
+		t.Errorf("saves(0) = true, want answers without an ID created instead")
+	}
+}
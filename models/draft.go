@@ -0,0 +1,420 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/config"
+	"letsquiz/logger"
+)
+
+// draftAutosaveInterval is how often the editor writes its draft, besides whenever it moves between questions
+const draftAutosaveInterval = 5 * time.Second
+
+// draftHealthTimeout bounds the backend check made before syncing a restored draft
+const draftHealthTimeout = 2 * time.Second
+
+// Draft is the state of the quiz editor kept on disk until it is saved to the backend,
+// so nothing typed is lost when the app crashes or the backend is down
+type Draft struct {
+	QuizID           int             `json:"quiz_id"` // 0 for a quiz being created
+	Focused          string          `json:"focused"`
+	ButtonLabel      string          `json:"button_label"`
+	CurrentFormGroup int             `json:"current_form_group"`
+	Questions        []draftQuestion `json:"questions"`
+	DeletedQuestions []Question      `json:"deleted_questions,omitempty"` // saved questions to delete on the backend
+	SavedAt          time.Time       `json:"saved_at"`
+}
+
+// draftQuestion is a question form with its per question inputs
+type draftQuestion struct {
+	Question            Question `json:"question"`
+	Answers             []Answer `json:"answers"`
+	LoadedAnswers       []Answer `json:"loaded_answers"` // kept so answers removed in the draft are deleted when it is synced
+	UploadedClip        string   `json:"uploaded_clip"`
+	Points              string   `json:"points"`
+	MultiChoiceAnsLimit string   `json:"multi_choice_ans_limit"`
+	AudioClipFile       string   `json:"audio_clip_file"`
+}
+
+type draftTickMsg struct{}
+
+// DraftAutosave schedules the next autosave of the editor's draft
+func DraftAutosave() tea.Cmd {
+	return tea.Tick(draftAutosaveInterval, func(time.Time) tea.Msg { return draftTickMsg{} })
+}
+
+// draftsDir returns the directory drafts are kept in, drafts_dir or letsquiz/drafts in the user's data directory
+func draftsDir() (string, error) {
+	if config.AppConfig.DraftsDir != "" {
+		return config.AppConfig.DraftsDir, nil
+	}
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drafts"), nil
+}
+
+func draftPath(quizID int) (string, error) {
+	dir, err := draftsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("quiz-%d.json", quizID)), nil
+}
+
+// ListDrafts returns the drafts left by previous sessions, the most recent first
+func ListDrafts() ([]Draft, error) {
+	dir, err := draftsDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "quiz-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var drafts []Draft
+	for _, path := range paths {
+		d, err := readDraft(path)
+		if err != nil {
+			logger.Warn("Skipping unreadable quiz draft", "file", path, "error", err)
+			continue
+		}
+		drafts = append(drafts, d)
+	}
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].SavedAt.After(drafts[j].SavedAt) })
+	return drafts, nil
+}
+
+// loadDraft returns the draft of a quiz, reporting false when there is none
+func loadDraft(quizID int) (Draft, bool) {
+	path, err := draftPath(quizID)
+	if err != nil {
+		logger.Warn("Cannot locate quiz drafts", "error", err)
+		return Draft{}, false
+	}
+	d, err := readDraft(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("Ignoring unreadable quiz draft", "file", path, "error", err)
+		}
+		return Draft{}, false
+	}
+	return d, true
+}
+
+func readDraft(path string) (Draft, error) {
+	var d Draft
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &d)
+	}
+	if err == nil && len(d.Questions) == 0 {
+		err = errors.New("the draft has no questions")
+	}
+	return d, err
+}
+
+// draft captures the editor state, along with the questions and deletions alone to tell whether they changed
+func (m DynamicQuizModel) draft() (Draft, string) {
+	d := Draft{
+		QuizID:           m.QuizID,
+		Focused:          m.Focused,
+		CurrentFormGroup: m.CurrentFormGroup,
+		DeletedQuestions: m.DeletedQuestions,
+	}
+	if len(m.Buttons) > 0 {
+		d.ButtonLabel = m.Buttons[0].Label
+	}
+	for _, q := range m.QuestionForms {
+		d.Questions = append(d.Questions, draftQuestion{
+			Question:            q.Question,
+			Answers:             q.Answers,
+			LoadedAnswers:       q.LoadedAnswers,
+			UploadedClip:        q.UploadedClip,
+			Points:              q.Points,
+			MultiChoiceAnsLimit: q.MultiChoiceAnsLimit,
+			AudioClipFile:       q.AudioClipFile,
+		})
+	}
+	state, _ := json.Marshal([]interface{}{d.Questions, d.DeletedQuestions})
+	return d, string(state)
+}
+
+// saveDraft writes the draft when the questions changed since it was last written or synced.
+// The file is replaced in one step, so a crash while writing leaves the previous draft.
+func (m *DynamicQuizModel) saveDraft() {
+	d, state := m.draft()
+	if state == m.DraftState {
+		return
+	}
+	path, err := draftPath(m.QuizID)
+	if err == nil {
+		d.SavedAt = time.Now()
+		err = writeDraft(path, d)
+	}
+	if err != nil {
+		logger.Error("Failed to autosave quiz draft", "quizID", m.QuizID, "error", err)
+		return
+	}
+	m.DraftState = state
+	logger.Debug("Autosaved quiz draft", "quizID", m.QuizID, "file", path)
+}
+
+func writeDraft(path string, d Draft) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".draft-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// draftSynced records the outcome of saving the editor to the backend: the draft is removed once
+// everything is saved, or else kept and synced again by the autosave when the backend is back
+func (m *DynamicQuizModel) draftSynced(saved bool) {
+	if !saved {
+		m.DraftUnsynced = true
+		m.saveDraft()
+		return
+	}
+	m.DraftUnsynced = false
+	_, m.DraftState = m.draft()
+	path, err := draftPath(m.QuizID)
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("Failed to remove synced quiz draft", "quizID", m.QuizID, "error", err)
+	}
+}
+
+// draftSyncMsg is the outcome of syncing the draft in the background, see autosave
+type draftSyncMsg struct {
+	synced   *DynamicQuizModel // the copy of the editor that was saved, nil when the backend was unreachable
+	saved    bool              // everything in the copy was saved or queued
+	state    string            // the questions and deletions when the copy was taken, see draft
+	deleting []Question        // the questions the copy was to delete
+}
+
+// autosave writes the draft, and returns the command syncing a draft that could not be saved before
+// once the backend is reachable. The sync runs on a copy of the editor, merged back by mergeSync.
+func (m *DynamicQuizModel) autosave() tea.Cmd {
+	if m.PendingRestore != "" {
+		return nil
+	}
+	m.saveDraft()
+	if !m.DraftUnsynced || m.Syncing {
+		return nil
+	}
+	m.saveCurrentFormData()
+	m.Syncing = true
+	snapshot := m.snapshot()
+	_, state := m.draft()
+	deleting := append([]Question(nil), m.DeletedQuestions...)
+	return func() tea.Msg {
+		if !backendReachable() {
+			return draftSyncMsg{}
+		}
+		logger.Info("Backend reachable, syncing quiz draft", "quizID", snapshot.QuizID)
+		saved := snapshot.saveResponsesToBackend()
+		return draftSyncMsg{synced: &snapshot, saved: saved, state: state, deleting: deleting}
+	}
+}
+
+// snapshot copies what saving to the backend reads and changes, giving the questions and the new answers
+// a serial to find them again once saved
+func (m *DynamicQuizModel) snapshot() DynamicQuizModel {
+	c := DynamicQuizModel{QuizID: m.QuizID, DeletedQuestions: append([]Question(nil), m.DeletedQuestions...)}
+	for i := range m.QuestionForms {
+		q := &m.QuestionForms[i]
+		if q.serial == 0 {
+			m.lastSerial++
+			q.serial = m.lastSerial
+		}
+		for n := range q.Answers {
+			if answer := &q.Answers[n]; answer.ID == 0 && answer.serial == 0 {
+				m.lastSerial++
+				answer.serial = m.lastSerial
+			}
+		}
+		copied := *q
+		copied.Form = nil // the form keeps changing in Update
+		copied.Answers = append([]Answer(nil), q.Answers...)
+		copied.LoadedAnswers = append([]Answer(nil), q.LoadedAnswers...)
+		c.QuestionForms = append(c.QuestionForms, copied)
+	}
+	return c
+}
+
+// mergeSync merges the outcome of a background sync into the editor, which may have changed meanwhile:
+// new questions and answers keep the IDs they were saved with, questions removed meanwhile after being
+// created are deleted with the next save, and the draft is only removed when nothing changed meanwhile
+func (m *DynamicQuizModel) mergeSync(msg draftSyncMsg) {
+	m.Syncing = false
+	if msg.synced == nil {
+		logger.Debug("Backend unreachable, quiz draft kept", "quizID", m.QuizID)
+		return
+	}
+	_, state := m.draft()
+	unchanged := state == msg.state
+
+	// The deletions that went through are dropped, those added meanwhile or that failed are kept
+	failed := make(map[int]bool)
+	for _, question := range msg.synced.DeletedQuestions {
+		failed[question.ID] = true
+	}
+	deleted := make(map[int]bool)
+	for _, question := range msg.deleting {
+		deleted[question.ID] = !failed[question.ID]
+	}
+	var deletions []Question
+	for _, question := range m.DeletedQuestions {
+		if !deleted[question.ID] {
+			deletions = append(deletions, question)
+		}
+	}
+
+	forms := make(map[int]*questionForms)
+	for i := range m.QuestionForms {
+		forms[m.QuestionForms[i].serial] = &m.QuestionForms[i]
+	}
+	for _, synced := range msg.synced.QuestionForms {
+		q, ok := forms[synced.serial]
+		if !ok {
+			if synced.Question.ID != 0 && !containsQuestion(deletions, synced.Question.ID) {
+				deletions = append(deletions, synced.Question)
+			}
+			continue
+		}
+		if q.Question.ID == 0 {
+			q.Question.ID = synced.Question.ID
+		}
+		q.Question.AudioMediaID, q.UploadedClip = synced.Question.AudioMediaID, synced.UploadedClip
+		created := make(map[int]int)
+		for _, answer := range synced.Answers {
+			if answer.serial != 0 {
+				created[answer.serial] = answer.ID
+			}
+		}
+		for n := range q.Answers {
+			if answer := &q.Answers[n]; answer.ID == 0 && answer.serial != 0 {
+				answer.ID = created[answer.serial]
+			}
+		}
+		// Answers created and then removed meanwhile are in the loaded answers, so the next save deletes them
+		q.LoadedAnswers = synced.LoadedAnswers
+	}
+	m.DeletedQuestions = deletions
+	m.draftSynced(msg.saved && unchanged)
+}
+
+func containsQuestion(questions []Question, id int) bool {
+	for _, question := range questions {
+		if question.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// save saves the editor to the backend, leaving it to the autosave while a background sync is running
+func (m *DynamicQuizModel) save() {
+	if m.Syncing {
+		logger.Info("Quiz draft being synced, saving it with the next autosave", "quizID", m.QuizID)
+		m.draftSynced(false)
+		return
+	}
+	m.draftSynced(m.saveResponsesToBackend())
+}
+
+// backendReachable checks the backend's health endpoint
+func backendReachable() bool {
+	client := &http.Client{Transport: http.DefaultClient.Transport, Timeout: draftHealthTimeout}
+	resp, err := client.Get(config.AppConfig.BackendURL + "/healthz")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// InitialDynamicQuizModelFromDraft opens the editor on a draft left by a previous session,
+// asking whether to keep it before it is synced to the backend
+func InitialDynamicQuizModelFromDraft(d Draft) DynamicQuizModel {
+	logger.Info("Restoring quiz draft", "quizID", d.QuizID, "questions", len(d.Questions), "savedAt", d.SavedAt)
+	m := DynamicQuizModel{
+		QuizID:           d.QuizID,
+		Model:            common.Model{CurrentScreen: "QuizMetadata", Focused: d.Focused, Buttons: []common.Button{{Label: d.ButtonLabel}}},
+		DeletedQuestions: d.DeletedQuestions,
+	}
+	questionCount := len(d.Questions)
+	m.QuestionForms = make([]questionForms, questionCount)
+	for i, q := range d.Questions {
+		m.QuestionForms[i] = questionForms{Question: q.Question, Answers: q.Answers, LoadedAnswers: q.LoadedAnswers, UploadedClip: q.UploadedClip,
+			Points: q.Points, MultiChoiceAnsLimit: q.MultiChoiceAnsLimit, AudioClipFile: q.AudioClipFile}
+	}
+	if d.CurrentFormGroup >= 0 && d.CurrentFormGroup < questionCount {
+		m.CurrentFormGroup = d.CurrentFormGroup
+	}
+	m.buildForms()
+	_, m.DraftState = m.draft()
+
+	quiz := "the new quiz"
+	if d.QuizID != 0 {
+		quiz = "quiz " + strconv.Itoa(d.QuizID)
+	}
+	m.PendingRestore = fmt.Sprintf("**Restore the unsaved draft of %s?**\n\nIt has %d questions and was autosaved on %s.",
+		quiz, questionCount, d.SavedAt.Local().Format("Jan 2 at 15:04"))
+	return m
+}
+
+// confirmRestore handles the keys while asked about a restored draft: y keeps it and saves it to the backend,
+// or leaves that to the autosave while the backend is down, d discards it and loads the quiz from the backend
+func (m DynamicQuizModel) confirmRestore(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		logger.Info("Quiz draft restored", "quizID", m.QuizID)
+		m.PendingRestore = ""
+		m.DraftUnsynced = true
+		return m, m.autosave()
+	case "d":
+		logger.Info("Quiz draft discarded", "quizID", m.QuizID)
+		m.PendingRestore = ""
+		m.draftSynced(true) // removes the draft
+		m.CurrentFormGroup = 0
+		m.DeletedQuestions = nil
+		m.initForm()
+		_, m.DraftState = m.draft()
+		return m, m.QuestionForms[m.CurrentFormGroup].Form.Init()
+	case "esc":
+		return m, tea.Quit
+	}
+	return m, nil
+}
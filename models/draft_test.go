@@ -0,0 +1,218 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/config"
+)
+
+// savedQuestion is testQuestion without an audio file to upload, so it saves without reading files
+func savedQuestion(n int) questionForms {
+	q := testQuestion(n)
+	q.AudioClipFile = ""
+	return q
+}
+
+// draftExists reports whether the draft of quiz 7 is on disk
+func draftExists(t *testing.T) bool {
+	t.Helper()
+	path, err := draftPath(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// syncDraft runs a background sync returned by autosave and hands its outcome to the editor
+func syncDraft(t *testing.T, m DynamicQuizModel, cmd tea.Cmd) DynamicQuizModel {
+	t.Helper()
+	if cmd == nil || !m.Syncing {
+		t.Fatalf("autosave() started no sync, want the unsynced draft synced")
+	}
+	model, _ := UpdateDynamicQuizModel(m, cmd())
+	if m = model.(DynamicQuizModel); m.Syncing {
+		t.Errorf("still syncing after the outcome arrived")
+	}
+	return m
+}
+
+func TestSaveDraft(t *testing.T) {
+	m := newTestEditor(t, testQuestion(1), testQuestion(2))
+	m.CurrentFormGroup = 1
+	m.saveDraft()
+	drafts, err := ListDrafts()
+	if err != nil || len(drafts) != 1 {
+		t.Fatalf("ListDrafts() = %d drafts, %v, want the one saved", len(drafts), err)
+	}
+	d := drafts[0]
+	if d.QuizID != 7 || d.CurrentFormGroup != 1 || d.ButtonLabel != "Update" || len(d.Questions) != 2 || d.SavedAt.IsZero() {
+		t.Errorf("draft = %+v, want quiz 7 on question 2 with both questions", d)
+	}
+	if q := d.Questions[1]; q.Points != "200" || q.MultiChoiceAnsLimit != "2" || q.AudioClipFile != "clip2.ogg" || len(q.LoadedAnswers) != 2 {
+		t.Errorf("second question = %+v, want its inputs and loaded answers", q)
+	}
+
+	// Only changes to the questions write the draft again, not moving between them
+	path, _ := draftPath(7)
+	os.Remove(path)
+	m.CurrentFormGroup = 0
+	if m.saveDraft(); draftExists(t) {
+		t.Errorf("moving to another question wrote the draft")
+	}
+	m.QuestionForms[0].Question.Text = "Edited"
+	if m.saveDraft(); !draftExists(t) {
+		t.Errorf("editing a question did not write the draft")
+	}
+
+	// The drafts of all quizzes are listed, the most recent first, skipping those that cannot be read
+	dir := config.AppConfig.DraftsDir
+	if err := writeDraft(filepath.Join(dir, "quiz-0.json"), Draft{Questions: []draftQuestion{{}}, SavedAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeDraft(filepath.Join(dir, "quiz-8.json"), Draft{QuizID: 8, SavedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "quiz-9.json"), []byte("{"), 0o600)
+	drafts, err = ListDrafts()
+	var quizzes []string
+	for _, d := range drafts {
+		quizzes = append(quizzes, fmt.Sprint(d.QuizID))
+	}
+	if got := strings.Join(quizzes, ","); err != nil || got != "0,7" {
+		t.Errorf("ListDrafts() = %s, %v, want the drafts of quizzes 0,7", got, err)
+	}
+}
+
+func TestRestoreDraft(t *testing.T) {
+	m := newTestEditor(t, savedQuestion(1), savedQuestion(2))
+	m.CurrentFormGroup = 1
+	m.DeletedQuestions = []Question{{ID: 3, Text: "Gone"}}
+	m.saveDraft()
+	d, ok := loadDraft(7)
+	if !ok {
+		t.Fatal("no draft saved")
+	}
+
+	r := InitialDynamicQuizModelFromDraft(d)
+	if !strings.Contains(r.PendingRestore, "quiz 7") || !strings.Contains(r.PendingRestore, "2 questions") {
+		t.Errorf("PendingRestore = %q, want the quiz and its number of questions", r.PendingRestore)
+	}
+	if got, want := layout(r), layout(m); got != want || r.CurrentFormGroup != 1 || len(r.DeletedQuestions) != 1 {
+		t.Errorf("restored %s on %d deleting %v, want %s on 1 deleting question 3", got, r.CurrentFormGroup, r.DeletedQuestions, want)
+	}
+	if r.autosave() != nil {
+		t.Errorf("autosave() synced a draft not restored yet")
+	}
+
+	up := false
+	requests := testBackend(t, func(r *http.Request) (int, string) {
+		switch {
+		case r.URL.Path == "/healthz" && !up:
+			return http.StatusServiceUnavailable, ""
+		case r.Method == http.MethodDelete:
+			return http.StatusNoContent, ""
+		}
+		return http.StatusOK, `{}`
+	})
+	model, cmd := r.confirmRestore(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if r = model.(DynamicQuizModel); r.PendingRestore != "" || !r.DraftUnsynced {
+		t.Fatalf("after y, asking %q, unsynced %v, want the draft kept to sync", r.PendingRestore, r.DraftUnsynced)
+	}
+
+	// While the backend is down the draft is kept, and synced by the next autosave once it is back
+	if r = syncDraft(t, r, cmd); !r.DraftUnsynced || !draftExists(t) {
+		t.Errorf("with the backend down, unsynced %v, want the draft kept", r.DraftUnsynced)
+	}
+	up = true
+	if r = syncDraft(t, r, r.autosave()); r.DraftUnsynced || draftExists(t) || len(r.DeletedQuestions) != 0 {
+		t.Errorf("after syncing, unsynced %v deleting %v, want the draft removed", r.DraftUnsynced, r.DeletedQuestions)
+	}
+	want := "GET /healthz,GET /healthz,DELETE /questions/3,PUT /questions/1,PUT /questions/2"
+	if got := strings.Join(requests(), ","); got != want {
+		t.Errorf("backend received %s, want %s", got, want)
+	}
+}
+
+func TestDiscardDraft(t *testing.T) {
+	m := newTestEditor(t, savedQuestion(1), savedQuestion(2))
+	m.saveDraft()
+	d, _ := loadDraft(7)
+	requests := testBackend(t, func(r *http.Request) (int, string) { return http.StatusOK, `[]` })
+
+	model, _ := InitialDynamicQuizModelFromDraft(d).confirmRestore(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	r := model.(DynamicQuizModel)
+	if r.PendingRestore != "" || draftExists(t) || layout(r) != ":::" {
+		t.Errorf("after d, questions %s, want the draft removed and the quiz loaded from the backend", layout(r))
+	}
+	if got := strings.Join(requests(), ","); got != "GET /quizzes/7/questions" {
+		t.Errorf("backend received %s, want the quiz loaded", got)
+	}
+}
+
+func TestAutosaveMergesEdits(t *testing.T) {
+	newQuestion := func(text string) questionForms {
+		return questionForms{Question: Question{Text: text, Type: "single"}, Answers: []Answer{{Text: "Yes", IsCorrect: true}, {Text: "No"}}}
+	}
+	m := newTestEditor(t, savedQuestion(1), newQuestion("Kept"), newQuestion("Removed"))
+	created := 100
+	requests := testBackend(t, func(r *http.Request) (int, string) {
+		switch r.Method {
+		case http.MethodPost:
+			created++
+			return http.StatusCreated, fmt.Sprintf(`{"id": %d}`, created)
+		case http.MethodDelete:
+			return http.StatusNoContent, ""
+		}
+		return http.StatusOK, `{}`
+	})
+	m.DraftUnsynced = true
+	cmd := m.autosave()
+	if m.autosave() != nil {
+		t.Errorf("autosave() started a second sync while one runs")
+	}
+
+	// Edits made while the sync runs: an answer added and both the saved and the new question removed,
+	// while saving leaves it to the autosave
+	m.QuestionForms[1].Answers = append(m.QuestionForms[1].Answers, Answer{Text: "Maybe"})
+	for _, i := range []int{2, 0} {
+		m = edit(t, m, i, KeyDeleteQuestion)
+		model, _ := m.confirmDelete(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+		m = model.(DynamicQuizModel)
+	}
+	if m.save(); len(requests()) != 0 {
+		t.Fatalf("saving while syncing sent %v", requests())
+	}
+
+	m = syncDraft(t, m, cmd)
+	if got := ids(m.QuestionForms[0].Answers); fmt.Sprint(got) != "[102 103 Maybe]" || m.QuestionForms[0].Question.ID != 101 {
+		t.Errorf("question %d with answers %v, want question 101 with answers 102, 103 and a new one", m.QuestionForms[0].Question.ID, got)
+	}
+	var deleting []int
+	for _, question := range m.DeletedQuestions {
+		deleting = append(deleting, question.ID)
+	}
+	if fmt.Sprint(deleting) != "[1 104]" {
+		t.Errorf("deleting questions %v, want the saved one and the one created by the sync", deleting)
+	}
+	if !m.DraftUnsynced || !draftExists(t) {
+		t.Errorf("unsynced %v, want the draft kept for the edits made meanwhile", m.DraftUnsynced)
+	}
+
+	// The next autosave saves those edits, creating nothing twice
+	before := len(requests())
+	if m = syncDraft(t, m, m.autosave()); m.DraftUnsynced || draftExists(t) {
+		t.Errorf("after syncing the edits, unsynced %v, want the draft removed", m.DraftUnsynced)
+	}
+	want := "GET /healthz,DELETE /questions/1,DELETE /questions/104,PUT /questions/101,POST /answers"
+	if got := strings.Join(requests()[before:], ","); got != want {
+		t.Errorf("backend received %s, want %s", got, want)
+	}
+}
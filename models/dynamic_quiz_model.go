@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +17,8 @@ import (
 	"letsquiz/common"
 	"letsquiz/config"
 	"letsquiz/logger"
+	"letsquiz/music"
+	"letsquiz/outbox"
 	"net/http"
 )
 
@@ -23,19 +27,25 @@ type Answer struct {
 	QuestionId       int       `json:"question_id"`
 	Text             string    `json:"text"`
 	IsCorrect        bool      `json:"is_correct"`
+	Position         int       `json:"position"` // order of the answer within its question
 	CreationDate     time.Time `json:"creation_date"`
 	LastModifiedDate time.Time `json:"last_modified_date"`
+
+	serial int // tells a new answer apart while it is synced in the background, see snapshot
 }
 
 type Question struct {
 	ID                  int       `json:"id"`
 	QuizId              int       `json:"quiz_id"`
+	Position            int       `json:"position"` // order of the question within its quiz
 	Text                string    `json:"text"`
 	Type                string    `json:"type"` // "single" or "multiple"
 	Points              int       `json:"points"`
 	MultiChoiceAnsLimit int       `json:"multi_choice_ans_limit"`
 	HintExplanation     string    `json:"hint_explanation"`
 	DifficultyLevel     string    `json:"difficulty_level"`
+	MediaID             *int      `json:"media_id,omitempty"`       // attached media, served by GET /media/{id}
+	AudioMediaID        *int      `json:"audio_media_id,omitempty"` // audio clip played on request, see saveAudioClip
 	CreationDate        time.Time `json:"creation_date"`
 	LastModifiedDate    time.Time `json:"last_modified_date"`
 }
@@ -43,32 +53,57 @@ type Question struct {
 type questionForms struct {
 	*huh.Form
 	Question      Question `json:"question"`
-	Answers       []Answer `json:"answers"`
-	CorrectAnswer string   `json:"correct_answer"`
+	Answers       []Answer `json:"answers"` // edited in place by the AnswerList field
+	LoadedAnswers []Answer `json:"-"`       // the answers as last loaded or saved, see diffAnswers
+	UploadedClip  string   `json:"-"`       // audio file already uploaded for the question, to avoid uploading it on every save
+
+	// The inputs typed as text, converted into Question by saveCurrentFormData
+	Points              string `json:"-"`
+	MultiChoiceAnsLimit string `json:"-"`
+	AudioClipFile       string `json:"-"` // audio file to attach, or removeAudioClip
+
+	serial int // tells the question apart while it is synced in the background, see snapshot
 }
 
+// markdownDescription explains the Markdown text areas of the question form
+const markdownDescription = "Markdown: **bold**, `code`, lists and ``` fenced code blocks. Alt+Enter for a new line"
+
+// removeAudioClip is entered in the audio clip input to detach the current clip
+const removeAudioClip = "none"
+
 type DynamicQuizModel struct {
 	common.Model
 	QuizID           int
 	CurrentFormGroup int
 	QuestionForms    []questionForms
 	TotalFormGroups  int
+	PendingSave      string     // Markdown summary of the changes awaiting confirmation, empty when none
+	PendingDelete    string     // Markdown asking to confirm deleting the current question, empty when none
+	DeletedQuestions []Question // saved questions removed in the editor, deleted on the backend when the quiz is saved
+	PendingRestore   string     // Markdown asking whether to keep a draft left by a previous session, empty when none
+	DraftState       string     // the questions as last autosaved or synced, see saveDraft
+	DraftUnsynced    bool       // a save to the backend failed or a restored draft awaits it, see autosave
+	Syncing          bool       // the draft is being synced in the background, see autosave
+
+	lastSerial int // the last serial given to a question or answer, see snapshot
 }
 
-var answersCommaSeparated, questionScorePoint, multiChoiceAnsLimit []string
-
-// Initialize forms for each question
-func InitialDynamicQuizModel(quizID, questionCount int, focused string, buttons []common.Button) DynamicQuizModel {
-	logger.Info("InitialDynamicQuizModel called", "quizID", quizID, "questionCount", questionCount)
+// Initialize forms for each question of the quiz, or a single blank one for a quiz without questions.
+// A draft of the quiz left by a previous session is offered instead, see InitialDynamicQuizModelFromDraft.
+func InitialDynamicQuizModel(quizID int, focused string, buttons []common.Button) DynamicQuizModel {
+	logger.Info("InitialDynamicQuizModel called", "quizID", quizID)
+	if d, ok := loadDraft(quizID); ok {
+		m := InitialDynamicQuizModelFromDraft(d)
+		m.Focused, m.Buttons = focused, buttons
+		return m
+	}
 	m := DynamicQuizModel{
 		QuizID:           quizID,
 		CurrentFormGroup: 0,
-		QuestionForms:    make([]questionForms, questionCount),
-		TotalFormGroups:  questionCount,
 		Model:            common.Model{CurrentScreen: "QuizMetadata", Focused: focused, Buttons: buttons},
 	}
-	answersCommaSeparated, questionScorePoint, multiChoiceAnsLimit = make([]string, questionCount), make([]string, questionCount), make([]string, questionCount)
 	m.initForm()
+	_, m.DraftState = m.draft()
 	logger.Info("Initialized DynamicQuizModel", "quizID", quizID, "totalSteps", m.TotalFormGroups)
 	return m
 }
@@ -82,36 +117,41 @@ func (m *DynamicQuizModel) initForm() {
 	if err != nil {
 		logger.Error("Failed to fetch existing questions and answers", "error", err)
 	}
+	m.QuestionForms = existingQuestionForms
+	if len(m.QuestionForms) == 0 {
+		m.QuestionForms = []questionForms{{}} // More questions are added from the editor, see KeyNewQuestion
+	}
 
-	for i := 0; i < m.TotalFormGroups; i++ {
-		if m.QuestionForms[i].Form == nil {
-			m.QuestionForms[i] = questionForms{
-				Form: huh.NewForm(),
-			}
+	for i := range m.QuestionForms {
+		if q := &m.QuestionForms[i]; q.Question.ID != 0 {
+			q.Points = strconv.Itoa(q.Question.Points)
+			q.MultiChoiceAnsLimit = strconv.Itoa(q.Question.MultiChoiceAnsLimit)
 		}
+	}
+	m.buildForms()
 
-		q := &m.QuestionForms[i]
-
-		if i < len(existingQuestionForms) {
-			*q = existingQuestionForms[i]
+	logger.Info("Initialized form with groups for all questions")
+}
 
-			var answers []string
-			for _, ans := range q.Answers {
-				answers = append(answers, ans.Text)
-			}
-			answersCommaSeparated[i] = strings.Join(answers, ",")
-			questionScorePoint[i] = strconv.Itoa(q.Question.Points)
-			multiChoiceAnsLimit[i] = strconv.Itoa(q.Question.MultiChoiceAnsLimit)
-		}
+// buildForms creates the form of every question. The forms are bound to the fields of the questions,
+// so they are built again whenever questions are added, removed or moved.
+func (m *DynamicQuizModel) buildForms() {
+	m.TotalFormGroups = len(m.QuestionForms)
+	for i := range m.QuestionForms {
+		q := &m.QuestionForms[i]
 
 		group := huh.NewGroup(
-			huh.NewInput().Key("text").
+			huh.NewText().Key("text").
 				Title(fmt.Sprintf("Enter text for Question %d", i+1)).
+				Description(markdownDescription).
 				Placeholder("Question text").
+				Lines(4).
 				Value(&q.Question.Text),
-			huh.NewInput().Key("hint_explanation").
+			huh.NewText().Key("hint_explanation").
 				Title("Enter question hints for help").
+				Description(markdownDescription).
 				Placeholder("Hints here").
+				Lines(3).
 				Value(&q.Question.HintExplanation),
 			huh.NewSelect[string]().Key("type").
 				Title("Type of Question").
@@ -131,11 +171,11 @@ func (m *DynamicQuizModel) initForm() {
 					}
 					return nil
 				}).
-				Value(&multiChoiceAnsLimit[i]),
+				Value(&q.MultiChoiceAnsLimit),
 			huh.NewInput().Key("points").
 				Title("Enter the score for correct answer").
 				Placeholder("Score points here").
-				Value(&questionScorePoint[i]),
+				Value(&q.Points),
 			huh.NewSelect[string]().Key("difficulty_level").
 				Title("Difficulty Level").
 				Options(
@@ -144,26 +184,28 @@ func (m *DynamicQuizModel) initForm() {
 					huh.NewOption("Hard", "hard"),
 				).
 				Value(&q.Question.DifficultyLevel),
-			huh.NewInput().Key("answers").
+			NewAnswerList().Key("answers").
 				Title("Enter your answers").
-				Placeholder("Comma separated values within \"\" here").
-				Value(&answersCommaSeparated[i]),
-			huh.NewInput().Key("correct_answer").
-				Title("Enter the correct answer").
-				Placeholder("Correct answer (match case) here").
-				Value(&q.CorrectAnswer),
+				Description("Markdown is supported, e.g. `code`. Mark the correct answers with ctrl+x").
+				Validate(validateAnswers(q)).
+				Value(&q.Answers),
+			huh.NewInput().Key("audio_clip").
+				Title("Attach an audio clip (optional)").
+				Description(audioClipDescription(q.Question.AudioMediaID)).
+				Placeholder("Path to an MP3, OGG or WAV file").
+				Validate(validateAudioClipFile).
+				Value(&q.AudioClipFile),
 		)
 
 		form := huh.NewForm(group)
 		m.QuestionForms[i].Form = form
 	}
-
-	logger.Info("Initialized form with groups for all questions")
+	m.resizeForms()
 }
 
-// Check if answers exist for the current question
-func (m *DynamicQuizModel) checkIfAnswersExist() (bool, []Answer, error) {
-	url := fmt.Sprintf("%s/questions/%d/answers", config.AppConfig.BackendURL, m.QuestionForms[m.CurrentFormGroup].Question.ID)
+// Check if answers exist for a question
+func (m *DynamicQuizModel) checkIfAnswersExist(questionID int) (bool, []Answer, error) {
+	url := fmt.Sprintf("%s/questions/%d/answers", config.AppConfig.BackendURL, questionID)
 	logger.Info("Checking if answers exist", "url", url)
 	resp, err := http.Get(url)
 	if err != nil {
@@ -179,7 +221,7 @@ func (m *DynamicQuizModel) checkIfAnswersExist() (bool, []Answer, error) {
 			logger.Error("Failed to decode answers response", "error", err)
 			return false, nil, err
 		}
-		logger.Info("Answers exist", "answers", answers)
+		logger.Debug("Answers exist", "answers", answers)
 		return true, answers, nil
 	}
 	logger.Info("Answers do not exist")
@@ -223,7 +265,7 @@ func (m *DynamicQuizModel) fetchQuestionsAndAnswers() ([]questionForms, error) {
 
 	var questionFormsList []questionForms
 	for _, question := range questions {
-		answersExist, answers, err := m.checkIfAnswersExist()
+		answersExist, answers, err := m.checkIfAnswersExist(question.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -233,37 +275,38 @@ func (m *DynamicQuizModel) fetchQuestionsAndAnswers() ([]questionForms, error) {
 		}
 		if answersExist {
 			qForm.Answers = answers
-			// Find the correct answer
-			for _, ans := range answers {
-				if ans.IsCorrect {
-					qForm.CorrectAnswer = ans.Text
-					break
-				}
-			}
+			qForm.LoadedAnswers = append([]Answer(nil), answers...)
 		}
 
 		questionFormsList = append(questionFormsList, qForm)
 	}
 
-	logger.Info("Fetched existing questions and answers", "questionFormsList", questionFormsList)
+	logger.Debug("Fetched existing questions and answers", "questionFormsList", questionFormsList)
 	return questionFormsList, nil
 }
 
-// Save responses to the backend
-func (m *DynamicQuizModel) saveResponsesToBackend() {
-	logger.Info("Saving responses to backend", "responsesCount", len(m.QuestionForms))
-	quizExists, _, err := m.checkIfQuizExists()
-	if err != nil {
-		logger.Error("Error checking if quiz exists", "error", err)
-	}
-
-	if !quizExists {
-		m.postResponseToBackend()
-		return
+// Save responses to the backend through the outbox, creating the questions without an ID and updating
+// the others, reporting whether everything was saved or queued
+func (m *DynamicQuizModel) saveResponsesToBackend() bool {
+	logger.Info("Saving responses to backend", "responsesCount", len(m.QuestionForms), "deleted", len(m.DeletedQuestions))
+	saved := true
+
+	// Questions removed in the editor are deleted first, those that fail are kept to be deleted on the next save
+	var notDeleted []Question
+	for _, question := range m.DeletedQuestions {
+		if err := m.deleteQuestionOnBackend(question.ID); err != nil {
+			logger.Error("Failed to delete question on backend", "questionID", question.ID, "error", err)
+			notDeleted = append(notDeleted, question)
+			saved = false
+		}
 	}
+	m.DeletedQuestions = notDeleted
 
-	for i, q := range m.QuestionForms {
-		logger.Info("Processing form data", "formIndex", i, "formData", q)
+	for i := range m.QuestionForms {
+		// The question refers to its clip, which is uploaded first
+		saved = m.saveAudioClip(i) && saved
+		q := m.QuestionForms[i]
+		logger.Debug("Processing form data", "formIndex", i, "formData", q)
 		currentDate := time.Now().UTC()
 		q.Question.CreationDate = currentDate
 		q.Question.LastModifiedDate = currentDate
@@ -272,96 +315,71 @@ func (m *DynamicQuizModel) saveResponsesToBackend() {
 		questionResponse := map[string]interface{}{
 			"quiz_id":                m.QuizID,
 			"id":                     q.Question.ID,
+			"position":               i,
 			"text":                   q.Question.Text,
 			"type":                   q.Question.Type,
 			"points":                 q.Question.Points,
 			"hint_explanation":       q.Question.HintExplanation,
 			"difficulty_level":       q.Question.DifficultyLevel,
 			"multi_choice_ans_limit": q.Question.MultiChoiceAnsLimit,
+			"media_id":               q.Question.MediaID,
+			"audio_media_id":         q.Question.AudioMediaID,
 			"creation_date":          q.Question.CreationDate.Format(time.RFC3339),
 			"last_modified_date":     q.Question.LastModifiedDate.Format(time.RFC3339),
 		}
-		logger.Info("Sending question to backend (PUT)", "questionID", q.Question.ID, "questionResponse", questionResponse)
-		questionID, err := m.postQuestionToBackend(questionResponse)
+		logger.Debug("Sending question to backend", "questionID", q.Question.ID, "questionResponse", questionResponse)
+		questionID, err := m.sendQuestionToBackend(q.Question.ID, questionResponse)
 		if err != nil {
 			logger.Error("Failed to send question to backend", "error", err)
+			saved = false
 			continue
 		}
 
 		// Update the question ID in the model
 		m.QuestionForms[i].Question.ID = questionID
+		saved = m.saveAnswers(i, questionID) && saved
+	}
+	logger.Info("All questions and answers saved to backend", "saved", saved)
+	return saved
+}
 
-		// Clear the Answers list to avoid duplication
-		q.Answers = nil
-
-		// Process and append answers
-		answerTexts := strings.Split(answersCommaSeparated[i], ",")
-		for _, answerText := range answerTexts {
-			if strings.TrimSpace(answerText) == q.CorrectAnswer {
-				q.Answers = append(q.Answers, Answer{QuestionId: questionID, Text: strings.TrimSpace(answerText), IsCorrect: true})
-			} else {
-				q.Answers = append(q.Answers, Answer{QuestionId: questionID, Text: strings.TrimSpace(answerText), IsCorrect: false})
-			}
-		}
-
-		logger.Info("Processed answers for question", "questionID", questionID, "answers", q.Answers)
-
-		// Send answers to the backend
-		for _, ans := range q.Answers {
-			ans.CreationDate = currentDate
-			ans.LastModifiedDate = currentDate
-			ans.QuestionId = questionID
-
-			answerResponse := map[string]interface{}{
-				"question_id":        questionID,
-				"text":               ans.Text,
-				"is_correct":         ans.IsCorrect,
-				"creation_date":      ans.CreationDate.Format(time.RFC3339),
-				"last_modified_date": ans.LastModifiedDate.Format(time.RFC3339),
-			}
-			logger.Info("Sending answer to backend", "answer", ans, "answerResponse", answerResponse)
-			err := m.postAnswerToBackend(answerResponse)
-			if err != nil {
-				logger.Error("Failed to send answer to backend", "error", err)
-			}
-		}
+// sendQuestionToBackend updates a saved question or creates a new one, returning its ID
+func (m *DynamicQuizModel) sendQuestionToBackend(questionID int, questionData map[string]interface{}) (int, error) {
+	if questionID == 0 {
+		return m.postQuestionToBackend(questionData)
 	}
-	logger.Info("All questions and answers saved to backend")
+	return questionID, m.putQuestionToBackend(questionID, questionData)
 }
 
 // Post a question to the backend and return its ID
 func (m *DynamicQuizModel) postQuestionToBackend(questionData map[string]interface{}) (int, error) {
-	url := config.AppConfig.BackendURL + "/questions"
 	jsonData, err := json.Marshal(questionData)
 	if err != nil {
 		logger.Error("Failed to marshal question data", "questionData", questionData, "error", err)
 		return 0, err
 	}
-	logger.Info("POSTing question data", "url", url, "data", string(jsonData))
+	logger.Debug("POSTing question data", "path", "/questions", "data", string(jsonData))
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := outbox.Send(http.MethodPost, "/questions", "application/json", jsonData, changeDescription("Create question", questionData["text"]))
 	if err != nil {
-		logger.Error("Failed to send question to backend", "url", url, "error", err)
+		logger.Error("Failed to send question to backend", "error", err)
 		return 0, err
 	}
-	defer resp.Body.Close()
+	if resp.Queued {
+		logger.Info("Question queued for the backend", "provisionalID", resp.ProvisionalID)
+		return resp.ProvisionalID, nil
+	}
 
 	// Handle non-successful status codes
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyString := string(bodyBytes)
+		bodyString := string(resp.Body)
 		logger.Error("Failed to save question, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
 		return 0, fmt.Errorf("failed to save question, status code: %d, body: %s", resp.StatusCode, bodyString)
 	}
 
 	// Parse the response to extract the question ID
 	var response map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err == io.EOF {
-		// Handle the case where the response body is empty
-		logger.Error("Failed to decode response, response body is empty", "error", err)
-		return 0, fmt.Errorf("failed to decode response, response body is empty")
-	} else if err != nil {
+	if err := json.Unmarshal(resp.Body, &response); err != nil {
 		logger.Error("Failed to decode response", "error", err)
 		return 0, err
 	}
@@ -376,44 +394,404 @@ func (m *DynamicQuizModel) postQuestionToBackend(questionData map[string]interfa
 	return int(id), nil
 }
 
-// Post an answer to the backend
-func (m *DynamicQuizModel) postAnswerToBackend(answerData map[string]interface{}) error {
-	url := config.AppConfig.BackendURL + "/answers"
-	jsonData, err := json.Marshal(answerData)
+// Update an existing question on the backend
+func (m *DynamicQuizModel) putQuestionToBackend(questionID int, questionData map[string]interface{}) error {
+	path := fmt.Sprintf("/questions/%d", questionID)
+	jsonData, err := json.Marshal(questionData)
 	if err != nil {
-		logger.Error("Failed to marshal answer data", "answerData", answerData, "error", err)
+		logger.Error("Failed to marshal question data", "questionData", questionData, "error", err)
 		return err
 	}
+	logger.Debug("PUTting question data", "path", path, "data", string(jsonData))
+
+	resp, err := outbox.Send(http.MethodPut, path, "application/json", jsonData, changeDescription("Update question", questionData["text"]))
+	if err != nil {
+		logger.Error("Failed to send question to backend", "path", path, "error", err)
+		return err
+	}
+
+	if !resp.Queued && resp.StatusCode != http.StatusOK {
+		bodyString := string(resp.Body)
+		logger.Error("Failed to update question, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
+		return fmt.Errorf("failed to update question, status code: %d, body: %s", resp.StatusCode, bodyString)
+	}
+	logger.Info("Successfully updated question", "questionID", questionID, "queued", resp.Queued)
+	return nil
+}
 
-	logger.Info("POSTing answer data", "url", url, "data", string(jsonData))
+// Delete a question with its answers on the backend, a question that is already gone counts as deleted
+func (m *DynamicQuizModel) deleteQuestionOnBackend(questionID int) error {
+	path := fmt.Sprintf("/questions/%d", questionID)
+	logger.Debug("DELETEing question", "path", path)
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := outbox.Send(http.MethodDelete, path, "", nil, fmt.Sprintf("Delete question %d", questionID))
 	if err != nil {
-		logger.Error("Failed to send answer to backend", "url", url, "error", err)
+		logger.Error("Failed to send question deletion to backend", "path", path, "error", err)
 		return err
 	}
-	defer resp.Body.Close()
+
+	if !resp.Queued && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		bodyString := string(resp.Body)
+		logger.Error("Failed to delete question, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
+		return fmt.Errorf("failed to delete question, status code: %d, body: %s", resp.StatusCode, bodyString)
+	}
+	logger.Info("Successfully deleted question", "questionID", questionID, "queued", resp.Queued)
+	return nil
+}
+
+// audioClipDescription explains the audio clip input, mentioning the clip already attached
+func audioClipDescription(mediaID *int) string {
+	if mediaID == nil {
+		return "Played in the quiz on request, leave blank for none"
+	}
+	return fmt.Sprintf("Current clip: media %d. Leave blank to keep it, or enter %q to remove it", *mediaID, removeAudioClip)
+}
+
+// validateAnswers checks the answers of a question against its type and multiple choice answer limit
+func validateAnswers(q *questionForms) func([]Answer) error {
+	return func(answers []Answer) error {
+		if len(answers) < 2 {
+			return errors.New("Enter at least two answers, add one with ctrl+n.")
+		}
+		seen := make(map[string]bool)
+		correct := 0
+		for n, answer := range answers {
+			if answer.Text == "" {
+				return fmt.Errorf("Answer %d is empty, enter its text or remove it with ctrl+d.", n+1)
+			}
+			if seen[answer.Text] {
+				return fmt.Errorf("Answer %d is a duplicate.", n+1)
+			}
+			seen[answer.Text] = true
+			if answer.IsCorrect {
+				correct++
+			}
+		}
+		if q.Question.Type == "multiple" {
+			limit, _ := strconv.Atoi(q.MultiChoiceAnsLimit)
+			if correct == 0 {
+				return errors.New("Mark at least one correct answer with ctrl+x.")
+			} else if limit > 0 && correct > limit {
+				return fmt.Errorf("Mark no more correct answers than the multiple choice answer limit of %d.", limit)
+			}
+		} else if correct != 1 {
+			return errors.New("Mark exactly one correct answer with ctrl+x, or make this a multiple choice question.")
+		}
+		return nil
+	}
+}
+
+// validateAudioClipFile accepts a blank value, removeAudioClip or an existing audio file
+func validateAudioClipFile(val string) error {
+	val = strings.TrimSpace(val)
+	if val == "" || val == removeAudioClip {
+		return nil
+	}
+	if !music.IsAudioFile(val) {
+		return errors.New("The audio clip must be an .mp3, .ogg or .wav file.")
+	}
+	if info, err := os.Stat(val); err != nil || info.IsDir() {
+		return fmt.Errorf("Cannot read the audio clip %q.", val)
+	}
+	return nil
+}
+
+// saveAudioClip uploads the audio file entered for question i as media, skipping files that were uploaded
+// already, or detaches its clip. The question refers to the clip, so it is saved with the question.
+// It reports whether the clip is saved.
+func (m *DynamicQuizModel) saveAudioClip(i int) bool {
+	q := &m.QuestionForms[i]
+	source := strings.TrimSpace(q.AudioClipFile)
+	if source == "" || source == q.UploadedClip {
+		return true
+	}
+	if source == removeAudioClip {
+		q.Question.AudioMediaID = nil
+		q.UploadedClip = source
+		return true
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		logger.Error("Failed to read audio clip", "questionID", q.Question.ID, "file", source, "error", err)
+		return false
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(source))
+	if err == nil {
+		_, err = part.Write(data)
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	if err != nil {
+		logger.Error("Failed to prepare audio clip upload", "file", source, "error", err)
+		return false
+	}
+	resp, err := outbox.Send(http.MethodPost, "/media", form.FormDataContentType(), body.Bytes(), "Upload "+filepath.Base(source))
+	if err != nil {
+		logger.Error("Failed to send audio clip to backend", "file", source, "error", err)
+		return false
+	}
+
+	id := resp.ProvisionalID
+	if !resp.Queued {
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+			logger.Error("Failed to save audio clip, invalid status code", "statusCode", resp.StatusCode, "body", string(resp.Body))
+			return false
+		}
+		var media struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(resp.Body, &media); err != nil || media.ID == 0 {
+			logger.Error("Failed to decode media response", "body", string(resp.Body), "error", err)
+			return false
+		}
+		id = media.ID
+	}
+	q.Question.AudioMediaID = &id
+	q.UploadedClip = source
+	logger.Info("Successfully saved audio clip", "questionID", q.Question.ID, "file", source, "mediaID", id, "queued", resp.Queued)
+	return true
+}
+
+// saveAnswers sends the changes to the answers of question i since they were loaded or last saved:
+// new answers are created, edited or moved ones updated and removed ones deleted.
+// It reports whether all changes were saved.
+func (m *DynamicQuizModel) saveAnswers(i, questionID int) bool {
+	q := &m.QuestionForms[i]
+	changes := diffAnswers(q.LoadedAnswers, q.Answers)
+	if changes.empty() {
+		return true
+	}
+	logger.Info("Saving answer changes", "questionID", questionID, "changes", changes.summary())
+
+	failed := false
+	for _, ans := range changes.Deleted {
+		if err := m.deleteAnswerOnBackend(ans.ID); err != nil {
+			logger.Error("Failed to delete answer on backend", "answerID", ans.ID, "error", err)
+			failed = true
+		}
+	}
+
+	currentDate := time.Now().UTC()
+	for n := range q.Answers {
+		ans := &q.Answers[n]
+		ans.QuestionId = questionID
+		ans.Text = strings.TrimSpace(ans.Text)
+		ans.Position = n
+		if ans.ID != 0 && !changes.saves(ans.ID) {
+			continue
+		}
+		if ans.CreationDate.IsZero() {
+			ans.CreationDate = currentDate
+		}
+		ans.LastModifiedDate = currentDate
+
+		answerResponse := map[string]interface{}{
+			"question_id":        questionID,
+			"text":               ans.Text,
+			"is_correct":         ans.IsCorrect,
+			"position":           ans.Position,
+			"creation_date":      ans.CreationDate.Format(time.RFC3339),
+			"last_modified_date": ans.LastModifiedDate.Format(time.RFC3339),
+		}
+		logger.Debug("Sending answer to backend", "answerID", ans.ID, "answerResponse", answerResponse)
+		if ans.ID != 0 {
+			if err := m.putAnswerToBackend(ans.ID, answerResponse); err != nil {
+				logger.Error("Failed to update answer on backend", "answerID", ans.ID, "error", err)
+				failed = true
+			}
+			continue
+		}
+		answerID, err := m.postAnswerToBackend(answerResponse)
+		if err != nil {
+			logger.Error("Failed to send answer to backend", "error", err)
+			failed = true
+			continue
+		}
+		ans.ID = answerID
+	}
+
+	// After a failure the next save compares against the old state again, retrying what did not go through
+	if !failed {
+		q.LoadedAnswers = append([]Answer(nil), q.Answers...)
+	}
+	return !failed
+}
+
+// Post an answer to the backend and return its ID
+func (m *DynamicQuizModel) postAnswerToBackend(answerData map[string]interface{}) (int, error) {
+	jsonData, err := json.Marshal(answerData)
+	if err != nil {
+		logger.Error("Failed to marshal answer data", "answerData", answerData, "error", err)
+		return 0, err
+	}
+
+	logger.Debug("POSTing answer data", "path", "/answers", "data", string(jsonData))
+
+	resp, err := outbox.Send(http.MethodPost, "/answers", "application/json", jsonData, changeDescription("Create answer", answerData["text"]))
+	if err != nil {
+		logger.Error("Failed to send answer to backend", "error", err)
+		return 0, err
+	}
+	if resp.Queued {
+		logger.Info("Answer queued for the backend", "provisionalID", resp.ProvisionalID)
+		return resp.ProvisionalID, nil
+	}
 
 	// Handle non-successful status codes
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyString := string(bodyBytes)
+		bodyString := string(resp.Body)
 		logger.Error("Failed to save answer, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
-		return fmt.Errorf("failed to save answer, status code: %d, body: %s", resp.StatusCode, bodyString)
+		return 0, fmt.Errorf("failed to save answer, status code: %d, body: %s", resp.StatusCode, bodyString)
+	}
+
+	var answer Answer
+	if err := json.Unmarshal(resp.Body, &answer); err != nil {
+		logger.Error("Failed to decode answer response", "error", err)
+		return 0, err
+	}
+	logger.Info("Successfully posted answer", "answerID", answer.ID)
+	return answer.ID, nil
+}
+
+// Delete an answer on the backend, an answer that is already gone counts as deleted
+func (m *DynamicQuizModel) deleteAnswerOnBackend(answerID int) error {
+	path := fmt.Sprintf("/answers/%d", answerID)
+	logger.Debug("DELETEing answer", "path", path)
+
+	resp, err := outbox.Send(http.MethodDelete, path, "", nil, fmt.Sprintf("Delete answer %d", answerID))
+	if err != nil {
+		logger.Error("Failed to send answer deletion to backend", "path", path, "error", err)
+		return err
+	}
+
+	if !resp.Queued && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		bodyString := string(resp.Body)
+		logger.Error("Failed to delete answer, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
+		return fmt.Errorf("failed to delete answer, status code: %d, body: %s", resp.StatusCode, bodyString)
+	}
+	logger.Info("Successfully deleted answer", "answerID", answerID, "queued", resp.Queued)
+	return nil
+}
+
+// Update an existing answer on the backend
+func (m *DynamicQuizModel) putAnswerToBackend(answerID int, answerData map[string]interface{}) error {
+	path := fmt.Sprintf("/answers/%d", answerID)
+	jsonData, err := json.Marshal(answerData)
+	if err != nil {
+		logger.Error("Failed to marshal answer data", "answerData", answerData, "error", err)
+		return err
+	}
+
+	logger.Debug("PUTting answer data", "path", path, "data", string(jsonData))
+
+	resp, err := outbox.Send(http.MethodPut, path, "application/json", jsonData, changeDescription("Update answer", answerData["text"]))
+	if err != nil {
+		logger.Error("Failed to send answer to backend", "path", path, "error", err)
+		return err
 	}
-	logger.Info("Successfully posted answer")
+
+	if !resp.Queued && resp.StatusCode != http.StatusOK {
+		bodyString := string(resp.Body)
+		logger.Error("Failed to update answer, invalid status code", "statusCode", resp.StatusCode, "body", bodyString)
+		return fmt.Errorf("failed to update answer, status code: %d, body: %s", resp.StatusCode, bodyString)
+	}
+	logger.Info("Successfully updated answer", "answerID", answerID, "queued", resp.Queued)
 	return nil
 }
 
+// changeDescription names a change for the pending changes screen, quoting the start of the text it saves
+func changeDescription(action string, text interface{}) string {
+	t, _ := text.(string)
+	t = strings.Join(strings.Fields(t), " ")
+	if r := []rune(t); len(r) > 40 {
+		t = string(r[:40]) + "…"
+	}
+	return fmt.Sprintf("%s %q", action, t)
+}
+
+// PreviewLayout splits the width inside the window boundary between the form and the Markdown preview,
+// placing the preview beside the form when the window is wide enough and below it otherwise
+func (m DynamicQuizModel) PreviewLayout() (formWidth, previewWidth int, sideBySide bool) {
+	width := m.WindowWidth - 24
+	if width >= 100 {
+		formWidth = width / 2
+		return formWidth, width - formWidth - 2, true
+	}
+	return width, width, false
+}
+
+// PreviewMarkdown returns the current question as the player will see it, with its answers and hints,
+// reflecting the form as it is being edited
+func (m DynamicQuizModel) PreviewMarkdown() string {
+	q := m.QuestionForms[m.CurrentFormGroup]
+	var preview strings.Builder
+	preview.WriteString(q.Question.Text)
+	preview.WriteString("\n\n**Answers**\n")
+	for _, answer := range q.Answers {
+		if strings.TrimSpace(answer.Text) == "" {
+			continue
+		}
+		marker := "- "
+		if answer.IsCorrect {
+			marker = "- ✓ "
+		}
+		preview.WriteString(marker + strings.TrimSpace(answer.Text) + "\n")
+	}
+	if q.Question.HintExplanation != "" {
+		preview.WriteString("\n**Hints**\n\n" + q.Question.HintExplanation)
+	}
+	return preview.String()
+}
+
+// resizeForms fits the question forms to their share of the window
+func (m *DynamicQuizModel) resizeForms() {
+	formWidth, _, _ := m.PreviewLayout()
+	for i := range m.QuestionForms {
+		if m.QuestionForms[i].Form != nil && formWidth > 0 {
+			m.QuestionForms[i].Form = m.QuestionForms[i].Form.WithWidth(formWidth)
+		}
+	}
+}
+
 // UpdateDynamicQuizModel handles the updates and state transitions
 func UpdateDynamicQuizModel(m DynamicQuizModel, msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.WindowWidth = msg.Width
+		m.WindowHeight = msg.Height
+		m.resizeForms()
+		logger.Info("Window size updated", "width", m.WindowWidth, "height", m.WindowHeight)
+	case draftTickMsg:
+		return m, tea.Batch(m.autosave(), DraftAutosave())
+	case draftSyncMsg:
+		m.mergeSync(msg)
+		return m, nil
 	case tea.KeyMsg:
+		if m.PendingRestore != "" {
+			return m.confirmRestore(msg)
+		}
+		if m.PendingSave != "" {
+			return m.confirmSave(msg)
+		}
+		if m.PendingDelete != "" {
+			return m.confirmDelete(msg)
+		}
 		logger.Info("UpdateDynamicQuizModel called", "CurrentFormGroup", m.CurrentFormGroup, "msgType", fmt.Sprintf("%T", msg), "Key pressed", msg.String())
 		switch msg.String() {
 		case "ctrl+right":
 			logger.Info("Handling next form step", "CurrentFormGroup", m.CurrentFormGroup)
 			m.saveCurrentFormData()
+			// Moving on saves an existing quiz, list the questions deleted and what will change in its answers first
+			if m.Focused == "table" {
+				if m.PendingSave = m.changesSummary(); m.PendingSave != "" {
+					logger.Info("Awaiting confirmation of changes", "CurrentFormGroup", m.CurrentFormGroup)
+					return m, nil
+				}
+			}
 			m.NextForm()
 			logger.Info("Model updated after NextForm", "CurrentFormGroup", m.CurrentFormGroup)
 			// Check if we have reached the end of forms and the form state is completed
@@ -435,6 +813,13 @@ func UpdateDynamicQuizModel(m DynamicQuizModel, msg tea.Msg) (tea.Model, tea.Cmd
 		case "esc":
 			logger.Info("Handling esc key press", "CurrentFormGroup", m.CurrentFormGroup)
 			return m, tea.Quit
+		case KeyNewQuestion, KeyInsertQuestion, KeyDuplicateQuestion, KeyDeleteQuestion, KeyMoveQuestionEarlier, KeyMoveQuestionLater:
+			logger.Info("Editing the list of questions", "key", msg.String(), "CurrentFormGroup", m.CurrentFormGroup)
+			return m.editQuestionList(msg.String())
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "CurrentFormGroup", m.CurrentFormGroup)
+			music.HandleKey(msg.String())
+			return m, nil
 		}
 	}
 
@@ -453,41 +838,46 @@ func UpdateDynamicQuizModel(m DynamicQuizModel, msg tea.Msg) (tea.Model, tea.Cmd
 	return m, nil
 }
 
+// confirmSave handles the keys while the changes are shown, saving them and moving on with y
+// or returning to the form with n
+func (m DynamicQuizModel) confirmSave(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		logger.Info("Changes confirmed", "CurrentFormGroup", m.CurrentFormGroup)
+		m.PendingSave = ""
+		m.NextForm()
+	case "n", "N", "esc":
+		logger.Info("Changes not confirmed, back to editing", "CurrentFormGroup", m.CurrentFormGroup)
+		m.PendingSave = ""
+	}
+	return m, nil
+}
+
 // Save the current form data to the model
 func (m *DynamicQuizModel) saveCurrentFormData() {
 	logger.Info("Saving current form data", "CurrentFormGroup", m.CurrentFormGroup)
-	q := &m.QuestionForms[m.CurrentFormGroup].Question
-	multiChoiceAnsLimit[m.CurrentFormGroup] = m.QuestionForms[m.CurrentFormGroup].Form.GetString("multi_choice_ans_limit")
-	q.MultiChoiceAnsLimit, _ = strconv.Atoi(multiChoiceAnsLimit[m.CurrentFormGroup])
-	questionScorePoint[m.CurrentFormGroup] = m.QuestionForms[m.CurrentFormGroup].Form.GetString("points")
-	q.Points, _ = strconv.Atoi(questionScorePoint[m.CurrentFormGroup])
-	answersCommaSeparated[m.CurrentFormGroup] = m.QuestionForms[m.CurrentFormGroup].Form.GetString("answers")
-	answerTexts := strings.Split(answersCommaSeparated[m.CurrentFormGroup], ",")
-	m.QuestionForms[m.CurrentFormGroup].Answers = nil // Clear the Answers list before appending new ones
-	for _, answerText := range answerTexts {
-		if strings.TrimSpace(answerText) == m.QuestionForms[m.CurrentFormGroup].CorrectAnswer {
-			m.QuestionForms[m.CurrentFormGroup].Answers = append(m.QuestionForms[m.CurrentFormGroup].Answers, Answer{QuestionId: m.QuestionForms[m.CurrentFormGroup].Question.ID, Text: strings.TrimSpace(answerText), IsCorrect: true})
-		} else {
-			m.QuestionForms[m.CurrentFormGroup].Answers = append(m.QuestionForms[m.CurrentFormGroup].Answers, Answer{QuestionId: m.QuestionForms[m.CurrentFormGroup].Question.ID, Text: strings.TrimSpace(answerText), IsCorrect: false})
-		}
-	}
+	form := &m.QuestionForms[m.CurrentFormGroup]
+	q := &form.Question
+	// The inputs keep their values up to date as they are typed, unlike the form's results which only
+	// hold the fields that were left, and are lost when the forms are built again
+	q.MultiChoiceAnsLimit, _ = strconv.Atoi(form.MultiChoiceAnsLimit)
+	q.Points, _ = strconv.Atoi(form.Points)
 	// Log all fields of the current question form data
-	logger.Info("Saved form data", "formIndex", m.CurrentFormGroup, "formData", map[string]interface{}{
-		"QuestionID":            q.ID,
-		"QuizID":                q.QuizId,
-		"Text":                  q.Text,
-		"Type":                  q.Type,
-		"Points":                q.Points,
-		"MultiChoiceAnsLimit":   q.MultiChoiceAnsLimit,
-		"HintExplanation":       q.HintExplanation,
-		"DifficultyLevel":       q.DifficultyLevel,
-		"CreationDate":          q.CreationDate,
-		"LastModifiedDate":      q.LastModifiedDate,
-		"Answers":               m.QuestionForms[m.CurrentFormGroup].Answers,
-		"CorrectAnswer":         m.QuestionForms[m.CurrentFormGroup].CorrectAnswer,
-		"answersCommaSeparated": answersCommaSeparated[m.CurrentFormGroup],
-		"multiChoiceAnsLimit":   multiChoiceAnsLimit[m.CurrentFormGroup],
-		"questionScorePoint":    questionScorePoint[m.CurrentFormGroup],
+	logger.Debug("Saved form data", "formIndex", m.CurrentFormGroup, "formData", map[string]interface{}{
+		"QuestionID":          q.ID,
+		"QuizID":              q.QuizId,
+		"Text":                q.Text,
+		"Type":                q.Type,
+		"Points":              q.Points,
+		"MultiChoiceAnsLimit": q.MultiChoiceAnsLimit,
+		"HintExplanation":     q.HintExplanation,
+		"DifficultyLevel":     q.DifficultyLevel,
+		"CreationDate":        q.CreationDate,
+		"LastModifiedDate":    q.LastModifiedDate,
+		"Answers":             form.Answers,
+		"multiChoiceAnsLimit": form.MultiChoiceAnsLimit,
+		"questionScorePoint":  form.Points,
+		"audioClipFile":       form.AudioClipFile,
 	})
 }
 
@@ -495,34 +885,24 @@ func (m *DynamicQuizModel) saveCurrentFormData() {
 func (m *DynamicQuizModel) NextForm() {
 	logger.Info("NextForm called", "CurrentFormGroup", m.CurrentFormGroup, "Focused", m.Focused, "ButtonLabel", m.Buttons[0].Label)
 
-	// Check if the current form group is less than the total form groups
-	if m.CurrentFormGroup < m.TotalFormGroups-1 {
-		// If the current focus is on the table, handle the save operation
-		if m.Focused == "table" {
-			logger.Info("Saving Data to Backend Server", "Operation", "Update")
-
-			// Check if the quiz exists in the backend
-			quizExists, _, err := m.checkIfQuizExists()
-			if err != nil {
-				logger.Error("Error checking if quiz exists", "error", err)
-			}
-
-			// Save responses to backend based on whether the quiz exists
-			if quizExists {
-				m.saveResponsesToBackend()
-			} else {
-				m.postResponseToBackend()
-			}
-		}
+	// If the current focus is on the table, handle the save operation, on the last form too so a
+	// quiz with a single question is saved
+	if m.Focused == "table" {
+		logger.Info("Saving Data to Backend Server", "Operation", "Update")
+		m.save()
+	}
 
-		// If the current focus is on the button and the label is "Create", handle the create operation
-		if m.Focused == "button" && m.Buttons[0].Label == "Create" {
-			logger.Info("Posting Data to Backend Server", "Operation", "Create")
-			m.postResponseToBackend()
-		}
+	// If the current focus is on the button and the label is "Create", handle the create operation
+	if m.Focused == "button" && m.Buttons[0].Label == "Create" {
+		logger.Info("Posting Data to Backend Server", "Operation", "Create")
+		m.save()
+	}
 
+	// Check if the current form group is less than the total form groups
+	if m.CurrentFormGroup < m.TotalFormGroups-1 {
 		// Move to the next form group
 		m.CurrentFormGroup++
+		m.saveDraft()
 		logger.Info("Moved to next form", "CurrentFormGroup", m.CurrentFormGroup)
 	} else {
 		// If the current form group is the last one, log that the end of forms has been reached
@@ -536,71 +916,6 @@ func (m *DynamicQuizModel) PreviousForm() {
 	if m.CurrentFormGroup > 0 {
 		m.CurrentFormGroup--
 	}
+	m.saveDraft()
 	logger.Info("Moved to previous form", "CurrentFormGroup", m.CurrentFormGroup)
 }
-
-// Post the response to the backend
-func (m *DynamicQuizModel) postResponseToBackend() {
-	logger.Info("Posting responses to backend")
-	for i, q := range m.QuestionForms {
-		// Parse the answersCommaSeparated into individual answers
-		answerTexts := strings.Split(answersCommaSeparated[i], ",")
-		for _, answerText := range answerTexts {
-			if strings.TrimSpace(answerText) == q.CorrectAnswer {
-				q.Answers = append(q.Answers, Answer{QuestionId: q.Question.ID, Text: strings.TrimSpace(answerText), IsCorrect: true})
-			} else {
-				q.Answers = append(q.Answers, Answer{QuestionId: q.Question.ID, Text: strings.TrimSpace(answerText), IsCorrect: false})
-			}
-		}
-
-		// Set the creation date and last modified date to the current date and time
-		currentDate := time.Now().UTC()
-		q.Question.CreationDate = currentDate
-		q.Question.LastModifiedDate = currentDate
-
-		// Create question response data
-		questionResponse := map[string]interface{}{
-			"quiz_id":                m.QuizID,
-			"id":                     q.Question.ID,
-			"text":                   q.Question.Text,
-			"type":                   q.Question.Type,
-			"points":                 q.Question.Points,
-			"hint_explanation":       q.Question.HintExplanation,
-			"difficulty_level":       q.Question.DifficultyLevel,
-			"multi_choice_ans_limit": q.Question.MultiChoiceAnsLimit,
-			"creation_date":          q.Question.CreationDate.Format(time.RFC3339),
-			"last_modified_date":     q.Question.LastModifiedDate.Format(time.RFC3339),
-		}
-		logger.Info("Sending question to backend (POST)", "questionID", q.Question.ID, "questionResponse", questionResponse)
-		questionID, err := m.postQuestionToBackend(questionResponse)
-		if err != nil {
-			logger.Error("Failed to send question to backend", "error", err)
-		}
-		q.Question.ID = questionID
-
-		// Clear the Answers list to avoid duplication
-		q.Answers = nil
-
-		// Send answers to the backend
-		for _, ans := range q.Answers {
-			// Set the creation date and last modified date to the current date and time for answers
-			ans.CreationDate = currentDate
-			ans.LastModifiedDate = currentDate
-			ans.QuestionId = questionID
-
-			answerResponse := map[string]interface{}{
-				"question_id":        questionID,
-				"text":               ans.Text,
-				"is_correct":         ans.IsCorrect,
-				"creation_date":      ans.CreationDate.Format(time.RFC3339),
-				"last_modified_date": ans.LastModifiedDate.Format(time.RFC3339),
-			}
-			logger.Info("Sending answer to backend", "answer", ans, "answerResponse", answerResponse)
-			err := m.postAnswerToBackend(answerResponse)
-			if err != nil {
-				logger.Error("Failed to send answer to backend", "error", err)
-			}
-		}
-	}
-	logger.Info("All responses posted to backend")
-}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSaveAudioClip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "intro.ogg")
+	if err := os.WriteFile(file, []byte("OggS, then the melody"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	q := savedQuestion(1)
+	q.AudioClipFile = file
+	m := newTestEditor(t, q)
+
+	var mu sync.Mutex
+	var sent map[string]interface{} // the last question saved
+	requests := testBackend(t, func(r *http.Request) (int, string) {
+		switch r.URL.Path {
+		case "/media":
+			if _, header, err := r.FormFile("file"); err != nil || header.Filename != "intro.ogg" {
+				t.Errorf("uploaded %v, %v, want the file as the form's file part", header, err)
+			}
+			return http.StatusCreated, `{"id": 9, "content_type": "audio/ogg"}`
+		case "/questions/1":
+			mu.Lock()
+			defer mu.Unlock()
+			sent = nil
+			json.NewDecoder(r.Body).Decode(&sent)
+		}
+		return http.StatusOK, `{}`
+	})
+	audioMediaID := func() interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent["audio_media_id"]
+	}
+
+	// The clip is uploaded as media before the question, which refers to it
+	if !m.saveResponsesToBackend() {
+		t.Fatal("save failed")
+	}
+	if id := m.QuestionForms[0].Question.AudioMediaID; id == nil || *id != 9 || audioMediaID() != float64(9) {
+		t.Errorf("question saved with audio_media_id %v, want media 9", audioMediaID())
+	}
+
+	// Saving again does not upload the file again, and "none" detaches the clip
+	m.saveResponsesToBackend()
+	m.QuestionForms[0].AudioClipFile = removeAudioClip
+	if !m.saveResponsesToBackend() || m.QuestionForms[0].Question.AudioMediaID != nil || audioMediaID() != nil {
+		t.Errorf("question saved with audio_media_id %v, want none", audioMediaID())
+	}
+	want := "POST /media,PUT /questions/1,PUT /questions/1,PUT /questions/1"
+	if got := strings.Join(requests(), ","); got != want {
+		t.Errorf("backend received %s, want %s", got, want)
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"letsquiz/config"
 	"letsquiz/logger"
 	"letsquiz/music"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 type Quiz struct {
@@ -24,11 +28,19 @@ type Quiz struct {
 	TimeLimitInMins int
 	IsActive        bool
 	QuestionCount   int
+	Soundtrack      string
 }
 
 type EditQuestionnaireModel struct {
 	common.Model
 	Quizzes []Quiz
+	Status  string // outcome of the last export
+}
+
+// quizExportedMsg carries the outcome of exporting a quiz as a spreadsheet
+type quizExportedMsg struct {
+	path string
+	err  error
 }
 
 func InitialEditQuestionnaireModel() EditQuestionnaireModel {
@@ -51,8 +63,8 @@ func InitialEditQuestionnaireModel() EditQuestionnaireModel {
 		Model:   common.Model{CurrentScreen: "EditQuestionnaire", Table: t},
 		Quizzes: []Quiz{},
 	}
-	model.Buttons = append(model.Buttons, common.Button{Label: "Create"})
-	model.Focused = "button" // Initially focus on the button
+	model.Buttons = append(model.Buttons, common.Button{Label: "Create"}, common.Button{Label: "Import"})
+	model.Focused = "button" // Initially focus on the buttons, Cursor selects one
 	return model
 }
 
@@ -64,6 +76,34 @@ func (m *EditQuestionnaireModel) ToggleFocus() {
 	}
 }
 
+// exportQuizCmd saves a quiz's questions as a CSV or TSV spreadsheet in the working directory, in the
+// layout the import reads back
+func exportQuizCmd(quizID int, format string) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := http.Get(fmt.Sprintf("%s/quizzes/%d/export?format=%s", config.AppConfig.BackendURL, quizID, format))
+		if err != nil {
+			return quizExportedMsg{err: fmt.Errorf("error exporting quiz %d: %w", quizID, err)}
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return quizExportedMsg{err: fmt.Errorf("error exporting quiz %d: %w", quizID, err)}
+		}
+		if resp.StatusCode != http.StatusOK {
+			return quizExportedMsg{err: fmt.Errorf("error exporting quiz %d: %s", quizID, strings.TrimSpace(string(body)))}
+		}
+		path := fmt.Sprintf("quiz-%d.%s", quizID, format)
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			path = filepath.Base(params["filename"])
+		}
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			return quizExportedMsg{err: fmt.Errorf("error saving %s: %w", path, err)}
+		}
+		logger.Info("Exported quiz", "quizID", quizID, "format", format, "path", path)
+		return quizExportedMsg{path: path}
+	}
+}
+
 func FetchQuizzesCmd() tea.Cmd {
 	return func() tea.Msg {
 		logger.Info("Starting FetchQuizzesCmd")
@@ -84,7 +124,7 @@ func FetchQuizzesCmd() tea.Cmd {
 			return nil
 		}
 
-		logger.Info("Fetched response body", "body", string(body))
+		logger.Debug("Fetched response body", "body", string(body))
 
 		// Check if response is an error message
 		if string(body) == "invalid transaction\n" {
@@ -101,7 +141,7 @@ func FetchQuizzesCmd() tea.Cmd {
 		}
 
 		logger.Info("Successfully fetched quizzes", "count", len(quizzes))
-		logger.Info("Quizzes Unmarshalled", "Quizzes", quizzes)
+		logger.Debug("Quizzes Unmarshalled", "Quizzes", quizzes)
 
 		// Resolve quizzes into the Quiz struct
 		var resolvedQuizzes []Quiz
@@ -128,6 +168,7 @@ func FetchQuizzesCmd() tea.Cmd {
 			timeLimit := int(quiz["time_limit_in_mins"].(float64))
 			isActive := quiz["is_active"].(bool)
 			questionCount := int(quiz["question_count"].(float64))
+			soundtrack, _ := quiz["soundtrack"].(string) // Absent on servers without soundtrack support
 
 			// Append the resolved quiz to the list
 			resolvedQuizzes = append(resolvedQuizzes, Quiz{
@@ -140,9 +181,10 @@ func FetchQuizzesCmd() tea.Cmd {
 				TimeLimitInMins: timeLimit,
 				IsActive:        isActive,
 				QuestionCount:   questionCount,
+				Soundtrack:      soundtrack,
 			})
 		}
-		logger.Info("Resolved quizzes", "table values", resolvedQuizzes)
+		logger.Debug("Resolved quizzes", "table values", resolvedQuizzes)
 		logger.Info("Resolved quizzes", "resolvedCount", len(resolvedQuizzes))
 		return resolvedQuizzes
 	}
@@ -164,7 +206,7 @@ func fetchCategory(categoryId int) (string, error) {
 		logger.Error("Failed to read response body (category)", "error", err)
 		return "", err
 	}
-	logger.Info("Fetched category response body", "body", string(body))
+	logger.Debug("Fetched category response body", "body", string(body))
 
 	if err := json.Unmarshal(body, &category); err != nil {
 		logger.Error("Failed to unmarshal category", "error", err)
@@ -196,7 +238,7 @@ func fetchCreator(creatorId int) (string, error) {
 		logger.Error("Failed to read response body (creator)", "error", err)
 		return "", err
 	}
-	logger.Info("Fetched creator response body", "body", string(body))
+	logger.Debug("Fetched creator response body", "body", string(body))
 
 	var user map[string]interface{}
 	if err := json.Unmarshal(body, &user); err != nil {
@@ -232,6 +274,14 @@ func UpdateEditQuestionnaire(m EditQuestionnaireModel, msg tea.Msg) (tea.Model,
 			return m, tea.Quit
 		case "tab", "shift+tab":
 			m.ToggleFocus()
+		case "left", "h":
+			if m.Focused == "button" && m.Cursor > 0 {
+				m.Cursor--
+			}
+		case "right", "l":
+			if m.Focused == "button" && m.Cursor < len(m.Buttons)-1 {
+				m.Cursor++
+			}
 		case "up", "k":
 			if m.Focused == "table" {
 				m.Table.MoveUp(1)
@@ -240,20 +290,45 @@ func UpdateEditQuestionnaire(m EditQuestionnaireModel, msg tea.Msg) (tea.Model,
 			if m.Focused == "table" {
 				m.Table.MoveDown(1)
 			}
-		case "alt+end":
-			logger.Info("Toggling music mute/unmute", "currentScreen", m.CurrentScreen)
-			music.ToggleMusicMuteUnmute()
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
+		case "x", "X":
+			// Export the selected quiz, x as CSV and X as TSV
+			if selectedRow := m.Table.SelectedRow(); m.Focused == "table" && selectedRow != nil {
+				quizID, err := strconv.Atoi(selectedRow[0])
+				if err != nil {
+					logger.Error("Invalid quiz ID in the selected row", "row", selectedRow, "error", err)
+					break
+				}
+				format := "csv"
+				if msg.String() == "X" {
+					format = "tsv"
+				}
+				m.Status = fmt.Sprintf("Exporting quiz %d...", quizID)
+				cmd = exportQuizCmd(quizID, format)
+			}
 		case "enter":
 			if m.Focused == "table" {
 				m.CurrentScreen = "QuizMetadata" // Set the CurrentScreen here to transition to
 				logger.Info("Setting CurrentScreen to QuizMetadata", "screen", m.CurrentScreen)
 				selectedRow := m.Table.SelectedRow()
 				logger.Info("Selected row", selectedRow)
-			} else if m.Focused == "button" && m.Buttons[0].Label == "Create" {
+			} else if m.Focused == "button" && m.Buttons[m.Cursor].Label == "Create" {
 				m.CurrentScreen = "QuizMetadata" // Set the CurrentScreen here to transition to
 				logger.Info("Setting CurrentScreen to QuizMetadata", "screen", m.CurrentScreen)
+			} else if m.Focused == "button" && m.Buttons[m.Cursor].Label == "Import" {
+				m.CurrentScreen = "ImportQuiz"
+				logger.Info("Setting CurrentScreen to ImportQuiz", "screen", m.CurrentScreen)
 			}
 		}
+	case quizExportedMsg:
+		if msg.err != nil {
+			logger.Error("Export failed", "error", msg.err)
+			m.Status = msg.err.Error()
+		} else {
+			m.Status = "Saved " + msg.path
+		}
 	case []Quiz:
 		// Handling the quizzes data received from FetchQuizzesCmd (Call to Database)
 		logger.Info("Received quizzes message", "count", len(msg))
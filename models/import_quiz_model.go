@@ -0,0 +1,325 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"letsquiz/common"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/music"
+)
+
+// ImportedQuiz is a quiz created by an import
+type ImportedQuiz struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Category  string `json:"category"`
+	Questions int    `json:"questions"`
+}
+
+// ImportedQuestion is a question read from an imported file, listed by a dry run
+type ImportedQuestion struct {
+	Line            int     `json:"line"`
+	Text            string  `json:"text"`
+	Type            string  `json:"type"`
+	DifficultyLevel string  `json:"difficulty_level"`
+	Points          float64 `json:"points"`
+	Answers         []struct {
+		Text    string `json:"text"`
+		Correct bool   `json:"correct"`
+	} `json:"answers"`
+}
+
+// ImportDiagnostic reports a line of an imported file that could not be mapped
+type ImportDiagnostic struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped"` // the question was not imported, otherwise only part of it was lost
+}
+
+// ImportResult is the backend's summary of an import
+type ImportResult struct {
+	Quizzes     []ImportedQuiz     `json:"quizzes"`
+	Imported    int                `json:"imported"`
+	Skipped     int                `json:"skipped"`
+	Diagnostics []ImportDiagnostic `json:"diagnostics"`
+	Questions   []ImportedQuestion `json:"questions"` // only listed by a dry run
+}
+
+// importFields holds the values of the import form, shared by the copies of the model
+type importFields struct {
+	Path     string
+	Format   string
+	Title    string
+	Category string
+}
+
+// importDoneMsg carries the outcome of an import, or of the dry run previewing it
+type importDoneMsg struct {
+	result *ImportResult
+	dryRun bool
+	err    error
+}
+
+// ImportQuizModel imports the questions of a Moodle GIFT or Aiken file or a spreadsheet into new quizzes.
+// A dry run first previews every row in Table, with the reasons the invalid ones would be skipped.
+type ImportQuizModel struct {
+	common.Model
+	fields    *importFields
+	Importing bool
+	Preview   *ImportResult
+	Result    *ImportResult
+	Err       error
+}
+
+func InitialImportQuizModel() ImportQuizModel {
+	logger.Info("InitialImportQuizModel called")
+	fields := &importFields{Format: "gift"}
+	categorySuggestions, err := FetchCategories()
+	if err != nil {
+		logger.Info("Error while fetching categorySuggestions", "error", err)
+	}
+
+	f := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Key("path").
+				Title("File").Description("Path of the GIFT or Aiken file exported from Moodle, or of a spreadsheet saved as CSV or TSV.").
+				Validate(func(val string) error {
+					if strings.TrimSpace(val) == "" {
+						return errors.New("This field is required")
+					}
+					if info, err := os.Stat(strings.TrimSpace(val)); err != nil {
+						return err
+					} else if info.IsDir() {
+						return errors.New("This is a directory, enter the path of a file")
+					}
+					return nil
+				}).
+				Value(&fields.Path),
+			huh.NewSelect[string]().Key("format").
+				Title("Format").
+				Options(
+					huh.NewOption("Moodle GIFT", "gift"),
+					huh.NewOption("Aiken", "aiken"),
+					huh.NewOption("CSV spreadsheet", "csv"),
+					huh.NewOption("TSV spreadsheet", "tsv"),
+				).
+				Value(&fields.Format),
+			huh.NewInput().Key("title").
+				Title("Quiz title").Description("For questions without a GIFT category, which become a quiz of their own. Aiken and spreadsheet questions have none.").
+				Placeholder("Imported questions").
+				Value(&fields.Title),
+			huh.NewInput().Key("category").
+				Title("Category").Description("Category of that quiz.").
+				Placeholder("Imported").
+				Suggestions(categorySuggestions).
+				Value(&fields.Category),
+		),
+	).WithShowHelp(true).WithShowErrors(true)
+
+	return ImportQuizModel{
+		Model:  common.Model{CurrentScreen: "ImportQuiz", Form: f},
+		fields: fields,
+	}
+}
+
+// importQuizCmd uploads the file to the backend's import endpoint, only to preview the import on a dry run
+func importQuizCmd(fields importFields, dryRun bool) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(strings.TrimSpace(fields.Path))
+		if err != nil {
+			return importDoneMsg{err: fmt.Errorf("error reading %s: %w", fields.Path, err)}
+		}
+		query := url.Values{"format": {fields.Format}}
+		if title := strings.TrimSpace(fields.Title); title != "" {
+			query.Set("title", title)
+		}
+		if category := strings.TrimSpace(fields.Category); category != "" {
+			query.Set("category", category)
+		}
+		if dryRun {
+			query.Set("dry_run", "true")
+		}
+		resp, err := http.Post(config.AppConfig.BackendURL+"/quizzes/import?"+query.Encode(), "text/plain; charset=utf-8", bytes.NewReader(data))
+		if err != nil {
+			return importDoneMsg{err: fmt.Errorf("error importing quizzes: %w", err)}
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return importDoneMsg{err: fmt.Errorf("error importing quizzes: %w", err)}
+		}
+		// Nothing importable is reported with the diagnostics, like a successful import
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusUnprocessableEntity {
+			return importDoneMsg{err: fmt.Errorf("error importing quizzes: %s", strings.TrimSpace(string(body)))}
+		}
+		var result ImportResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return importDoneMsg{err: fmt.Errorf("error importing quizzes: %w", err)}
+		}
+		if dryRun {
+			logger.Info("Previewed import", "format", fields.Format, "questions", result.Imported, "skipped", result.Skipped)
+			return importDoneMsg{result: &result, dryRun: true}
+		}
+		logger.Info("Imported quizzes", "format", fields.Format, "quizzes", len(result.Quizzes), "imported", result.Imported, "skipped", result.Skipped)
+		for _, diagnostic := range result.Diagnostics {
+			logger.Info("Import diagnostic", "file", fields.Path, "line", diagnostic.Line, "message", diagnostic.Message, "skipped", diagnostic.Skipped)
+		}
+		return importDoneMsg{result: &result}
+	}
+}
+
+func UpdateImportQuiz(m ImportQuizModel, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.WindowWidth = msg.Width
+		m.WindowHeight = msg.Height
+		if m.Preview != nil {
+			m.sizePreviewTable()
+		}
+	case importDoneMsg:
+		m.Importing = false
+		if msg.err != nil {
+			logger.Error("Import failed", "error", msg.err, "dryRun", msg.dryRun)
+			m.Err = msg.err
+		} else if msg.dryRun {
+			m.Preview = msg.result
+			m.Table = previewTable(msg.result)
+			m.sizePreviewTable()
+		} else {
+			m.Preview, m.Result = nil, msg.result
+		}
+		return m, nil
+	case tea.KeyMsg:
+		logger.Info("Key pressed", "key", msg.String(), "currentScreen", m.CurrentScreen)
+		switch msg.String() {
+		case "esc":
+			if m.Preview != nil && !m.Importing {
+				// Back to the form to pick another file
+				return m.restart()
+			}
+			return m, func() tea.Msg { return common.ImportQuizClosedMsg{} }
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
+		case "enter":
+			switch {
+			case m.Result != nil || m.Err != nil:
+				// Import another file once the outcome of the last import is shown
+				return m.restart()
+			case m.Preview != nil && !m.Importing && m.Preview.Imported > 0:
+				m.Importing = true
+				return m, importQuizCmd(*m.fields, false)
+			}
+		}
+	}
+	if m.Importing || m.Result != nil || m.Err != nil {
+		return m, nil
+	}
+	if m.Preview != nil {
+		var cmd tea.Cmd
+		m.Table, cmd = m.Table.Update(msg)
+		return m, cmd
+	}
+
+	var cmds []tea.Cmd
+	form, cmd := m.Form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.Form = f
+		cmds = append(cmds, cmd)
+	}
+	if m.Form.State == huh.StateCompleted {
+		m.Importing = true
+		cmds = append(cmds, importQuizCmd(*m.fields, true))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// restart returns to a new form keeping the window size
+func (m ImportQuizModel) restart() (tea.Model, tea.Cmd) {
+	next := InitialImportQuizModel()
+	next.WindowWidth, next.WindowHeight = m.WindowWidth, m.WindowHeight
+	return next, next.Form.Init()
+}
+
+// previewTable lists the questions of a dry run and the diagnostics, in the order of their lines
+func previewTable(result *ImportResult) table.Model {
+	type previewRow struct {
+		line int
+		row  table.Row
+	}
+	var rows []previewRow
+	for _, question := range result.Questions {
+		correct := []string{}
+		for n, answer := range question.Answers {
+			if answer.Correct {
+				correct = append(correct, strconv.Itoa(n+1))
+			}
+		}
+		details := fmt.Sprintf("%s, %d answers, correct %s, %g points", question.Type, len(question.Answers), strings.Join(correct, ";"), question.Points)
+		if question.DifficultyLevel != "" {
+			details += ", " + question.DifficultyLevel
+		}
+		rows = append(rows, previewRow{question.Line, table.Row{strconv.Itoa(question.Line), "OK", strings.ReplaceAll(question.Text, "\n", " "), details}})
+	}
+	for _, diagnostic := range result.Diagnostics {
+		status := "Warning"
+		if diagnostic.Skipped {
+			status = "Skipped"
+		}
+		rows = append(rows, previewRow{diagnostic.Line, table.Row{strconv.Itoa(diagnostic.Line), status, "", diagnostic.Message}})
+	}
+	// A question's warnings follow it
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].line < rows[j].line })
+
+	tableRows := make([]table.Row, len(rows))
+	for i, row := range rows {
+		tableRows[i] = row.row
+	}
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Line", Width: 6},
+			{Title: "Status", Width: 8},
+			{Title: "Question", Width: 40},
+			{Title: "Details", Width: 40},
+		}),
+		table.WithRows(tableRows),
+		table.WithFocused(true),
+	)
+	style := table.DefaultStyles()
+	style.Header = style.Header.Bold(true)
+	t.SetStyles(style)
+	return t
+}
+
+// sizePreviewTable fits the preview table within the window boundary
+func (m *ImportQuizModel) sizePreviewTable() {
+	width := m.WindowWidth - 40 - 6 - 8
+	if width < 40 {
+		width = 40
+	}
+	m.Table.SetColumns([]table.Column{
+		{Title: "Line", Width: 6},
+		{Title: "Status", Width: 8},
+		{Title: "Question", Width: width * 2 / 5},
+		{Title: "Details", Width: width - width*2/5},
+	})
+	height := m.WindowHeight - 24
+	if height < 5 {
+		height = 5
+	}
+	m.Table.SetHeight(height)
+}
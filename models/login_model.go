@@ -16,7 +16,6 @@ func InitialLoginModel() LoginModel {
 	model := common.InitializeChoices("login")
 	model.Tick = common.Tick()
 	model.Banner = common.Frames[0]
-	model.IsPlaying = true
 	model.ConfirmationDialog = common.NewConfirmationDialog()
 	return LoginModel{Model: model}
 }
@@ -40,9 +39,9 @@ func UpdateLogin(m common.Model, msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			logger.Info("quitting application", "currentScreen", m.CurrentScreen)
 			return m, tea.Quit
-		case "alt+end":
-			logger.Info("Toggling music mute/unmute", "currentScreen", m.CurrentScreen)
-			music.ToggleMusicMuteUnmute()
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
 			return m, nil
 		case "left", "h":
 			if m.Cursor > 0 {
@@ -5,16 +5,32 @@ import (
 	"letsquiz/common"
 	"letsquiz/logger"
 	"letsquiz/music"
+	"letsquiz/outbox"
 )
 
 type MenuModel struct {
 	common.Model
 }
 
+// RestoreDraftChoice is offered in the menu while the quiz editor has drafts from previous sessions
+const RestoreDraftChoice = "Restore unsaved quiz draft"
+
 func InitialMenuModel() MenuModel {
 	logger.Info("InitialMenuModel called")
 	model := common.InitializeChoices("menu")
 	model.Tick = common.Tick()
+	if drafts, err := ListDrafts(); err != nil {
+		logger.Warn("Cannot list quiz drafts", "error", err)
+	} else if len(drafts) > 0 {
+		logger.Info("Found unsaved quiz drafts", "count", len(drafts))
+		model.Choices = append([]string{RestoreDraftChoice}, model.Choices...)
+		model.ButtonPos = append(model.ButtonPos, common.Rect{})
+	}
+	if pending, rejected := len(outbox.Pending()), len(outbox.Rejected()); pending+rejected > 0 {
+		logger.Info("Changes waiting for the backend", "count", pending, "rejected", rejected)
+		model.Choices = append([]string{ReviewPendingChoice}, model.Choices...)
+		model.ButtonPos = append(model.ButtonPos, common.Rect{})
+	}
 	return MenuModel{Model: model}
 }
 
@@ -37,9 +53,9 @@ func UpdateMenu(m common.Model, msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			logger.Info("quitting application", "currentScreen", m.CurrentScreen)
 			return m, tea.Quit
-		case "alt+end":
-			logger.Info("Toggling music mute/unmute", "currentScreen", m.CurrentScreen)
-			music.ToggleMusicMuteUnmute()
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
 		case "up", "k":
 			if m.Cursor > 0 {
 				m.Cursor--
@@ -53,9 +69,21 @@ func UpdateMenu(m common.Model, msg tea.Msg) (tea.Model, tea.Cmd) {
 			logger.Info("Option selected", "selected", m.Selected)
 			switch m.Selected {
 			case "Setup (for Admins Only)":
+				logger.Info("Transitioning to Setup")
+				m.CurrentScreen = "Setup"
 				return m, func() tea.Msg { return "setup" }
 			case "Select category & Start Quiz":
+				logger.Info("Transitioning to Quiz Player")
+				m.CurrentScreen = "QuizPlayer"
 				return m, func() tea.Msg { return "start_quiz" }
+			case RestoreDraftChoice:
+				logger.Info("Transitioning to the most recent quiz draft")
+				m.CurrentScreen = "RestoreDraft"
+				return m, func() tea.Msg { return "restore_draft" }
+			case ReviewPendingChoice:
+				logger.Info("Transitioning to Pending Operations")
+				m.CurrentScreen = "PendingOperations"
+				return m, func() tea.Msg { return "pending_operations" }
 			case "Create/Edit Questionnaire & Answers":
 				logger.Info("Transitioning to Edit Questionnaire")
 				editQuestionnaireModel := InitialEditQuestionnaireModel()
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/logger"
+	"letsquiz/music"
+	"letsquiz/outbox"
+)
+
+// ReviewPendingChoice is offered in the menu while changes are waiting for the backend or were rejected by it
+const ReviewPendingChoice = "Review pending changes"
+
+// pendingRefreshInterval is how often the pending changes screen shows the outbox again while it replays
+const pendingRefreshInterval = time.Second
+
+type pendingRefreshMsg struct{}
+
+// PendingOperationsModel lists the changes waiting for the backend, oldest first, so they can be retried or discarded,
+// followed by the changes the backend rejected, which can only be discarded
+type PendingOperationsModel struct {
+	common.Model
+	Operations     []outbox.Operation // the waiting changes, then the rejected ones
+	PendingDiscard string             // key of the change asked to be discarded, empty when not asking
+}
+
+func InitialPendingOperationsModel() PendingOperationsModel {
+	logger.Info("InitialPendingOperationsModel called")
+	return PendingOperationsModel{
+		Model:      common.Model{CurrentScreen: "PendingOperations"},
+		Operations: pendingOperations(),
+	}
+}
+
+// pendingOperations lists the waiting changes followed by the rejected ones
+func pendingOperations() []outbox.Operation {
+	return append(outbox.Pending(), outbox.Rejected()...)
+}
+
+// RefreshPendingOperations schedules the next refresh of the pending changes screen
+func RefreshPendingOperations() tea.Cmd {
+	return tea.Tick(pendingRefreshInterval, func(time.Time) tea.Msg { return pendingRefreshMsg{} })
+}
+
+func UpdatePendingOperations(m PendingOperationsModel, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.WindowWidth = msg.Width
+		m.WindowHeight = msg.Height
+	case pendingRefreshMsg:
+		m.refresh()
+		return m, RefreshPendingOperations()
+	case tea.KeyMsg:
+		logger.Info("Key pressed", "key", msg.String(), "currentScreen", m.CurrentScreen)
+		if m.PendingDiscard != "" {
+			return m.confirmDiscard(msg)
+		}
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return common.PendingOperationsClosedMsg{} }
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
+		case "up", "k":
+			if m.Cursor > 0 {
+				m.Cursor--
+			}
+		case "down", "j":
+			if m.Cursor < len(m.Operations)-1 {
+				m.Cursor++
+			}
+		case "r":
+			logger.Info("Retrying pending changes now", "pending", len(m.Operations))
+			outbox.RetryNow()
+		case "d":
+			if len(m.Operations) > 0 {
+				m.PendingDiscard = m.Operations[m.Cursor].Key
+			}
+		}
+	}
+	return m, nil
+}
+
+// confirmDiscard handles the keys while asked to discard a change, discarding it with y or keeping it with n
+func (m PendingOperationsModel) confirmDiscard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		if !outbox.Discard(m.PendingDiscard) {
+			logger.Info("Change was sent before it could be discarded", "key", m.PendingDiscard)
+		}
+		m.PendingDiscard = ""
+		m.refresh()
+	case "n", "esc":
+		m.PendingDiscard = ""
+	}
+	return m, nil
+}
+
+// refresh shows the outbox again, keeping the cursor on the list. A change asked to be discarded
+// that was sent in the meantime is no longer asked about.
+func (m *PendingOperationsModel) refresh() {
+	m.Operations = pendingOperations()
+	if m.Cursor >= len(m.Operations) {
+		m.Cursor = len(m.Operations) - 1
+	}
+	if m.Cursor < 0 {
+		m.Cursor = 0
+	}
+	if m.PendingDiscard != "" {
+		for _, op := range m.Operations {
+			if op.Key == m.PendingDiscard {
+				return
+			}
+		}
+		m.PendingDiscard = ""
+	}
+}
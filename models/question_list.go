@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/logger"
+)
+
+// Keys editing the list of questions of a quiz. The letters are upper case, typed with shift,
+// so they do not clash with the alt keys the text fields use to edit words.
+const (
+	KeyNewQuestion         = "alt+N" // adds a blank question after the current one
+	KeyInsertQuestion      = "alt+I" // adds a blank question before the current one
+	KeyDuplicateQuestion   = "alt+D"
+	KeyDeleteQuestion      = "alt+X"
+	KeyMoveQuestionEarlier = "alt+shift+left"
+	KeyMoveQuestionLater   = "alt+shift+right"
+)
+
+// editQuestionList adds, copies or moves questions, or asks to delete the current one.
+// The editor stays on the question the key acted on, the new order is saved with the quiz.
+func (m DynamicQuizModel) editQuestionList(key string) (tea.Model, tea.Cmd) {
+	m.saveCurrentFormData()
+	i := m.CurrentFormGroup
+	switch key {
+	case KeyNewQuestion:
+		m.insertQuestion(i+1, questionForms{})
+	case KeyInsertQuestion:
+		m.insertQuestion(i, questionForms{})
+	case KeyDuplicateQuestion:
+		m.insertQuestion(i+1, m.QuestionForms[i].duplicate())
+	case KeyDeleteQuestion:
+		if len(m.QuestionForms) == 1 {
+			logger.Info("Not deleting the only question of the quiz", "CurrentFormGroup", i)
+			return m, nil
+		}
+		m.PendingDelete = m.deleteQuestionPrompt()
+		return m, nil
+	case KeyMoveQuestionEarlier:
+		if !m.moveQuestion(i, i-1) {
+			return m, nil
+		}
+	case KeyMoveQuestionLater:
+		if !m.moveQuestion(i, i+1) {
+			return m, nil
+		}
+	}
+	m.buildForms()
+	m.saveDraft()
+	return m, m.QuestionForms[m.CurrentFormGroup].Form.Init()
+}
+
+// confirmDelete handles the keys while asked to delete the current question, deleting it with y
+// or returning to the form with n
+func (m DynamicQuizModel) confirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		m.PendingDelete = ""
+		i := m.CurrentFormGroup
+		// A saved question is deleted on the backend when the quiz is saved, like the answers removed
+		if q := m.QuestionForms[i].Question; q.ID != 0 {
+			m.DeletedQuestions = append(m.DeletedQuestions, q)
+		}
+		logger.Info("Question deleted", "CurrentFormGroup", i, "questionID", m.QuestionForms[i].Question.ID)
+		m.removeQuestion(i)
+		m.buildForms()
+		m.saveDraft()
+		return m, m.QuestionForms[m.CurrentFormGroup].Form.Init()
+	case "n", "N", "esc":
+		logger.Info("Question deletion not confirmed, back to editing", "CurrentFormGroup", m.CurrentFormGroup)
+		m.PendingDelete = ""
+	}
+	return m, nil
+}
+
+// deleteQuestionPrompt asks to confirm deleting the current question as Markdown, quoting its text
+func (m DynamicQuizModel) deleteQuestionPrompt() string {
+	q := m.QuestionForms[m.CurrentFormGroup]
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "**Delete question %d?**\n\n", m.CurrentFormGroup+1)
+	if text := strings.TrimSpace(q.Question.Text); text != "" {
+		prompt.WriteString("> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n\n")
+	}
+	if q.Question.ID != 0 {
+		prompt.WriteString("It is deleted from the quiz with its answers when the quiz is saved.")
+	} else {
+		prompt.WriteString("It has not been saved yet.")
+	}
+	return prompt.String()
+}
+
+// duplicate returns a copy of the question, its answers and its inputs, to be saved as a new question.
+// The copy plays the same audio clip, which is media the questions share.
+func (q questionForms) duplicate() questionForms {
+	question := q.Question
+	question.ID = 0
+	answers := make([]Answer, len(q.Answers))
+	for n, answer := range q.Answers {
+		answers[n] = Answer{Text: answer.Text, IsCorrect: answer.IsCorrect}
+	}
+	return questionForms{Question: question, Answers: answers, UploadedClip: q.UploadedClip,
+		Points: q.Points, MultiChoiceAnsLimit: q.MultiChoiceAnsLimit, AudioClipFile: q.AudioClipFile}
+}
+
+// insertQuestion adds q at index i and moves to it.
+// The forms need to be built again afterwards.
+func (m *DynamicQuizModel) insertQuestion(i int, q questionForms) {
+	m.QuestionForms = append(m.QuestionForms, questionForms{})
+	copy(m.QuestionForms[i+1:], m.QuestionForms[i:])
+	m.QuestionForms[i] = q
+	m.CurrentFormGroup = i
+}
+
+// removeQuestion drops the question at index i, moving to the question
+// that took its place or else the last one. The forms need to be built again afterwards.
+func (m *DynamicQuizModel) removeQuestion(i int) {
+	m.QuestionForms = append(m.QuestionForms[:i], m.QuestionForms[i+1:]...)
+	if i >= len(m.QuestionForms) {
+		i = len(m.QuestionForms) - 1
+	}
+	m.CurrentFormGroup = i
+}
+
+// moveQuestion swaps the question at index i with the one at index j and follows it,
+// reporting false when j is out of range. The forms need to be built again afterwards.
+func (m *DynamicQuizModel) moveQuestion(i, j int) bool {
+	if j < 0 || j >= len(m.QuestionForms) {
+		return false
+	}
+	m.QuestionForms[i], m.QuestionForms[j] = m.QuestionForms[j], m.QuestionForms[i]
+	m.CurrentFormGroup = j
+	return true
+}
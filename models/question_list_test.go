@@ -0,0 +1,227 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/config"
+	"letsquiz/outbox"
+)
+
+// newTestEditor opens the editor on questions without the backend, keeping drafts in a temporary directory
+func newTestEditor(t *testing.T, questions ...questionForms) DynamicQuizModel {
+	t.Helper()
+	previous := config.AppConfig.DraftsDir
+	config.AppConfig.DraftsDir = t.TempDir()
+	t.Cleanup(func() { config.AppConfig.DraftsDir = previous })
+	m := DynamicQuizModel{
+		QuizID:        7,
+		Model:         common.Model{CurrentScreen: "QuizMetadata", Focused: "table", Buttons: []common.Button{{Label: "Update"}}},
+		QuestionForms: questions,
+	}
+	m.buildForms()
+	return m
+}
+
+// testQuestion is a saved question whose inputs are all derived from n, to tell whether they stay together
+func testQuestion(n int) questionForms {
+	answers := []Answer{{ID: n*10 + 1, Text: "Lisbon", IsCorrect: true}, {ID: n*10 + 2, Text: "Porto", Position: 1}}
+	return questionForms{
+		Question:            Question{ID: n, Text: fmt.Sprintf("Question %d", n), Type: "multiple"},
+		Answers:             answers,
+		LoadedAnswers:       append([]Answer(nil), answers...),
+		Points:              fmt.Sprint(n * 100),
+		MultiChoiceAnsLimit: fmt.Sprint(n),
+		AudioClipFile:       fmt.Sprintf("clip%d.ogg", n),
+	}
+}
+
+// layout describes the questions in the editor with their inputs, e.g. "Question 1:100:1:clip1.ogg"
+func layout(m DynamicQuizModel) string {
+	var questions []string
+	for _, q := range m.QuestionForms {
+		questions = append(questions, fmt.Sprintf("%s:%s:%s:%s", q.Question.Text, q.Points, q.MultiChoiceAnsLimit, q.AudioClipFile))
+	}
+	return strings.Join(questions, ", ")
+}
+
+// edit sends a question list key to the editor on question current
+func edit(t *testing.T, m DynamicQuizModel, current int, key string) DynamicQuizModel {
+	t.Helper()
+	m.CurrentFormGroup = current
+	model, _ := m.editQuestionList(key)
+	return model.(DynamicQuizModel)
+}
+
+func TestEditQuestionList(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int
+		key     string
+		want    string
+		wantAt  int
+	}{
+		{"new after", 0, KeyNewQuestion, "Question 1:100:1:clip1.ogg, :::, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg", 1},
+		{"new after the last", 2, KeyNewQuestion, "Question 1:100:1:clip1.ogg, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg, :::", 3},
+		{"insert before", 1, KeyInsertQuestion, "Question 1:100:1:clip1.ogg, :::, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg", 1},
+		{"duplicate", 1, KeyDuplicateQuestion, "Question 1:100:1:clip1.ogg, Question 2:200:2:clip2.ogg, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg", 2},
+		{"move earlier", 1, KeyMoveQuestionEarlier, "Question 2:200:2:clip2.ogg, Question 1:100:1:clip1.ogg, Question 3:300:3:clip3.ogg", 0},
+		{"move later", 1, KeyMoveQuestionLater, "Question 1:100:1:clip1.ogg, Question 3:300:3:clip3.ogg, Question 2:200:2:clip2.ogg", 2},
+		{"move the first earlier", 0, KeyMoveQuestionEarlier, "Question 1:100:1:clip1.ogg, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg", 0},
+		{"move the last later", 2, KeyMoveQuestionLater, "Question 1:100:1:clip1.ogg, Question 2:200:2:clip2.ogg, Question 3:300:3:clip3.ogg", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := edit(t, newTestEditor(t, testQuestion(1), testQuestion(2), testQuestion(3)), tt.current, tt.key)
+			if got := layout(m); got != tt.want {
+				t.Errorf("questions = %s\nwant %s", got, tt.want)
+			}
+			if m.CurrentFormGroup != tt.wantAt {
+				t.Errorf("current question = %d, want %d", m.CurrentFormGroup, tt.wantAt)
+			}
+			if m.TotalFormGroups != len(m.QuestionForms) {
+				t.Errorf("TotalFormGroups = %d, want %d", m.TotalFormGroups, len(m.QuestionForms))
+			}
+			for i, q := range m.QuestionForms {
+				if q.Form == nil {
+					t.Errorf("question %d has no form", i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateQuestion(t *testing.T) {
+	original := testQuestion(1)
+	clip := 5
+	original.Question.AudioMediaID = &clip
+	original.UploadedClip = "clip1.ogg"
+	m := edit(t, newTestEditor(t, original), 0, KeyDuplicateQuestion)
+
+	copied := m.QuestionForms[1]
+	if copied.Question.ID != 0 || copied.Question.AudioMediaID != &clip || copied.UploadedClip != "clip1.ogg" {
+		t.Errorf("copy = %+v, want a new question playing the original's clip", copied.Question)
+	}
+	if len(copied.LoadedAnswers) != 0 {
+		t.Errorf("copy has loaded answers %v, want none", copied.LoadedAnswers)
+	}
+	for n, answer := range copied.Answers {
+		if answer.ID != 0 || answer.Text != original.Answers[n].Text || answer.IsCorrect != original.Answers[n].IsCorrect {
+			t.Errorf("copied answer %d = %+v, want a new answer like %+v", n, answer, original.Answers[n])
+		}
+	}
+
+	// Editing the copy leaves the original alone
+	copied.Answers[0].Text = "Lisboa"
+	if m.QuestionForms[0].Answers[0].Text != "Lisbon" {
+		t.Errorf("editing the copy changed the original's answer to %q", m.QuestionForms[0].Answers[0].Text)
+	}
+}
+
+func TestDeleteQuestion(t *testing.T) {
+	m := newTestEditor(t, testQuestion(1))
+	if m = edit(t, m, 0, KeyDeleteQuestion); m.PendingDelete != "" {
+		t.Errorf("asked to delete the only question, want it kept")
+	}
+
+	m = edit(t, m, 0, KeyNewQuestion)
+	m.QuestionForms[1].Question.Text = "Unsaved"
+	m = edit(t, m, 1, KeyDeleteQuestion)
+	if !strings.Contains(m.PendingDelete, "Unsaved") || !strings.Contains(m.PendingDelete, "not been saved") {
+		t.Fatalf("PendingDelete = %q, want the unsaved question quoted", m.PendingDelete)
+	}
+	model, _ := m.confirmDelete(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m = model.(DynamicQuizModel); m.PendingDelete != "" || len(m.QuestionForms) != 2 {
+		t.Fatalf("after n, %d questions asking %q, want both kept", len(m.QuestionForms), m.PendingDelete)
+	}
+
+	m = edit(t, m, 1, KeyDeleteQuestion)
+	model, _ = m.confirmDelete(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = model.(DynamicQuizModel)
+	if got, want := layout(m), "Question 1:100:1:clip1.ogg"; got != want || m.CurrentFormGroup != 0 {
+		t.Errorf("after deleting, questions = %s on %d, want %s on 0", got, m.CurrentFormGroup, want)
+	}
+}
+
+// testBackend records the requests sent through the outbox, answering them with respond
+func testBackend(t *testing.T, respond func(r *http.Request) (int, string)) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		status, body := respond(r)
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	if err := outbox.Open(filepath.Join(t.TempDir(), "outbox.json")); err != nil {
+		t.Fatal(err)
+	}
+	previous := config.AppConfig.BackendURL
+	config.AppConfig.BackendURL = server.URL
+	t.Cleanup(func() { config.AppConfig.BackendURL = previous })
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestDeleteSavedQuestion(t *testing.T) {
+	first, second := testQuestion(1), testQuestion(2)
+	first.AudioClipFile, second.AudioClipFile = "", ""
+	m := edit(t, newTestEditor(t, first, second), 1, KeyDeleteQuestion)
+	if !strings.Contains(m.PendingDelete, "when the quiz is saved") {
+		t.Errorf("PendingDelete = %q, want it to say the question is deleted on save", m.PendingDelete)
+	}
+	model, _ := m.confirmDelete(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = model.(DynamicQuizModel)
+
+	// Nothing is sent yet, the deletion is listed with the changes to save and kept in the draft
+	if len(m.QuestionForms) != 1 || len(m.DeletedQuestions) != 1 || m.DeletedQuestions[0].ID != 2 {
+		t.Fatalf("after deleting, %d questions and deleted %+v, want question 2 to delete", len(m.QuestionForms), m.DeletedQuestions)
+	}
+	if summary := m.changesSummary(); !strings.Contains(summary, `Deleted: question "Question 2"`) {
+		t.Errorf("changesSummary() = %q, want the deleted question", summary)
+	}
+	d, ok := loadDraft(m.QuizID)
+	if !ok || len(d.DeletedQuestions) != 1 {
+		t.Fatalf("draft = %+v, want the deleted question kept", d)
+	}
+	if restored := InitialDynamicQuizModelFromDraft(d); len(restored.DeletedQuestions) != 1 || restored.DeletedQuestions[0].ID != 2 {
+		t.Errorf("restored deletions = %+v, want question 2", restored.DeletedQuestions)
+	}
+
+	// Saving deletes it before saving the others, a failed deletion is kept for the next save
+	failing := true
+	requests := testBackend(t, func(r *http.Request) (int, string) {
+		if r.Method == http.MethodDelete {
+			if failing {
+				return http.StatusInternalServerError, "database unavailable"
+			}
+			return http.StatusNoContent, ""
+		}
+		return http.StatusOK, `{}`
+	})
+	if m.saveResponsesToBackend() || len(m.DeletedQuestions) != 1 {
+		t.Errorf("save with the deletion failing kept %+v, want it reported and kept", m.DeletedQuestions)
+	}
+	failing = false
+	if !m.saveResponsesToBackend() || len(m.DeletedQuestions) != 0 {
+		t.Errorf("save kept %+v, want the question deleted", m.DeletedQuestions)
+	}
+	want := "DELETE /questions/2,PUT /questions/1,DELETE /questions/2,PUT /questions/1"
+	if got := strings.Join(requests(), ","); got != want {
+		t.Errorf("backend received %s, want %s", got, want)
+	}
+}
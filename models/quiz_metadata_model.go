@@ -1,7 +1,6 @@
 package models
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,6 +11,7 @@ import (
 	"letsquiz/config"
 	"letsquiz/logger"
 	"letsquiz/music"
+	"letsquiz/outbox"
 	"letsquiz/server/models"
 	"net/http"
 	"net/url"
@@ -34,13 +34,15 @@ type QuizMetadata struct {
 	CategoryId       int       `json:"category_id"`
 	CreatorId        int       `json:"creator_id"`
 	TimeLimitInMins  int       `json:"time_limit_in_mins"`
-	QuestionCount    int       `json:"question_count"`
+	QuestionCount    int       `json:"question_count"` // counted by the backend from the quiz's questions
 	IsActive         bool      `json:"is_active"`
+	Soundtrack       string    `json:"soundtrack"`
+	MediaID          *int      `json:"media_id,omitempty"` // uploaded content, served by GET /media/{id}
 	CreationDate     time.Time `json:"creation_date"`
 	LastModifiedDate time.Time `json:"last_modified_date"`
 }
 
-var timeLimitInMins, isActive, questionCount string
+var timeLimitInMins, isActive string
 
 type QuizMetadataModel struct {
 	common.Model
@@ -55,7 +57,6 @@ func InitialQuizMetadata(quizData *QuizMetadata, focused string, buttons []commo
 		logger.Info("InitialQuizMetadata called", "quizId", quizData.ID, "focused", focused, "buttons", buttons)
 		fields = *quizData
 		timeLimitInMins = strconv.Itoa(quizData.TimeLimitInMins)
-		questionCount = strconv.Itoa(quizData.QuestionCount)
 		category.Name, category.Description, _ = FetchCategoryNameDescByID(quizData.CategoryId)
 	}
 	logger.Info("InitialQuizMetadata called", "focused", focused, "buttons", buttons)
@@ -83,6 +84,9 @@ func InitialQuizMetadata(quizData *QuizMetadata, focused string, buttons []commo
 			huh.NewInput().Key("content_url").
 				Title("Content URL").Description("Shine like a star by guiding others to your knowledge repository!").
 				Value(&fields.ContentURL),
+			huh.NewInput().Key("soundtrack").
+				Title("Soundtrack").Description("Set the mood with an MP3, OGG or WAV file path or URL played while your quiz is on.").
+				Value(&fields.Soundtrack),
 			huh.NewInput().Key("category").
 				Title("Category").Description("Enter a category of your quiz.").
 				Validate(func(val string) error {
@@ -102,15 +106,6 @@ func InitialQuizMetadata(quizData *QuizMetadata, focused string, buttons []commo
 					return nil
 				}).
 				Value(&timeLimitInMins),
-			huh.NewInput().Key("question_count").
-				Title("Question Count").Description("How many questions do you plan?").
-				Validate(func(val string) error {
-					if val == "" || val == "0" {
-						return errors.New("You should have atleast a question in your Quiz.")
-					}
-					return nil
-				}).
-				Value(&questionCount),
 			huh.NewSelect[string]().
 				Key("is_active").
 				Options(huh.NewOptions("Yes", "No")...).
@@ -157,9 +152,9 @@ func UpdateQuizMetadata(m QuizMetadataModel, msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			logger.Info("quitting application", "currentScreen", m.CurrentScreen)
 			return m, tea.Quit
-		case "alt+end":
-			logger.Info("Toggling music mute/unmute", "currentScreen", m.CurrentScreen)
-			music.ToggleMusicMuteUnmute()
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
 		}
 	}
 
@@ -191,14 +186,6 @@ func UpdateQuizMetadata(m QuizMetadataModel, msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		questionCount, err := strconv.Atoi(strings.Trim(questionCount, " "))
-		if err != nil {
-			logger.Error("Error fetching questionCount", "error", err)
-			return m, nil
-		} else {
-			m.QuizData.QuestionCount = questionCount
-		}
-
 		metadata := QuizMetadata{
 			ID:               m.QuizData.ID,
 			Title:            m.Form.GetString("title"),
@@ -207,11 +194,12 @@ func UpdateQuizMetadata(m QuizMetadataModel, msg tea.Msg) (tea.Model, tea.Cmd) {
 			CategoryId:       categoryId,
 			CreatorId:        m.QuizData.CreatorId,
 			TimeLimitInMins:  timeLimitInMins,
-			QuestionCount:    questionCount,
 			IsActive:         isActive,
+			Soundtrack:       m.Form.GetString("soundtrack"),
 			CreationDate:     time.Now(),
 			LastModifiedDate: time.Now(),
 		}
+		m.QuizData.Soundtrack = metadata.Soundtrack
 		logger.Info("Quiz metadata prepared", "metadata", metadata, "m.Focused", m.Focused, "m.Buttons", m.Buttons)
 		if m.Focused == "table" {
 			if err := saveQuizMetadata(metadata); err != nil {
@@ -237,19 +225,20 @@ func UpdateQuizMetadata(m QuizMetadataModel, msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func postQuizMetadata(metadata QuizMetadata) error {
-	url := config.AppConfig.BackendURL + "/quizzes" // Read from AppConfig
 	jsonData, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := outbox.Send(http.MethodPost, "/quizzes", "application/json", jsonData, fmt.Sprintf("Create quiz %q", metadata.Title))
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if resp.Queued {
+		return nil
+	}
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to post quiz metadata, status code: %d", resp.StatusCode)
 	}
 	return nil
@@ -257,23 +246,18 @@ func postQuizMetadata(metadata QuizMetadata) error {
 
 // saveQuizMetadata saves the quiz metadata using a PUT request
 func saveQuizMetadata(metadata QuizMetadata) error {
-	url := fmt.Sprintf("%s/quizzes/%d", config.AppConfig.BackendURL, metadata.ID)
 	jsonData, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(jsonData))
+	resp, err := outbox.Send(http.MethodPut, fmt.Sprintf("/quizzes/%d", metadata.ID), "application/json", jsonData, fmt.Sprintf("Update quiz %q", metadata.Title))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	if resp.Queued {
+		return nil
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to put quiz metadata, status code: %d", resp.StatusCode)
@@ -321,7 +305,7 @@ func FetchCategoryNameDescByID(categoryID int) (string, string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		logger.Info("Failed to fetch category name", "status", resp.StatusCode, "body", string(body), "categoryID", categoryID, "error", err)
+		logger.Debug("Failed to fetch category name", "status", resp.StatusCode, "body", string(body), "categoryID", categoryID, "error", err)
 		return "", "", fmt.Errorf("failed to fetch category name, status code: %d", resp.StatusCode)
 	}
 
@@ -350,7 +334,7 @@ func FetchCategoryIDByName(categoryName string) (int, error) {
 
 	if err != nil {
 		body, _ := io.ReadAll(resp.Body)
-		logger.Info("Failed to fetch category ID", "status", resp.StatusCode, "body", string(body), "categoryName", categoryName, "error", err)
+		logger.Debug("Failed to fetch category ID", "status", resp.StatusCode, "body", string(body), "categoryName", categoryName, "error", err)
 		return 0, fmt.Errorf("error fetching category ID: %w", err)
 	}
 	defer resp.Body.Close()
@@ -389,7 +373,7 @@ func FetchCreatorIDByName(creatorName string) (int, error) {
 
 	if err != nil {
 		body, _ := io.ReadAll(resp.Body)
-		logger.Info("Failed to fetch creator ID", "status", resp.StatusCode, "body", string(body), "creatorName", creatorName, "error", err)
+		logger.Debug("Failed to fetch creator ID", "status", resp.StatusCode, "body", string(body), "creatorName", creatorName, "error", err)
 		return 0, fmt.Errorf("error fetching creator ID: %w", err)
 	}
 	defer resp.Body.Close()
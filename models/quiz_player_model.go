@@ -0,0 +1,540 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"letsquiz/common"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/markdown"
+	"letsquiz/music"
+	"letsquiz/termimage"
+)
+
+// Phases of the quiz player
+const (
+	PlayerLoading  = "loading"  // fetching quizzes or questions
+	PlayerSelect   = "select"   // choosing a quiz
+	PlayerQuestion = "question" // answering the current question
+	PlayerFeedback = "feedback" // showing whether the answer was right
+	PlayerFinished = "finished" // showing the score
+)
+
+// KeyPlayClip plays the audio clip of the current question
+const KeyPlayClip = "alt+p"
+
+// playerQuestion is a question together with its answer options
+type playerQuestion struct {
+	Question Question
+	Answers  []Answer
+}
+
+type QuizPlayerModel struct {
+	common.Model
+	Phase      string
+	Quizzes    []QuizMetadata // active quizzes offered for selection
+	Categories map[int]string // category names by ID
+	Quiz       QuizMetadata
+	Questions  []playerQuestion
+	Current    int
+	Score      int
+	MaxScore   int
+	Correct    int
+	Feedback   string    // rendered Markdown
+	Deadline   time.Time // zero when the quiz has no time limit
+	Remaining  time.Duration
+	Err        error
+
+	// QuestionView is the current question's Markdown, rendered again when the window is resized
+	QuestionView string
+
+	// Audio clip of the current question
+	ClipPlays  int    // times the clip was played, limited by audio_clip_max_plays
+	ClipStatus string // why the clip cannot be played, empty while it can
+	clip       []byte // downloaded once per question, replays reuse it
+
+	// Image of the current question, rendered again when the window is resized
+	Image         image.Image
+	ImageView     string
+	ImageProtocol string // resolved from image_protocol when the player opens
+}
+
+// playerQuizzesMsg carries the quizzes that can be played
+type playerQuizzesMsg struct {
+	quizzes    []QuizMetadata
+	categories map[int]string
+}
+
+// playerQuestionsMsg carries the questions of the selected quiz
+type playerQuestionsMsg []playerQuestion
+
+// playerErrorMsg reports a failed backend call
+type playerErrorMsg struct{ err error }
+
+// playerClipMsg carries the downloaded audio clip of a question
+type playerClipMsg struct {
+	questionID int
+	data       []byte
+	err        error
+}
+
+// playerImageMsg carries the downloaded image of a question
+type playerImageMsg struct {
+	questionID int
+	img        image.Image
+	err        error
+}
+
+// playerTickMsg advances the quiz clock, separate from common.TickMsg so a banner tick
+// still in flight from the previous screen cannot start a second clock
+type playerTickMsg time.Time
+
+func playerTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return playerTickMsg(t)
+	})
+}
+
+func InitialQuizPlayerModel() QuizPlayerModel {
+	logger.Info("InitialQuizPlayerModel called")
+	return QuizPlayerModel{
+		Model:         common.Model{CurrentScreen: "QuizPlayer"},
+		Phase:         PlayerLoading,
+		ImageProtocol: termimage.Resolve(config.AppConfig.ImageProtocol),
+	}
+}
+
+// FetchPlayableQuizzesCmd loads the active quizzes and category names
+func FetchPlayableQuizzesCmd() tea.Cmd {
+	return func() tea.Msg {
+		var quizzes []QuizMetadata
+		if err := getJSON(config.AppConfig.BackendURL+"/quizzes", &quizzes); err != nil {
+			return playerErrorMsg{fmt.Errorf("error fetching quizzes: %w", err)}
+		}
+		var categoryList []Category
+		if err := getJSON(config.AppConfig.BackendURL+"/categories", &categoryList); err != nil {
+			return playerErrorMsg{fmt.Errorf("error fetching categories: %w", err)}
+		}
+		categories := make(map[int]string, len(categoryList))
+		for _, category := range categoryList {
+			categories[category.ID] = category.Name
+		}
+
+		var active []QuizMetadata
+		for _, quiz := range quizzes {
+			if quiz.IsActive {
+				active = append(active, quiz)
+			}
+		}
+		sort.Slice(active, func(i, j int) bool {
+			if categories[active[i].CategoryId] != categories[active[j].CategoryId] {
+				return categories[active[i].CategoryId] < categories[active[j].CategoryId]
+			}
+			return active[i].Title < active[j].Title
+		})
+		logger.Info("Fetched playable quizzes", "count", len(active))
+		return playerQuizzesMsg{quizzes: active, categories: categories}
+	}
+}
+
+// fetchQuizQuestionsCmd loads the questions of a quiz with their answer options
+func fetchQuizQuestionsCmd(quizID int) tea.Cmd {
+	return func() tea.Msg {
+		var questions []Question
+		if err := getJSON(fmt.Sprintf("%s/quizzes/%d/questions", config.AppConfig.BackendURL, quizID), &questions); err != nil {
+			return playerErrorMsg{fmt.Errorf("error fetching questions: %w", err)}
+		}
+		var result []playerQuestion
+		for _, question := range questions {
+			var answers []Answer
+			if err := getJSON(fmt.Sprintf("%s/questions/%d/answers", config.AppConfig.BackendURL, question.ID), &answers); err != nil {
+				return playerErrorMsg{fmt.Errorf("error fetching answers: %w", err)}
+			}
+			if len(answers) == 0 {
+				logger.Info("Skipping question without answers", "questionID", question.ID)
+				continue
+			}
+			result = append(result, playerQuestion{Question: question, Answers: answers})
+		}
+		logger.Info("Fetched quiz questions", "quizID", quizID, "count", len(result))
+		return playerQuestionsMsg(result)
+	}
+}
+
+// fetchAudioClipCmd downloads the audio clip of a question
+func fetchAudioClipCmd(questionID, mediaID int) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := http.Get(fmt.Sprintf("%s/media/%d", config.AppConfig.BackendURL, mediaID))
+		if err != nil {
+			return playerClipMsg{questionID: questionID, err: err}
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return playerClipMsg{questionID: questionID, err: fmt.Errorf("status code: %d", resp.StatusCode)}
+		}
+		data, err := io.ReadAll(resp.Body)
+		return playerClipMsg{questionID: questionID, data: data, err: err}
+	}
+}
+
+// fetchQuestionImageCmd downloads and decodes the image attached to a question
+func fetchQuestionImageCmd(questionID, mediaID int) tea.Cmd {
+	return func() tea.Msg {
+		resp, err := http.Get(fmt.Sprintf("%s/media/%d", config.AppConfig.BackendURL, mediaID))
+		if err != nil {
+			return playerImageMsg{questionID: questionID, err: err}
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return playerImageMsg{questionID: questionID, err: fmt.Errorf("status code: %d", resp.StatusCode)}
+		}
+		if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/") {
+			return playerImageMsg{questionID: questionID, err: fmt.Errorf("media %d is not an image but %s", mediaID, contentType)}
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return playerImageMsg{questionID: questionID, err: err}
+		}
+		img, err := termimage.Decode(data)
+		return playerImageMsg{questionID: questionID, img: img, err: err}
+	}
+}
+
+// getJSON decodes the JSON response of a GET request into v
+func getJSON(url string, v interface{}) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// UpdateQuizPlayer handles the updates and state transitions of the quiz player
+func UpdateQuizPlayer(m QuizPlayerModel, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.WindowWidth = msg.Width
+		m.WindowHeight = msg.Height
+		m.renderQuestion()
+		m.renderImage()
+	case tea.KeyMsg:
+		logger.Info("Key pressed", "key", msg.String(), "phase", m.Phase)
+		switch msg.String() {
+		case "esc":
+			logger.Info("quitting application", "currentScreen", m.CurrentScreen)
+			return m, tea.Quit
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
+			return m, nil
+		case KeyPlayClip:
+			if m.Phase == PlayerQuestion {
+				return m, m.playClip()
+			}
+		case "enter":
+			switch m.Phase {
+			case PlayerFeedback:
+				m.nextQuestion()
+				if m.Phase == PlayerQuestion {
+					return m, tea.Batch(m.Form.Init(), m.fetchImage())
+				}
+				return m, nil
+			case PlayerFinished:
+				return m, func() tea.Msg { return common.QuizPlayerClosedMsg{} }
+			}
+		}
+	case playerErrorMsg:
+		logger.Error("Quiz player backend call failed", "error", msg.err)
+		m.Err = msg.err
+		return m, nil
+	case playerClipMsg:
+		if m.Phase != PlayerQuestion || msg.questionID != m.Questions[m.Current].Question.ID {
+			return m, nil // The player moved on while the clip was downloading
+		}
+		if msg.err != nil {
+			logger.Error("Failed to download audio clip", "questionID", msg.questionID, "error", msg.err)
+			m.ClipStatus = "The audio clip could not be loaded."
+			return m, nil
+		}
+		m.clip = msg.data
+		return m, m.playClip()
+	case playerImageMsg:
+		if m.Phase != PlayerQuestion || msg.questionID != m.Questions[m.Current].Question.ID {
+			return m, nil // The player moved on while the image was downloading
+		}
+		if msg.err != nil {
+			logger.Error("Failed to load question image", "questionID", msg.questionID, "error", msg.err)
+			m.ImageView = "[The image could not be displayed]"
+			return m, nil
+		}
+		m.Image = msg.img
+		m.renderImage()
+		return m, nil
+	case playerQuizzesMsg:
+		m.Quizzes, m.Categories = msg.quizzes, msg.categories
+		if len(m.Quizzes) == 0 {
+			m.Err = errors.New("there are no active quizzes yet")
+			return m, nil
+		}
+		m.Phase = PlayerSelect
+		m.Form = m.selectForm()
+		return m, m.Form.Init()
+	case playerQuestionsMsg:
+		if len(msg) == 0 {
+			m.Err = errors.New("this quiz has no questions yet")
+			return m, nil
+		}
+		m.start(msg)
+		return m, tea.Batch(m.Form.Init(), playerTick(), m.fetchImage())
+	case playerTickMsg:
+		if m.Phase != PlayerQuestion && m.Phase != PlayerFeedback {
+			return m, nil // Stop ticking once the quiz is over
+		}
+		if m.Deadline.IsZero() {
+			return m, playerTick()
+		}
+		m.Remaining = time.Until(m.Deadline).Round(time.Second)
+		if m.Remaining <= 0 {
+			logger.Info("Quiz time limit reached", "quizID", m.Quiz.ID)
+			m.finish("Time's up!")
+			return m, nil
+		}
+		if m.Remaining <= music.CountdownWindow {
+			music.PlayEffect(music.EffectTick)
+		}
+		return m, playerTick()
+	}
+
+	if m.Form == nil || (m.Phase != PlayerSelect && m.Phase != PlayerQuestion) {
+		return m, nil
+	}
+	form, cmd := m.Form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.Form = f
+	}
+	if m.Form.State != huh.StateCompleted {
+		return m, cmd
+	}
+
+	switch m.Phase {
+	case PlayerSelect:
+		quizID := m.Form.Get("quiz").(int)
+		for _, quiz := range m.Quizzes {
+			if quiz.ID == quizID {
+				m.Quiz = quiz
+			}
+		}
+		logger.Info("Quiz selected", "quizID", quizID)
+		m.Phase = PlayerLoading
+		return m, fetchQuizQuestionsCmd(quizID)
+	case PlayerQuestion:
+		m.answer()
+	}
+	return m, cmd
+}
+
+// selectForm lets the player pick one of the active quizzes, labelled with their category
+func (m *QuizPlayerModel) selectForm() *huh.Form {
+	var options []huh.Option[int]
+	for _, quiz := range m.Quizzes {
+		label := fmt.Sprintf("%s - %s (%d questions, %d mins)", m.Categories[quiz.CategoryId], quiz.Title, quiz.QuestionCount, quiz.TimeLimitInMins)
+		options = append(options, huh.NewOption(label, quiz.ID))
+	}
+	return huh.NewForm(huh.NewGroup(
+		huh.NewSelect[int]().Key("quiz").
+			Title("Select category & quiz").
+			Options(options...),
+	)).WithShowHelp(true)
+}
+
+// start begins the selected quiz with its first question and starts the clock
+func (m *QuizPlayerModel) start(questions []playerQuestion) {
+	m.Questions = questions
+	m.Current = 0
+	m.Score, m.MaxScore, m.Correct = 0, 0, 0
+	m.ClipPlays, m.ClipStatus, m.clip = 0, "", nil
+	m.Image, m.ImageView = nil, ""
+	for _, q := range questions {
+		m.MaxScore += q.Question.Points
+	}
+	if m.Quiz.TimeLimitInMins > 0 {
+		m.Deadline = time.Now().Add(time.Duration(m.Quiz.TimeLimitInMins) * time.Minute)
+		m.Remaining = time.Until(m.Deadline).Round(time.Second)
+	}
+	music.PlaySoundtrack(m.Quiz.Soundtrack)
+	logger.Info("Quiz started", "quizID", m.Quiz.ID, "questions", len(questions))
+	m.Phase = PlayerQuestion
+	m.Form = m.questionForm()
+	m.renderQuestion()
+}
+
+// contentWidth is the width available inside the window boundary
+func (m QuizPlayerModel) contentWidth() int {
+	return m.WindowWidth - 26
+}
+
+// renderQuestion renders the current question's Markdown to fit the window
+func (m *QuizPlayerModel) renderQuestion() {
+	if m.Phase != PlayerQuestion && m.Phase != PlayerFeedback {
+		return
+	}
+	m.QuestionView = markdown.Render(m.Questions[m.Current].Question.Text, m.contentWidth())
+}
+
+// questionForm asks the current question, as a single or multiple choice.
+// The question itself is shown above the form, see QuestionView.
+func (m *QuizPlayerModel) questionForm() *huh.Form {
+	q := m.Questions[m.Current]
+	var options []huh.Option[int]
+	for i, answer := range q.Answers {
+		options = append(options, huh.NewOption(markdown.Render(answer.Text, m.contentWidth()-4), i))
+	}
+	title := fmt.Sprintf("Question %d of %d", m.Current+1, len(m.Questions))
+	var field huh.Field
+	if q.Question.Type == "multiple" {
+		multi := huh.NewMultiSelect[int]().Key("answer").Title(title).Options(options...)
+		if q.Question.MultiChoiceAnsLimit > 0 {
+			multi = multi.Limit(q.Question.MultiChoiceAnsLimit)
+		}
+		field = multi
+	} else {
+		field = huh.NewSelect[int]().Key("answer").Title(title).Options(options...)
+	}
+	return huh.NewForm(huh.NewGroup(field)).WithShowHelp(true)
+}
+
+// answer scores the submitted answer and shows the feedback
+func (m *QuizPlayerModel) answer() {
+	q := m.Questions[m.Current]
+	chosen := make(map[int]bool)
+	switch value := m.Form.Get("answer").(type) {
+	case int:
+		chosen[value] = true
+	case []int:
+		for _, i := range value {
+			chosen[i] = true
+		}
+	}
+
+	correct := true
+	var correctTexts []string
+	for i, a := range q.Answers {
+		if a.IsCorrect {
+			correctTexts = append(correctTexts, a.Text)
+		}
+		if a.IsCorrect != chosen[i] {
+			correct = false
+		}
+	}
+
+	var feedback string
+	if correct {
+		m.Correct++
+		m.Score += q.Question.Points
+		feedback = "**Correct!**"
+		music.PlayEffect(music.EffectCorrect)
+	} else if len(correctTexts) == 1 {
+		feedback = "**Wrong**, the correct answer is:\n\n" + correctTexts[0]
+		music.PlayEffect(music.EffectWrong)
+	} else {
+		feedback = "**Wrong**, the correct answers are:\n\n- " + strings.Join(correctTexts, "\n- ")
+		music.PlayEffect(music.EffectWrong)
+	}
+	if q.Question.HintExplanation != "" {
+		feedback += "\n\n" + q.Question.HintExplanation
+	}
+	m.Feedback = markdown.Render(feedback, m.contentWidth())
+	logger.Info("Question answered", "questionID", q.Question.ID, "correct", correct)
+	m.Phase = PlayerFeedback
+}
+
+// playClip plays the current question's audio clip over the paused background music, downloading it first
+func (m *QuizPlayerModel) playClip() tea.Cmd {
+	q := m.Questions[m.Current].Question
+	limit := config.AppConfig.AudioClipMaxPlays
+	switch {
+	case q.AudioMediaID == nil || m.ClipStatus != "":
+		return nil
+	case limit > 0 && m.ClipPlays >= limit:
+		return nil
+	case m.clip == nil:
+		return fetchAudioClipCmd(q.ID, *q.AudioMediaID)
+	}
+	if err := music.PlayClip(m.clip, fmt.Sprintf("media %d", *q.AudioMediaID)); errors.Is(err, music.ErrClipDropped) {
+		// Not played, so it does not count against the limit and can be tried again
+		logger.Warn("Audio clip not played", "questionID", q.ID, "error", err)
+		return nil
+	} else if err != nil {
+		logger.Error("Failed to play audio clip", "questionID", q.ID, "error", err)
+		m.ClipStatus = "The audio clip cannot be played: " + err.Error()
+		return nil
+	}
+	m.ClipPlays++
+	logger.Info("Playing audio clip", "questionID", q.ID, "plays", m.ClipPlays)
+	return nil
+}
+
+// ClipPlaysLeft returns how often the current question's clip can still be played, -1 when unlimited
+func (m QuizPlayerModel) ClipPlaysLeft() int {
+	if config.AppConfig.AudioClipMaxPlays == 0 {
+		return -1
+	}
+	return config.AppConfig.AudioClipMaxPlays - m.ClipPlays
+}
+
+// fetchImage downloads the current question's image, unless it has none or images are turned off
+func (m *QuizPlayerModel) fetchImage() tea.Cmd {
+	q := m.Questions[m.Current].Question
+	if q.MediaID == nil || m.ImageProtocol == termimage.ProtocolNone {
+		return nil
+	}
+	return fetchQuestionImageCmd(q.ID, *q.MediaID)
+}
+
+// renderImage draws the current question's image to fit inside the window boundary,
+// leaving at most a third of its height to the image so the answers stay visible
+func (m *QuizPlayerModel) renderImage() {
+	if m.Image == nil {
+		return
+	}
+	m.ImageView = termimage.Render(m.Image, m.ImageProtocol, m.Questions[m.Current].Question.ID,
+		m.contentWidth(), (m.WindowHeight-10)/3)
+}
+
+// nextQuestion moves on after the feedback, finishing the quiz after the last question
+func (m *QuizPlayerModel) nextQuestion() {
+	if m.Current == len(m.Questions)-1 {
+		m.finish("")
+		return
+	}
+	m.Current++
+	m.ClipPlays, m.ClipStatus, m.clip = 0, "", nil
+	m.Image, m.ImageView = nil, ""
+	m.Phase = PlayerQuestion
+	m.Form = m.questionForm()
+	m.renderQuestion()
+}
+
+// finish ends the quiz, reason explains an early end
+func (m *QuizPlayerModel) finish(reason string) {
+	m.Phase = PlayerFinished
+	m.Feedback = strings.TrimSpace(fmt.Sprintf("%s\nYou scored %d of %d points, %d of %d questions right.",
+		reason, m.Score, m.MaxScore, m.Correct, len(m.Questions)))
+	music.StopSoundtrack()
+	music.PlayEffect(music.EffectFanfare)
+	logger.Info("Quiz finished", "quizID", m.Quiz.ID, "score", m.Score, "maxScore", m.MaxScore)
+}
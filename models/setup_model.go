@@ -0,0 +1,331 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/music"
+)
+
+// packManifest is the file describing a quiz pack, a directory holding one is zipped for upload
+const packManifest = "pack.json"
+
+// PackListing is a quiz pack shipped with the backend, installed, or both
+type PackListing struct {
+	Name             string `json:"name"`
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	SampleVersion    string `json:"sample_version"`    // empty when the backend ships no such pack
+	InstalledVersion string `json:"installed_version"` // empty when the pack is not installed
+}
+
+// packInstallResult is the backend's summary of an install
+type packInstallResult struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previous_version"`
+	Created         int    `json:"created"`
+	Updated         int    `json:"updated"`
+	Removed         int    `json:"removed"`
+}
+
+// setupPacksMsg carries the packs listed by the backend
+type setupPacksMsg struct {
+	packs []PackListing
+	err   error
+}
+
+// setupDoneMsg reports the outcome of an install or uninstall
+type setupDoneMsg struct {
+	status string
+	err    error
+}
+
+// SetupModel lets admins install, update and uninstall quiz packs, the samples shipped with the backend
+// or packs read from a directory, zip archive or manifest
+type SetupModel struct {
+	common.Model
+	Packs            []PackListing
+	Status           string // outcome of the last install or uninstall
+	Err              error  // why the last backend call failed
+	Busy             bool   // a backend call is in flight
+	PendingUninstall string // name of the pack asked to be uninstalled, empty when not asking
+	EnteringPath     bool   // the path of a pack to install is being entered
+	PathInput        textinput.Model
+}
+
+func InitialSetupModel() SetupModel {
+	logger.Info("InitialSetupModel called")
+	input := textinput.New()
+	input.Placeholder = "path to a pack directory, zip archive or pack.json"
+	input.Prompt = "Pack: "
+	return SetupModel{
+		Model:     common.Model{CurrentScreen: "Setup"},
+		Busy:      true,
+		PathInput: input,
+	}
+}
+
+// FetchPacksCmd lists the sample and installed packs
+func FetchPacksCmd() tea.Cmd {
+	return func() tea.Msg {
+		var packs []PackListing
+		if err := getJSON(config.AppConfig.BackendURL+"/packs", &packs); err != nil {
+			return setupPacksMsg{err: fmt.Errorf("error fetching packs: %w", err)}
+		}
+		logger.Info("Fetched quiz packs", "count", len(packs))
+		return setupPacksMsg{packs: packs}
+	}
+}
+
+// installSampleCmd installs or updates a sample pack shipped with the backend
+func installSampleCmd(name string) tea.Cmd {
+	return func() tea.Msg {
+		return installPack(config.AppConfig.BackendURL+"/packs?sample="+url.QueryEscape(name), nil, "")
+	}
+}
+
+// installPathCmd installs or updates the pack read from path
+func installPathCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		body, contentType, err := packUpload(path)
+		if err != nil {
+			return setupDoneMsg{err: fmt.Errorf("error reading pack %s: %w", path, err)}
+		}
+		return installPack(config.AppConfig.BackendURL+"/packs", body, contentType)
+	}
+}
+
+func installPack(endpoint string, body []byte, contentType string) setupDoneMsg {
+	data, err := packRequest(http.MethodPost, endpoint, body, contentType)
+	if err != nil {
+		return setupDoneMsg{err: fmt.Errorf("error installing pack: %w", err)}
+	}
+	var result packInstallResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return setupDoneMsg{err: fmt.Errorf("error installing pack: %w", err)}
+	}
+	logger.Info("Installed quiz pack", "pack", result.Name, "version", result.Version, "previousVersion", result.PreviousVersion)
+	if result.PreviousVersion == "" {
+		return setupDoneMsg{status: fmt.Sprintf("Installed %s %s with %d quizzes.", result.Name, result.Version, result.Created)}
+	}
+	return setupDoneMsg{status: fmt.Sprintf("Updated %s from %s to %s: %d quizzes created, %d updated, %d removed.",
+		result.Name, result.PreviousVersion, result.Version, result.Created, result.Updated, result.Removed)}
+}
+
+// uninstallPackCmd removes an installed pack and its quizzes
+func uninstallPackCmd(name string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := packRequest(http.MethodDelete, config.AppConfig.BackendURL+"/packs/"+url.PathEscape(name), nil, ""); err != nil {
+			return setupDoneMsg{err: fmt.Errorf("error uninstalling pack: %w", err)}
+		}
+		logger.Info("Uninstalled quiz pack", "pack", name)
+		return setupDoneMsg{status: fmt.Sprintf("Uninstalled %s.", name)}
+	}
+}
+
+// packRequest sends a request to the packs endpoints, returning the backend's message as the error when it fails
+func packRequest(method, endpoint string, body []byte, contentType string) ([]byte, error) {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		if message := strings.TrimSpace(string(data)); message != "" {
+			return nil, errors.New(message)
+		}
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+	return data, nil
+}
+
+// packUpload reads a pack to upload: a directory, or the one holding a pack.json, is zipped with its media,
+// while a zip archive or another manifest is sent as is
+func packUpload(path string) ([]byte, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() && filepath.Base(path) == packManifest {
+		path = filepath.Dir(path)
+	} else if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if strings.EqualFold(filepath.Ext(path), ".zip") {
+			return data, "application/zip", err
+		}
+		return data, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(path, name)
+		if err != nil {
+			return err
+		}
+		w, err := archive.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "application/zip", nil
+}
+
+func UpdateSetup(m SetupModel, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.WindowWidth = msg.Width
+		m.WindowHeight = msg.Height
+		m.PathInput.Width = m.WindowWidth - 40
+	case setupPacksMsg:
+		m.Busy = false
+		if msg.err != nil {
+			logger.Error("Failed to list quiz packs", "error", msg.err)
+			m.Err = msg.err
+			return m, nil
+		}
+		m.Packs = msg.packs
+		if m.Cursor >= len(m.Packs) {
+			m.Cursor = len(m.Packs) - 1
+		}
+		if m.Cursor < 0 {
+			m.Cursor = 0
+		}
+	case setupDoneMsg:
+		m.Status, m.Err = msg.status, msg.err
+		if msg.err != nil {
+			logger.Error("Quiz pack operation failed", "error", msg.err)
+		}
+		return m, FetchPacksCmd()
+	case tea.KeyMsg:
+		logger.Info("Key pressed", "key", msg.String(), "currentScreen", m.CurrentScreen)
+		if m.EnteringPath {
+			return m.updatePathInput(msg)
+		}
+		if m.PendingUninstall != "" {
+			return m.confirmUninstall(msg)
+		}
+		switch msg.String() {
+		case "esc":
+			return m, func() tea.Msg { return common.SetupClosedMsg{} }
+		case music.KeyMute, music.KeyVolumeUp, music.KeyVolumeDown, music.KeyNextTrack, music.KeyPreviousTrack:
+			logger.Info("Music control", "key", msg.String(), "currentScreen", m.CurrentScreen)
+			music.HandleKey(msg.String())
+		case "up", "k":
+			if m.Cursor > 0 {
+				m.Cursor--
+			}
+		case "down", "j":
+			if m.Cursor < len(m.Packs)-1 {
+				m.Cursor++
+			}
+		}
+		if m.Busy {
+			return m, nil
+		}
+		switch msg.String() {
+		case "enter", "i":
+			if len(m.Packs) == 0 {
+				return m, nil
+			}
+			pack := m.Packs[m.Cursor]
+			if pack.SampleVersion == "" {
+				m.Status, m.Err = fmt.Sprintf("%s is not shipped with the backend, press f to install a new version from a file.", pack.Name), nil
+				return m, nil
+			}
+			m.Busy, m.Status, m.Err = true, fmt.Sprintf("Installing %s...", pack.Name), nil
+			return m, installSampleCmd(pack.Name)
+		case "u":
+			if len(m.Packs) > 0 && m.Packs[m.Cursor].InstalledVersion != "" {
+				m.PendingUninstall = m.Packs[m.Cursor].Name
+			}
+		case "f":
+			m.EnteringPath = true
+			return m, m.PathInput.Focus()
+		case "r":
+			m.Busy = true
+			return m, FetchPacksCmd()
+		}
+	}
+	return m, nil
+}
+
+// updatePathInput handles the keys while the path of a pack is entered, installing it with enter
+func (m SetupModel) updatePathInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.EnteringPath = false
+		m.PathInput.Blur()
+		return m, nil
+	case "enter":
+		path := strings.TrimSpace(m.PathInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.EnteringPath = false
+		m.PathInput.Blur()
+		m.PathInput.SetValue("")
+		m.Busy, m.Status, m.Err = true, fmt.Sprintf("Installing %s...", path), nil
+		return m, installPathCmd(path)
+	}
+	var cmd tea.Cmd
+	m.PathInput, cmd = m.PathInput.Update(msg)
+	return m, cmd
+}
+
+// confirmUninstall handles the keys while asked to uninstall a pack, uninstalling it with y or keeping it with n
+func (m SetupModel) confirmUninstall(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		name := m.PendingUninstall
+		m.PendingUninstall = ""
+		m.Busy, m.Status, m.Err = true, fmt.Sprintf("Uninstalling %s...", name), nil
+		return m, uninstallPackCmd(name)
+	case "n", "esc":
+		m.PendingUninstall = ""
+	}
+	return m, nil
+}
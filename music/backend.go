@@ -0,0 +1,49 @@
+package music
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Output format shared by every backend: 44.1kHz stereo signed 16-bit little endian PCM.
+// Every decoded file is resampled and remixed to it, see decodeAudio
+const (
+	SampleRate   = 44100
+	ChannelCount = 2
+)
+
+// Supported values for the audio_backend setting
+const (
+	BackendOto  = "oto"  // play through the system sound device
+	BackendNone = "none" // discard all audio, e.g. on headless machines or over SSH
+)
+
+// errNoAudio is reported when audio was disabled through configuration
+var errNoAudio = errors.New("disabled by configuration")
+
+// Player plays a single PCM stream
+type Player interface {
+	Play()
+	Pause()
+	IsPlaying() bool
+	Close() error
+}
+
+// AudioBackend turns PCM streams into players on some output device
+type AudioBackend interface {
+	Name() string
+	NewPlayer(pcm io.Reader) Player
+}
+
+// OpenBackend opens the named backend, see BackendOto and BackendNone
+func OpenBackend(name string) (AudioBackend, error) {
+	switch name {
+	case BackendOto, "":
+		return newOtoBackend()
+	case BackendNone:
+		return nullBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported audio backend %q", name)
+	}
+}
@@ -0,0 +1,51 @@
+package music
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"letsquiz/logger"
+)
+
+var clipPlaying atomic.Bool // Whether a question's audio clip is playing, for the UI
+
+// ErrClipDropped is returned by PlayClip when the playback loop is too busy to take the clip,
+// it has not been played and can be tried again
+var ErrClipDropped = errors.New("audio is busy, the clip was not played")
+
+// PlayClip pauses the background music and plays a question's audio clip, an MP3, OGG Vorbis or WAV file,
+// through the same audio backend, also when no background music is playing. The music resumes once the clip
+// has played; playing another clip while one is playing starts over. name is only used to guess the format
+// by extension.
+func PlayClip(data []byte, name string) error {
+	if ok, err := Available(); !ok && err != nil {
+		return err
+	}
+	pcm, err := decodeAudio(data, name)
+	if err != nil {
+		return err
+	}
+	if !send(command{kind: commandClip, clip: pcm}) {
+		return ErrClipDropped
+	}
+	return nil
+}
+
+// ClipPlaying reports whether an audio clip is playing, or paused because the music is muted
+func ClipPlaying() bool {
+	return clipPlaying.Load()
+}
+
+func startClip(backend AudioBackend, pcm io.Reader) Player {
+	logger.Info("Playing audio clip", "volume", Volume())
+	clip := backend.NewPlayer(&volumeReader{src: pcm})
+	clipPlaying.Store(true)
+	applyMute(clip)
+	return clip
+}
+
+func stopClip(clip Player) {
+	clip.Close()
+	clipPlaying.Store(false)
+}
@@ -0,0 +1,327 @@
+package music
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"  // Import MP3 decoder for decoding MP3 files
+	"github.com/jfreymuth/oggvorbis" // Import Vorbis decoder for decoding OGG files
+)
+
+// Supported audio formats
+const (
+	FormatMP3 = "mp3"
+	FormatOGG = "ogg"
+	FormatWAV = "wav"
+)
+
+// formatExtensions maps file extensions to formats, used when the content is not recognized
+var formatExtensions = map[string]string{
+	".mp3":  FormatMP3,
+	".ogg":  FormatOGG,
+	".oga":  FormatOGG,
+	".wav":  FormatWAV,
+	".wave": FormatWAV,
+}
+
+// bytesPerFrame is the size of one output frame: a 16-bit sample per channel
+const bytesPerFrame = 2 * ChannelCount
+
+// detectFormat recognizes the audio format by its magic bytes, falling back to the file extension of name
+func detectFormat(data []byte, name string) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return FormatOGG
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return FormatWAV
+	case bytes.HasPrefix(data, []byte("ID3")), len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		return FormatMP3 // ID3 tag or MPEG frame sync
+	}
+	return formatExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// IsAudioFile reports whether name has the extension of a supported audio format
+func IsAudioFile(name string) bool {
+	return formatExtensions[strings.ToLower(path.Ext(name))] != ""
+}
+
+// ResolveTrack returns name if it exists, otherwise the first existing file named name plus a supported
+// extension, e.g. "theme" finds theme.mp3, theme.ogg or theme.wav. Unresolved names are returned as is.
+func ResolveTrack(name string) string {
+	if _, err := os.Stat(name); err == nil || IsAudioFile(name) {
+		return name
+	}
+	for _, ext := range []string{".mp3", ".ogg", ".wav"} {
+		if _, err := os.Stat(name + ext); err == nil {
+			return name + ext
+		}
+	}
+	return name
+}
+
+// decodeAudio decodes an MP3, OGG Vorbis or WAV file into 44.1kHz stereo signed 16-bit PCM,
+// resampling and remixing channels as needed. name is only used to guess the format by extension.
+func decodeAudio(data []byte, name string) (io.Reader, error) {
+	var pcm io.Reader
+	var rate int
+	switch format := detectFormat(data, name); format {
+	case FormatMP3:
+		decoder, err := mp3.NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		pcm, rate = decoder, decoder.SampleRate() // go-mp3 always produces stereo 16-bit samples
+	case FormatOGG:
+		decoder, err := oggvorbis.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		pcm, rate = &oggReader{src: decoder, channels: decoder.Channels()}, decoder.SampleRate()
+	case FormatWAV:
+		reader, sampleRate, err := newWAVReader(data)
+		if err != nil {
+			return nil, err
+		}
+		pcm, rate = reader, sampleRate
+	default:
+		return nil, fmt.Errorf("unsupported audio format, expected MP3, OGG Vorbis or WAV: %s", name)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d: %s", rate, name)
+	}
+	if rate == SampleRate {
+		return pcm, nil
+	}
+	return newResampler(pcm, rate), nil
+}
+
+// decodeAll decodes a whole file into PCM, for short sounds that are cached in memory
+func decodeAll(data []byte, name string) ([]byte, error) {
+	pcm, err := decodeAudio(data, name)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(pcm)
+}
+
+// stereoFrame converts the samples of one frame to a stereo pair, duplicating mono and dropping extra channels
+func stereoFrame(samples []int16) (int16, int16) {
+	if len(samples) == 1 {
+		return samples[0], samples[0]
+	}
+	return samples[0], samples[1]
+}
+
+// oggReader converts the interleaved float samples of a Vorbis stream to stereo 16-bit PCM
+type oggReader struct {
+	src      *oggvorbis.Reader
+	channels int
+	buf      []float32
+}
+
+func (r *oggReader) Read(p []byte) (int, error) {
+	frames := len(p) / bytesPerFrame
+	if frames == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	if cap(r.buf) < frames*r.channels {
+		r.buf = make([]float32, frames*r.channels)
+	}
+	n, err := r.src.Read(r.buf[:frames*r.channels])
+	samples := make([]int16, r.channels)
+	out := 0
+	for i := 0; i+r.channels <= n; i += r.channels {
+		for c := range samples {
+			samples[c] = floatToInt16(float64(r.buf[i+c]))
+		}
+		left, right := stereoFrame(samples)
+		binary.LittleEndian.PutUint16(p[out:], uint16(left))
+		binary.LittleEndian.PutUint16(p[out+2:], uint16(right))
+		out += bytesPerFrame
+	}
+	return out, err
+}
+
+func floatToInt16(v float64) int16 {
+	return int16(math.Max(-1, math.Min(1, v)) * math.MaxInt16)
+}
+
+// wavReader converts the frames of a WAV data chunk to stereo 16-bit PCM
+type wavReader struct {
+	data          []byte
+	channels      int
+	bytesPerValue int
+	float         bool
+}
+
+// WAV format tags
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// newWAVReader parses the RIFF chunks of a WAV file, supporting 8/16/24/32-bit integer and 32/64-bit float samples
+func newWAVReader(data []byte) (*wavReader, int, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, errors.New("not a WAV file")
+	}
+	var r *wavReader
+	var rate int
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		start := offset + 8
+		end := start + size
+		if size < 0 || end > len(data) {
+			end = len(data) // Tolerate a truncated last chunk
+		}
+		chunk := data[start:end]
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, 0, errors.New("invalid WAV fmt chunk")
+			}
+			format := binary.LittleEndian.Uint16(chunk)
+			if format == wavFormatExtensible && len(chunk) >= 26 {
+				format = binary.LittleEndian.Uint16(chunk[24:]) // The sub format GUID starts with the format tag
+			}
+			channels := int(binary.LittleEndian.Uint16(chunk[2:]))
+			rate = int(binary.LittleEndian.Uint32(chunk[4:]))
+			bits := int(binary.LittleEndian.Uint16(chunk[14:]))
+			r = &wavReader{channels: channels, bytesPerValue: bits / 8, float: format == wavFormatFloat}
+			switch {
+			case channels < 1:
+				return nil, 0, fmt.Errorf("invalid WAV channel count %d", channels)
+			case format == wavFormatPCM && (bits == 8 || bits == 16 || bits == 24 || bits == 32):
+			case format == wavFormatFloat && (bits == 32 || bits == 64):
+			default:
+				return nil, 0, fmt.Errorf("unsupported WAV encoding: format %d with %d bits per sample", format, bits)
+			}
+		case "data":
+			if r == nil {
+				return nil, 0, errors.New("WAV data chunk before fmt chunk")
+			}
+			r.data = chunk
+			return r, rate, nil
+		}
+		offset = start + size + size%2 // Chunks are padded to an even size
+	}
+	return nil, 0, errors.New("WAV file has no data chunk")
+}
+
+func (r *wavReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	frameSize := r.channels * r.bytesPerValue
+	frames := len(p) / bytesPerFrame
+	if frames == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	samples := make([]int16, r.channels)
+	out := 0
+	for ; frames > 0 && len(r.data) >= frameSize; frames-- {
+		for c := range samples {
+			samples[c] = r.sample(r.data[c*r.bytesPerValue:])
+		}
+		left, right := stereoFrame(samples)
+		binary.LittleEndian.PutUint16(p[out:], uint16(left))
+		binary.LittleEndian.PutUint16(p[out+2:], uint16(right))
+		out += bytesPerFrame
+		r.data = r.data[frameSize:]
+	}
+	if len(r.data) < frameSize {
+		r.data = nil // Drop a trailing partial frame
+	}
+	return out, nil
+}
+
+// sample converts one little endian sample to 16 bits
+func (r *wavReader) sample(b []byte) int16 {
+	switch {
+	case r.float && r.bytesPerValue == 4:
+		return floatToInt16(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case r.float:
+		return floatToInt16(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case r.bytesPerValue == 1:
+		return int16(int(b[0])-128) << 8 // 8-bit WAV samples are unsigned
+	case r.bytesPerValue == 2:
+		return int16(binary.LittleEndian.Uint16(b))
+	case r.bytesPerValue == 3:
+		return int16(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 16)
+	default:
+		return int16(int32(binary.LittleEndian.Uint32(b)) >> 16)
+	}
+}
+
+// resampler converts a stereo 16-bit stream to SampleRate with linear interpolation
+type resampler struct {
+	src    *bufio.Reader
+	step   float64 // source frames per output frame
+	pos    float64 // position of the next output frame between prev (0) and next (1)
+	prev   [ChannelCount]int16
+	next   [ChannelCount]int16
+	primed bool
+}
+
+func newResampler(src io.Reader, rate int) *resampler {
+	return &resampler{src: bufio.NewReader(src), step: float64(rate) / SampleRate}
+}
+
+func (r *resampler) readFrame() ([ChannelCount]int16, error) {
+	var frame [ChannelCount]int16
+	var buf [bytesPerFrame]byte
+	if _, err := io.ReadFull(r.src, buf[:]); err != nil {
+		return frame, err
+	}
+	for c := range frame {
+		frame[c] = int16(binary.LittleEndian.Uint16(buf[2*c:]))
+	}
+	return frame, nil
+}
+
+func (r *resampler) Read(p []byte) (int, error) {
+	if len(p) < bytesPerFrame {
+		return 0, io.ErrShortBuffer
+	}
+	if !r.primed {
+		var err error
+		if r.prev, err = r.readFrame(); err != nil {
+			return 0, io.EOF
+		}
+		if r.next, err = r.readFrame(); err != nil {
+			r.next = r.prev
+		}
+		r.primed = true
+	}
+	n := 0
+	for n+bytesPerFrame <= len(p) {
+		for r.pos >= 1 {
+			frame, err := r.readFrame()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, io.EOF
+			}
+			r.prev, r.next = r.next, frame
+			r.pos--
+		}
+		for c := 0; c < ChannelCount; c++ {
+			v := float64(r.prev[c]) + (float64(r.next[c])-float64(r.prev[c]))*r.pos
+			binary.LittleEndian.PutUint16(p[n+2*c:], uint16(int16(math.Round(v))))
+		}
+		n += bytesPerFrame
+		r.pos += r.step
+	}
+	return n, nil
+}
@@ -0,0 +1,151 @@
+package music
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"letsquiz/logger"
+)
+
+// Effect is a short cue played over the background music
+type Effect string
+
+const (
+	EffectCorrect Effect = "correct" // the answer was right
+	EffectWrong   Effect = "wrong"   // the answer was wrong
+	EffectTick    Effect = "tick"    // one second of the final countdown passed
+	EffectFanfare Effect = "fanfare" // the quiz is finished
+)
+
+// maxConcurrentEffects bounds the one-shot players alive at once, the oldest is cut off beyond it
+const maxConcurrentEffects = 8
+
+// CountdownWindow is the final part of a quiz's time limit during which EffectTick is played every second
+const CountdownWindow = 10 * time.Second
+
+// effectMixer plays effects on the background track's backend, which mixes concurrent players
+type effectMixer struct {
+	mu      sync.Mutex
+	backend AudioBackend
+	sounds  map[Effect][]byte // decoded PCM, loaded once
+	players []Player
+}
+
+var effects effectMixer
+
+// initEffects decodes every effect from dir, e.g. sounds/correct.mp3 or sounds/correct.wav,
+// falling back to a synthesized tone for effects without a file
+func initEffects(backend AudioBackend, dir string) {
+	sounds := make(map[Effect][]byte)
+	for _, effect := range []Effect{EffectCorrect, EffectWrong, EffectTick, EffectFanfare} {
+		pcm, err := loadEffect(ResolveTrack(filepath.Join(dir, string(effect))))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Failed to load sound effect, using a built-in tone", "effect", effect, "error", err)
+			}
+			pcm = synthesizeEffect(effect)
+		}
+		sounds[effect] = pcm
+	}
+
+	effects.mu.Lock()
+	defer effects.mu.Unlock()
+	effects.backend = backend
+	effects.sounds = sounds
+}
+
+func loadEffect(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll(data, path)
+}
+
+// PlayEffect plays a cue over the background music. It does nothing while sound effects are
+// disabled, the music is muted or the audio backend has not been opened yet.
+func PlayEffect(effect Effect) {
+	if muted.Load() {
+		return
+	}
+	effects.mu.Lock()
+	defer effects.mu.Unlock()
+	pcm, ok := effects.sounds[effect]
+	if effects.backend == nil || !ok {
+		return
+	}
+
+	// Drop players that finished, and cut off the oldest one when too many overlap
+	active := effects.players[:0]
+	for _, player := range effects.players {
+		if player.IsPlaying() {
+			active = append(active, player)
+		} else {
+			player.Close()
+		}
+	}
+	if len(active) >= maxConcurrentEffects {
+		active[0].Close()
+		active = active[1:]
+	}
+
+	player := effects.backend.NewPlayer(&volumeReader{src: bytes.NewReader(pcm)})
+	player.Play()
+	effects.players = append(active, player)
+}
+
+// closeEffects stops every effect still playing at shutdown
+func closeEffects() {
+	effects.mu.Lock()
+	defer effects.mu.Unlock()
+	for _, player := range effects.players {
+		player.Close()
+	}
+	effects.players = nil
+	effects.backend = nil
+}
+
+// tone is one note of a synthesized effect
+type tone struct {
+	frequency float64
+	duration  time.Duration
+}
+
+// builtinEffects are played when no effect file is provided
+var builtinEffects = map[Effect][]tone{
+	EffectCorrect: {{660, 100 * time.Millisecond}, {880, 160 * time.Millisecond}},
+	EffectWrong:   {{220, 150 * time.Millisecond}, {165, 250 * time.Millisecond}},
+	EffectTick:    {{1000, 40 * time.Millisecond}},
+	EffectFanfare: {{523, 120 * time.Millisecond}, {659, 120 * time.Millisecond}, {784, 120 * time.Millisecond}, {1047, 360 * time.Millisecond}},
+}
+
+// synthesizeEffect renders an effect's tones as stereo PCM with short fades to avoid clicks
+func synthesizeEffect(effect Effect) []byte {
+	const amplitude = 0.3 * math.MaxInt16
+	const fade = 5 * time.Millisecond
+	var buf bytes.Buffer
+	for _, t := range builtinEffects[effect] {
+		samples := int(t.duration.Seconds() * SampleRate)
+		fadeSamples := int(fade.Seconds() * SampleRate)
+		for i := 0; i < samples; i++ {
+			gain := 1.0
+			if i < fadeSamples {
+				gain = float64(i) / float64(fadeSamples)
+			} else if samples-i < fadeSamples {
+				gain = float64(samples-i) / float64(fadeSamples)
+			}
+			value := int16(amplitude * gain * math.Sin(2*math.Pi*t.frequency*float64(i)/SampleRate))
+			for c := 0; c < ChannelCount; c++ {
+				binary.Write(&buf, binary.LittleEndian, value)
+			}
+		}
+	}
+	return buf.Bytes()
+}
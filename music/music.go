@@ -1,115 +1,341 @@
 package music
 
 import (
-	"bytes"
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/ebitengine/oto/v3"  // Import Oto for audio playback
-	"github.com/hajimehoshi/go-mp3" // Import MP3 decoder for decoding MP3 files
-	"letsquiz/common"
+	"letsquiz/logger"
 )
 
-var player *oto.Player         // Player instance for audio playback
-var context *oto.Context       // Audio context for managing audio settings
-var appModel *common.Model     // Application model that controls playback state
-var shutdownChan chan struct{} // Channel to signal when the application is shutting down
+// trackEndPollInterval is how often the playback loop checks whether the current track has finished,
+// oto offers no completion callback
+const trackEndPollInterval = 200 * time.Millisecond
+
+// soundtrackDownloadTimeout bounds fetching a quiz soundtrack given as a URL
+const soundtrackDownloadTimeout = 30 * time.Second
+
+// Key bindings handled by HandleKey on every screen
+const (
+	KeyMute          = "alt+end"
+	KeyVolumeUp      = "alt+up"
+	KeyVolumeDown    = "alt+down"
+	KeyNextTrack     = "alt+pgdown"
+	KeyPreviousTrack = "alt+pgup"
+)
+
+// Options selects the audio backend and the background music
+type Options struct {
+	Backend string   // see BackendOto and BackendNone
+	Tracks  []string // audio files or directories of audio files, played in order
+	Shuffle bool     // shuffle the tracks once at startup
 
-// SetModel initializes the music package with the provided application model
-func SetModel(model *common.Model) {
-	appModel = model
+	Effects    bool   // play sound effects for quiz events, see PlayEffect
+	EffectsDir string // directory with effect files overriding the built-in tones, e.g. correct.mp3
 }
 
+// commandKind identifies a request sent to the playback loop
+type commandKind int
+
+const (
+	commandMute       commandKind = iota // the mute state changed
+	commandNext                          // skip to the next playlist track
+	commandPrevious                      // go back to the previous playlist track
+	commandSoundtrack                    // play a quiz soundtrack, or return to the playlist when source is empty
+	commandClip                          // pause the track and play a question's audio clip over it
+	commandFinished                      // the current track played to the end
+	commandShutdown                      // the application is closing
+)
+
+type command struct {
+	kind   commandKind
+	source string
+	clip   io.Reader // decoded PCM of a commandClip
+}
+
+var shutdownChan chan struct{} // Channel to signal when the application is shutting down
+
+var muted atomic.Bool                // Mute state, toggled from the UI and applied by the playback loop
+var commands = make(chan command, 8) // Requests from the UI to the playback loop
+var status audioStatus               // Audio availability reported to the UI
+
 // SetShutdownChannel initializes the shutdown channel for handling graceful shutdown
 func SetShutdownChannel(ch chan struct{}) {
 	shutdownChan = ch
 }
 
-// PlayBackgroundMusic starts playing the specified MP3 file as background music
-func PlayBackgroundMusic(filePath string) {
-	// Initialize the Oto audio context with specified options
-	options := &oto.NewContextOptions{
-		SampleRate:   44100,                   // Sample rate for audio playback
-		ChannelCount: 2,                       // Number of audio channels (stereo)
-		Format:       oto.FormatSignedInt16LE, // Audio format (mp3)
-		BufferSize:   8192,                    // Buffer size for audio playback
-	}
-	var err error
-	context, readyChan, err := oto.NewContext(options)
+// PlayBackgroundMusic opens the audio backend and plays the playlist in a loop until shutdown,
+// switching to a quiz soundtrack while one is requested through PlaySoundtrack.
+// Failures never stop the application: without a sound device the audio is reported as unavailable,
+// and without a playable track the loop goes on silently, still playing question clips and soundtracks,
+// so the TUI keeps working either way.
+func PlayBackgroundMusic(opts Options) {
+	loadState()
+
+	backend, err := OpenBackend(opts.Backend)
 	if err != nil {
-		log.Fatal("Failed to create audio context:", err) // Log error and exit if context creation fails
+		// Fall back to the null sink so mute and playback state keep behaving the same
+		unavailable(fmt.Errorf("audio backend %q: %w", opts.Backend, err))
+		backend = nullBackend{}
+	} else if backend.Name() == BackendNone {
+		unavailable(errNoAudio)
+	} else {
+		status.set(true, nil)
+		logger.Info("Audio available", "backend", backend.Name())
+	}
+	if opts.Effects {
+		initEffects(backend, opts.EffectsDir)
+		defer closeEffects()
 	}
-	<-readyChan // Wait until the context is ready
 
-	// Read the MP3 file into memory
-	fileBytes, err := os.ReadFile(filePath)
-	if err != nil {
-		log.Fatal("Failed to read audio file:", err) // Log error and exit if file reading fails
+	tracks := newPlaylist(opts.Tracks, opts.Shuffle)
+	if tracks.len() == 0 {
+		logger.Warn("The playlist has no tracks, continuing without background music")
 	}
 
-	// Convert the file bytes into a reader object
-	fileBytesReader := bytes.NewReader(fileBytes)
+	ticker := time.NewTicker(trackEndPollInterval)
+	defer ticker.Stop()
+	soundtrack := ""
+	failures := 0 // playlist tracks that failed in a row, all of them leave the loop silent
+	for {
+		source := soundtrack
+		if source == "" && failures < tracks.len() {
+			source = tracks.current()
+		}
 
-	// Create a new MP3 decoder to decode the MP3 file
-	decodedMp3, err := mp3.NewDecoder(fileBytesReader)
-	if err != nil {
-		log.Fatal("Failed to create MP3 decoder:", err) // Log error and exit if decoder creation fails
+		var player Player
+		if source == "" {
+			// Nothing to play, a silent track keeps serving clips, mute and soundtracks
+			player = nullBackend{}.NewPlayer(nil)
+		} else {
+			pcm, err := openTrack(source)
+			if err != nil {
+				logger.Warn("Skipping track that cannot be played", "track", source, "error", err)
+				if soundtrack != "" {
+					soundtrack = "" // Fall back to the playlist
+					continue
+				}
+				failures++
+				if failures >= tracks.len() {
+					logger.Warn("No playable track, continuing without background music", "error", err)
+				} else {
+					tracks.next()
+				}
+				continue
+			}
+			if soundtrack == "" {
+				failures = 0
+			}
+			logger.Info("Playing track", "track", source, "volume", Volume())
+			player = backend.NewPlayer(&volumeReader{src: pcm})
+		}
+		applyMute(player)
+		c := waitForTrack(backend, player, ticker, soundtrack != "")
+		player.Close()
+
+		switch c.kind {
+		case commandShutdown:
+			return
+		case commandFinished, commandNext:
+			if soundtrack == "" {
+				tracks.next()
+			} // A soundtrack repeats until the quiz ends
+			if c.kind == commandNext {
+				failures = 0 // Try the playlist again
+			}
+		case commandPrevious:
+			tracks.previous()
+			failures = 0
+		case commandSoundtrack:
+			soundtrack = c.source
+		}
 	}
+}
 
+// waitForTrack applies mute changes until the track finishes or another command needs a new player.
+// Track skipping is ignored while a soundtrack plays. A clip pauses the track until it has played.
+func waitForTrack(backend AudioBackend, player Player, ticker *time.Ticker, soundtrack bool) command {
+	var clip Player
+	defer func() {
+		if clip != nil {
+			stopClip(clip)
+		}
+	}()
 	for {
 		select {
 		case <-shutdownChan: // Handle shutdown signal
-			if player != nil {
-				player.Close() // Close the player if it is open
+			return command{kind: commandShutdown}
+		case c := <-commands:
+			switch {
+			case c.kind == commandMute && clip != nil:
+				applyMute(clip)
+			case c.kind == commandMute:
+				applyMute(player)
+			case c.kind == commandClip:
+				if clip != nil {
+					stopClip(clip) // A replay starts the clip over
+				}
+				player.Pause()
+				clip = startClip(backend, c.clip)
+			case soundtrack && (c.kind == commandNext || c.kind == commandPrevious):
+			default:
+				return c
 			}
-			return // Exit the function when shutdown signal is received
-		default:
-			// Reset the reader to the beginning of the MP3 file
-			fileBytesReader.Seek(0, 0)
-
-			// Create a new player for audio playback
-			player = context.NewPlayer(decodedMp3)
-			player.Play() // Start playing the audio
-
-			// Start a goroutine to monitor the playback state
-			go func() {
-				for {
-					if appModel == nil {
-						time.Sleep(100 * time.Millisecond) // Wait if appModel is not initialized
-						continue
-					}
-					// Pause or play the audio based on the application's playback state
-					if !appModel.IsPlaying {
-						player.Pause()
-					} else {
-						player.Play()
-					}
-					time.Sleep(100 * time.Millisecond) // Sleep to prevent busy loop
+		case <-ticker.C:
+			if clip != nil {
+				if !muted.Load() && !clip.IsPlaying() {
+					stopClip(clip)
+					clip = nil
+					applyMute(player) // Resume the track where the clip interrupted it
 				}
-			}()
-
-			// Wait for the audio playback to finish
-			for player.IsPlaying() {
-				time.Sleep(time.Millisecond)
+				continue
+			}
+			// A paused player is not playing either, only a running one can reach the end of the track
+			if !muted.Load() && !player.IsPlaying() {
+				return command{kind: commandFinished}
 			}
-
-			player.Close() // Close the player after playback finishes
 		}
 	}
 }
 
+// openTrack decodes an audio file, or one downloaded from an http(s) URL, into PCM
+func openTrack(source string) (io.Reader, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = download(source)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeAudio(data, source)
+}
+
+func download(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), soundtrackDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download track, status code: %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// applyMute plays or pauses the player according to the current mute state
+func applyMute(player Player) {
+	if muted.Load() {
+		player.Pause()
+	} else {
+		player.Play()
+	}
+}
+
+// unavailable records that audio cannot be played, the application carries on without it
+func unavailable(err error) {
+	status.set(false, err)
+	logger.Warn("Audio unavailable, continuing without sound", "error", err)
+}
+
+// send passes a command to the playback loop without ever blocking the UI, reporting whether it was queued
+func send(c command) bool {
+	select {
+	case commands <- c:
+		return true
+	default:
+		logger.Warn("Music command dropped, playback loop is busy", "command", c.kind)
+		return false
+	}
+}
+
+// HandleKey performs the music action bound to key, see KeyMute and friends
+func HandleKey(key string) {
+	switch key {
+	case KeyMute:
+		ToggleMusicMuteUnmute()
+	case KeyVolumeUp:
+		VolumeUp()
+	case KeyVolumeDown:
+		VolumeDown()
+	case KeyNextTrack:
+		send(command{kind: commandNext})
+	case KeyPreviousTrack:
+		send(command{kind: commandPrevious})
+	}
+}
+
+// PlaySoundtrack replaces the playlist with a quiz soundtrack, a file path or an http(s) URL,
+// until StopSoundtrack is called. An empty source is ignored.
+func PlaySoundtrack(source string) {
+	if source != "" {
+		send(command{kind: commandSoundtrack, source: source})
+	}
+}
+
+// StopSoundtrack returns to the playlist after a quiz soundtrack
+func StopSoundtrack() {
+	send(command{kind: commandSoundtrack})
+}
+
 // ToggleMusicMuteUnmute toggles the music playback state between playing and paused
 func ToggleMusicMuteUnmute() {
-	if appModel != nil {
-		appModel.IsPlaying = !appModel.IsPlaying // Toggle playback state
-		if player != nil {
-			// Play or pause the audio based on the new playback state
-			if appModel.IsPlaying {
-				player.Play()
-			} else {
-				player.Pause()
-			}
+	for {
+		current := muted.Load()
+		if muted.CompareAndSwap(current, !current) {
+			break
 		}
 	}
+	send(command{kind: commandMute})
+}
+
+// Muted reports whether the music is muted
+func Muted() bool {
+	return muted.Load()
+}
+
+// Available reports whether audio is playing through a sound device, and why not otherwise
+func Available() (bool, error) {
+	return status.get()
+}
+
+// FooterHint returns the key help shown at the bottom of each screen, mentioning the audio state
+func FooterHint() string {
+	if ok, err := Available(); !ok && err != nil {
+		return "Press esc to quit. Audio unavailable."
+	}
+	return fmt.Sprintf("Press esc to quit, alt+end to mute/unmute, alt+up/down volume (%d%%), alt+pgup/pgdown track.", Volume())
+}
+
+// audioStatus is written by the playback goroutine and read while rendering
+type audioStatus struct {
+	mu        sync.RWMutex
+	available bool
+	err       error
+}
+
+func (s *audioStatus) set(available bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.available, s.err = available, err
+}
+
+func (s *audioStatus) get() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.available, s.err
 }
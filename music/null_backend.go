@@ -0,0 +1,36 @@
+package music
+
+import "io"
+
+// nullBackend is a sink that discards all audio, used when no sound device is available
+type nullBackend struct{}
+
+func (nullBackend) Name() string {
+	return BackendNone
+}
+
+func (nullBackend) NewPlayer(io.Reader) Player {
+	return &nullPlayer{}
+}
+
+// nullPlayer keeps track of the play state without producing any sound
+type nullPlayer struct {
+	playing bool
+}
+
+func (p *nullPlayer) Play() {
+	p.playing = true
+}
+
+func (p *nullPlayer) Pause() {
+	p.playing = false
+}
+
+func (p *nullPlayer) IsPlaying() bool {
+	return p.playing
+}
+
+func (p *nullPlayer) Close() error {
+	p.playing = false
+	return nil
+}
@@ -0,0 +1,36 @@
+package music
+
+import (
+	"io"
+
+	"github.com/ebitengine/oto/v3" // Import Oto for audio playback
+)
+
+// otoBackend plays audio through the system sound device
+type otoBackend struct {
+	context *oto.Context
+}
+
+// newOtoBackend creates the audio context; it fails when no sound device is available
+func newOtoBackend() (AudioBackend, error) {
+	options := &oto.NewContextOptions{
+		SampleRate:   SampleRate,              // Sample rate for audio playback
+		ChannelCount: ChannelCount,            // Number of audio channels (stereo)
+		Format:       oto.FormatSignedInt16LE, // Sample format produced by the decoders
+		BufferSize:   8192,                    // Buffer size for audio playback
+	}
+	context, readyChan, err := oto.NewContext(options)
+	if err != nil {
+		return nil, err
+	}
+	<-readyChan // Wait until the context is ready
+	return &otoBackend{context: context}, nil
+}
+
+func (b *otoBackend) Name() string {
+	return BackendOto
+}
+
+func (b *otoBackend) NewPlayer(pcm io.Reader) Player {
+	return b.context.NewPlayer(pcm)
+}
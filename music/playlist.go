@@ -0,0 +1,63 @@
+package music
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"letsquiz/logger"
+)
+
+// playlist cycles through the background music tracks
+type playlist struct {
+	tracks []string
+	index  int
+}
+
+// newPlaylist expands directories in entries into the tracks they contain and optionally shuffles them
+func newPlaylist(entries []string, shuffle bool) *playlist {
+	var tracks []string
+	for _, entry := range entries {
+		info, err := os.Stat(entry)
+		if err != nil || !info.IsDir() {
+			tracks = append(tracks, entry) // Missing files are reported when they are played
+			continue
+		}
+		dirEntries, err := os.ReadDir(entry)
+		if err != nil {
+			logger.Warn("Failed to read playlist directory", "directory", entry, "error", err)
+			continue
+		}
+		var found []string
+		for _, dirEntry := range dirEntries {
+			if !dirEntry.IsDir() && IsAudioFile(dirEntry.Name()) {
+				found = append(found, filepath.Join(entry, dirEntry.Name()))
+			}
+		}
+		sort.Strings(found)
+		tracks = append(tracks, found...)
+	}
+	if shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+	}
+	return &playlist{tracks: tracks}
+}
+
+func (p *playlist) len() int {
+	return len(p.tracks)
+}
+
+func (p *playlist) current() string {
+	return p.tracks[p.index]
+}
+
+// next moves to the following track, wrapping around at the end
+func (p *playlist) next() {
+	p.index = (p.index + 1) % len(p.tracks)
+}
+
+// previous moves to the preceding track, wrapping around at the start
+func (p *playlist) previous() {
+	p.index = (p.index - 1 + len(p.tracks)) % len(p.tracks)
+}
@@ -0,0 +1,134 @@
+package music
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"letsquiz/logger"
+)
+
+// Volume is a percentage of the track's original loudness, changed in steps of volumeStep
+const (
+	maxVolume  = 100
+	volumeStep = 10
+)
+
+var volume atomic.Int32 // Current volume, applied to the PCM stream by volumeReader
+
+func init() {
+	volume.Store(maxVolume)
+}
+
+// Volume returns the current volume in percent
+func Volume() int {
+	return int(volume.Load())
+}
+
+// VolumeUp raises the volume by one step and remembers it for the next session
+func VolumeUp() {
+	setVolume(Volume() + volumeStep)
+}
+
+// VolumeDown lowers the volume by one step and remembers it for the next session
+func VolumeDown() {
+	setVolume(Volume() - volumeStep)
+}
+
+func setVolume(v int) {
+	v = clampVolume(v)
+	volume.Store(int32(v))
+	if err := saveState(state{Volume: &v}); err != nil {
+		logger.Warn("Failed to save music volume", "error", err)
+	}
+}
+
+func clampVolume(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > maxVolume {
+		return maxVolume
+	}
+	return v
+}
+
+// volumeReader scales the signed 16-bit little endian samples read from src by the current volume
+type volumeReader struct {
+	src io.Reader
+}
+
+func (r *volumeReader) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	if n%2 == 1 && n < len(p) {
+		// Complete the last sample so it is scaled as a whole
+		m, _ := io.ReadFull(r.src, p[n:n+1])
+		n += m
+	}
+	v := volume.Load()
+	if v == maxVolume {
+		return n, err
+	}
+	for i := 0; i+1 < n; i += 2 {
+		sample := int32(int16(binary.LittleEndian.Uint16(p[i:])))
+		binary.LittleEndian.PutUint16(p[i:], uint16(int16(sample*v/maxVolume)))
+	}
+	return n, err
+}
+
+// state holds the music settings kept between sessions
+type state struct {
+	Volume *int `json:"volume,omitempty"`
+}
+
+// statePath returns the file the music settings are kept in, next to the user's configuration
+func statePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "letsquiz", "music.json"), nil
+}
+
+// loadState restores the volume saved by a previous session, if any
+func loadState() {
+	path, err := statePath()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var s state
+	if err == nil {
+		err = json.Unmarshal(data, &s)
+	}
+	if err != nil {
+		logger.Warn("Failed to load music settings", "file", path, "error", err)
+		return
+	}
+	if s.Volume != nil {
+		volume.Store(int32(clampVolume(*s.Volume)))
+	}
+}
+
+func saveState(s state) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
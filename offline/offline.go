@@ -0,0 +1,107 @@
+// Package offline runs the backend in process against an SQLite database in the user's data directory,
+// so the application can be used without a server, e.g. to practise on a plane.
+//
+// The backend's routes and controllers handle the application's requests as they would on a server,
+// only the requests never leave the process. Sync pushes what was authored and played offline to a server.
+package offline
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/server/controllers"
+	"letsquiz/server/database"
+	"letsquiz/server/routes"
+	"letsquiz/server/storage"
+)
+
+// BackendURL is the backend_url the application uses in offline mode, served by Transport
+const BackendURL = "http://offline.letsquiz"
+
+// Path returns the offline database, offline_db or letsquiz/offline.db in the user's data directory
+func Path() (string, error) {
+	if config.AppConfig.OfflineDB != "" {
+		return config.AppConfig.OfflineDB, nil
+	}
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "offline.db"), nil
+}
+
+// Open connects to the SQLite database in file, creating it when needed. Audio clips and media are kept
+// in the audio and media directories next to it.
+func Open(file string) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := config.UseOfflineServer(file, dir); err != nil {
+		return fmt.Errorf("offline backend configuration: %w", err)
+	}
+	if err := database.Open(config.DbConfig.DbType, file); err != nil {
+		return err
+	}
+	if err := database.DB.AutoMigrate(&syncedRecord{}, &store{}); err != nil {
+		return fmt.Errorf("failed to auto-migrate sync state: %w", err)
+	}
+	return storage.Connect()
+}
+
+// Start opens the offline database in file and serves the application's requests from it
+func Start(file string) error {
+	if err := Open(file); err != nil {
+		return err
+	}
+
+	router := routes.NewRouter()
+	routes.RegisterRoutes(router)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", controllers.Healthz)
+	mux.HandleFunc("/readyz", controllers.Readyz)
+	mux.Handle("/", router)
+
+	config.AppConfig.BackendURL = BackendURL
+	http.DefaultClient.Transport = &Transport{Handler: mux, Next: http.DefaultClient.Transport}
+	logger.Info("Offline mode, serving the backend in process", "database", file)
+	return nil
+}
+
+// Transport hands the requests to BackendURL to Handler in process, and the others,
+// e.g. for soundtracks given as URLs, to Next
+type Transport struct {
+	Handler http.Handler
+	Next    http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme+"://"+req.URL.Host != BackendURL {
+		next := t.Next
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return next.RoundTrip(req)
+	}
+
+	served := req.Clone(req.Context())
+	served.RequestURI = req.URL.RequestURI()
+	served.RemoteAddr = "offline"
+	if served.Body == nil {
+		served.Body = http.NoBody
+	}
+	rec := httptest.NewRecorder()
+	t.Handler.ServeHTTP(rec, served)
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,477 @@
+package offline
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"letsquiz/logger"
+	"letsquiz/requestid"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"letsquiz/server/storage"
+)
+
+// idempotencyHeader is the header the server deduplicates creates by, see the outbox package
+const idempotencyHeader = "Idempotency-Key"
+
+// syncedRecord maps a record of the offline database to the record Sync created for it on a server
+type syncedRecord struct {
+	Server   string    `gorm:"primaryKey;type:varchar(255)"`
+	Table    string    `gorm:"column:record_table;primaryKey;type:varchar(30)"`
+	LocalID  int       `gorm:"primaryKey;autoIncrement:false"`
+	RemoteID int       `gorm:"not null"`
+	SyncedAt time.Time `gorm:"not null"`
+}
+
+// store identifies the offline database, so the idempotency keys of its records differ from another's
+type store struct {
+	ID  int    `gorm:"primaryKey"`
+	Key string `gorm:"type:varchar(64);not null"`
+}
+
+// IsSyncCommand reports whether the positional arguments request "sync"
+func IsSyncCommand(args []string) bool {
+	return len(args) == 1 && args[0] == "sync"
+}
+
+// Summary counts the records Sync created and updated on the server, by table
+type Summary struct {
+	Created map[string]int
+	Updated map[string]int
+}
+
+func (s Summary) String() string {
+	var tables []string
+	for table := range s.Created {
+		tables = append(tables, table)
+	}
+	for table := range s.Updated {
+		if _, ok := s.Created[table]; !ok {
+			tables = append(tables, table)
+		}
+	}
+	if len(tables) == 0 {
+		return "nothing to sync"
+	}
+	sort.Strings(tables)
+	var parts []string
+	for _, table := range tables {
+		parts = append(parts, fmt.Sprintf("%s: %d created, %d updated", table, s.Created[table], s.Updated[table]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// syncer pushes the offline database to one server
+type syncer struct {
+	server   string
+	storeKey string
+	mapped   map[string]map[int]syncedRecord // by table and local ID
+	summary  Summary
+}
+
+// Sync pushes the quizzes authored offline, with their questions, answers, audio clips and media, and the
+// attempts played offline to server, which Open must have connected the offline database for.
+// Records synced before are updated, or left alone for users, categories, media and attempts, which are
+// not edited offline; records deleted offline are not deleted on the server. Sync stops at the first
+// failure and can be run again, the records synced so far are not sent twice.
+func Sync(server string) (Summary, error) {
+	s := &syncer{
+		server:  strings.TrimSuffix(server, "/"),
+		mapped:  map[string]map[int]syncedRecord{},
+		summary: Summary{Created: map[string]int{}, Updated: map[string]int{}},
+	}
+
+	var identity store
+	if err := database.DB.FirstOrCreate(&identity, store{ID: 1}).Error; err != nil {
+		return s.summary, err
+	}
+	if identity.Key == "" {
+		identity.Key = requestid.New()
+		if err := database.DB.Save(&identity).Error; err != nil {
+			return s.summary, err
+		}
+	}
+	s.storeKey = identity.Key
+
+	var records []syncedRecord
+	if err := database.DB.Where("server = ?", s.server).Find(&records).Error; err != nil {
+		return s.summary, err
+	}
+	for _, record := range records {
+		if s.mapped[record.Table] == nil {
+			s.mapped[record.Table] = map[int]syncedRecord{}
+		}
+		s.mapped[record.Table][record.LocalID] = record
+	}
+
+	logger.Info("Syncing the offline database", "server", s.server, "synced", len(records))
+	for _, step := range []func() error{s.users, s.categories, s.media, s.quizzes, s.questions, s.answers, s.attempts, s.userAnswers} {
+		if err := step(); err != nil {
+			return s.summary, err
+		}
+	}
+	logger.Info("Offline database synced", "server", s.server, "summary", s.summary.String())
+	return s.summary, nil
+}
+
+// remote returns the server's ID for a local record, 0 when it was not synced
+func (s *syncer) remote(table string, localID int) int {
+	return s.mapped[table][localID].RemoteID
+}
+
+// remoteRef maps an optional reference such as a media ID, dropping it when the record was not synced
+func (s *syncer) remoteRef(table string, localID *int) *int {
+	if localID == nil {
+		return nil
+	}
+	if id := s.remote(table, *localID); id != 0 {
+		return &id
+	}
+	return nil
+}
+
+func (s *syncer) record(table string, localID, remoteID int) error {
+	record := syncedRecord{Server: s.server, Table: table, LocalID: localID, RemoteID: remoteID, SyncedAt: time.Now()}
+	if err := database.DB.Save(&record).Error; err != nil {
+		return err
+	}
+	if s.mapped[table] == nil {
+		s.mapped[table] = map[int]syncedRecord{}
+	}
+	s.mapped[table][localID] = record
+	return nil
+}
+
+// send makes a request to the server, failing on error statuses except those listed as accepted
+func (s *syncer) send(method, path, contentType string, body []byte, key string, accepted ...int) (int, []byte, error) {
+	req, err := http.NewRequest(method, s.server+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if key != "" {
+		req.Header.Set(idempotencyHeader, key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, status := range accepted {
+		if resp.StatusCode == status {
+			return resp.StatusCode, data, nil
+		}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode, data, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp.StatusCode, data, nil
+}
+
+// create posts a new record, keyed so that a create the server received before a failure is not repeated.
+// The key covers the body too, so a record fixed after the server rejected it is not answered with the
+// rejection stored for its earlier version.
+func (s *syncer) create(table string, localID int, path, contentType string, body []byte) (int, error) {
+	sum := sha256.Sum256(body)
+	key := fmt.Sprintf("sync-%s-%s-%d-%s", s.storeKey, table, localID, hex.EncodeToString(sum[:8]))
+	_, data, err := s.send(http.MethodPost, path, contentType, body, key)
+	if err != nil {
+		return 0, err
+	}
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil || created.ID == 0 {
+		return 0, fmt.Errorf("POST %s: no ID in the response: %s", path, strings.TrimSpace(string(data)))
+	}
+	s.summary.Created[table]++
+	return created.ID, nil
+}
+
+// save creates a record on the server, or updates the one synced before, and returns its ID there
+func (s *syncer) save(table string, localID int, path string, payload interface{}) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	if id := s.remote(table, localID); id != 0 {
+		if _, _, err := s.send(http.MethodPut, fmt.Sprintf("%s/%d", path, id), "application/json", body, ""); err != nil {
+			return 0, err
+		}
+		s.summary.Updated[table]++
+		return id, nil
+	}
+	id, err := s.create(table, localID, path, "application/json", body)
+	if err != nil {
+		return 0, err
+	}
+	return id, s.record(table, localID, id)
+}
+
+// lookup finds a record the server already has by name, e.g. a user or category, returning 0 when there is none
+func (s *syncer) lookup(path, name string) (int, error) {
+	status, data, err := s.send(http.MethodGet, path+url.PathEscape(name), "", nil, "", http.StatusNotFound)
+	if err != nil || status == http.StatusNotFound {
+		return 0, err
+	}
+	var found struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(data, &found); err != nil {
+		return 0, fmt.Errorf("GET %s%s: %w", path, name, err)
+	}
+	return found.ID, nil
+}
+
+// users maps the local users to the server's by user name, creating those it does not have
+func (s *syncer) users() error {
+	var users []models.User
+	if err := database.DB.Order("id").Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		if s.remote("users", user.ID) != 0 {
+			continue
+		}
+		id, err := s.lookup("/users/byname/", user.UserName)
+		if err == nil && id == 0 {
+			localID := user.ID
+			user.ID = 0
+			body, _ := json.Marshal(user)
+			id, err = s.create("users", localID, "/users", "application/json", body)
+			user.ID = localID
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.record("users", user.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// categories maps the local categories to the server's by name, creating those it does not have
+func (s *syncer) categories() error {
+	var categories []models.Category
+	if err := database.DB.Order("id").Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if s.remote("categories", category.ID) != 0 {
+			continue
+		}
+		id, err := s.lookup("/categories/byname/", category.Name)
+		if err == nil && id == 0 {
+			localID := category.ID
+			category.ID = 0
+			body, _ := json.Marshal(category)
+			id, err = s.create("categories", localID, "/categories", "application/json", body)
+			category.ID = localID
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.record("categories", category.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// media uploads the media attached to quizzes or questions, which the server stores once per content
+func (s *syncer) media() error {
+	var media []models.Media
+	if err := database.DB.Order("id").Find(&media).Error; err != nil {
+		return err
+	}
+	for _, m := range media {
+		if s.remote("media", m.ID) != 0 {
+			continue
+		}
+		body, contentType, err := mediaUpload(m)
+		if err != nil {
+			return fmt.Errorf("media %d: %w", m.ID, err)
+		}
+		id, err := s.create("media", m.ID, "/media", contentType, body)
+		if err != nil {
+			return err
+		}
+		if err := s.record("media", m.ID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mediaUpload reads a stored media file into the multipart form POST /media expects
+func mediaUpload(m models.Media) ([]byte, string, error) {
+	object, err := storage.Media.Open(context.Background(), m.StorageKey)
+	if err != nil {
+		return nil, "", err
+	}
+	defer object.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if len(m.SHA256) >= 48 {
+		// The same boundary on every sync keeps the body, and so its idempotency key, the same
+		if err := form.SetBoundary("letsquiz-" + m.SHA256[:48]); err != nil {
+			return nil, "", err
+		}
+	}
+	name := m.FileName
+	if name == "" {
+		name = fmt.Sprintf("media-%d", m.ID)
+	}
+	part, err := form.CreateFormFile("file", name)
+	if err == nil {
+		_, err = io.Copy(part, object)
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	return body.Bytes(), form.FormDataContentType(), err
+}
+
+func (s *syncer) quizzes() error {
+	var quizzes []models.Quiz
+	if err := database.DB.Order("id").Find(&quizzes).Error; err != nil {
+		return err
+	}
+	for _, quiz := range quizzes {
+		localID := quiz.ID
+		quiz.ID = 0
+		quiz.CategoryID = s.remote("categories", quiz.CategoryID)
+		quiz.CreatorID = s.remote("users", quiz.CreatorID)
+		quiz.MediaID = s.remoteRef("media", quiz.MediaID)
+		if _, err := s.save("quizzes", localID, "/quizzes", quiz); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// questions syncs the questions of the synced quizzes, whose audio clips are synced with the media
+func (s *syncer) questions() error {
+	var questions []models.Question
+	if err := database.DB.Order("quiz_id, position, id").Find(&questions).Error; err != nil {
+		return err
+	}
+	for _, question := range questions {
+		quizID := s.remote("quizzes", question.QuizID)
+		if quizID == 0 {
+			logger.Warn("Not syncing a question of a missing quiz", "questionID", question.ID, "quizID", question.QuizID)
+			continue
+		}
+		localID := question.ID
+		question.ID = 0
+		question.QuizID = quizID
+		question.MediaID = s.remoteRef("media", question.MediaID)
+		question.AudioMediaID = s.remoteRef("media", question.AudioMediaID)
+		if _, err := s.save("questions", localID, "/questions", question); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syncer) answers() error {
+	var answers []models.Answer
+	if err := database.DB.Order("question_id, position, id").Find(&answers).Error; err != nil {
+		return err
+	}
+	for _, answer := range answers {
+		questionID := s.remote("questions", answer.QuestionID)
+		if questionID == 0 {
+			logger.Warn("Not syncing an answer of a missing question", "answerID", answer.ID, "questionID", answer.QuestionID)
+			continue
+		}
+		localID := answer.ID
+		answer.ID = 0
+		answer.QuestionID = questionID
+		if _, err := s.save("answers", localID, "/answers", answer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attempts pushes the attempts played offline, which do not change once synced
+func (s *syncer) attempts() error {
+	var attempts []models.UserQuizAttempt
+	if err := database.DB.Order("id").Find(&attempts).Error; err != nil {
+		return err
+	}
+	for _, attempt := range attempts {
+		if s.remote("attempts", attempt.ID) != 0 {
+			continue
+		}
+		quizID := s.remote("quizzes", attempt.QuizID)
+		if quizID == 0 {
+			logger.Warn("Not syncing an attempt at a missing quiz", "attemptID", attempt.ID, "quizID", attempt.QuizID)
+			continue
+		}
+		localID := attempt.ID
+		attempt.ID = 0
+		attempt.QuizID = quizID
+		attempt.UserID = s.remote("users", attempt.UserID)
+		body, _ := json.Marshal(attempt)
+		id, err := s.create("attempts", localID, "/attempts", "application/json", body)
+		if err != nil {
+			return err
+		}
+		if err := s.record("attempts", localID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syncer) userAnswers() error {
+	var answers []models.UserAnswer
+	if err := database.DB.Order("id").Find(&answers).Error; err != nil {
+		return err
+	}
+	for _, answer := range answers {
+		if s.remote("user_answers", answer.ID) != 0 {
+			continue
+		}
+		attemptID, questionID := s.remote("attempts", answer.AttemptID), s.remote("questions", answer.QuestionID)
+		if attemptID == 0 || questionID == 0 {
+			logger.Warn("Not syncing an answer of a missing attempt or question", "userAnswerID", answer.ID)
+			continue
+		}
+		localID := answer.ID
+		answer.ID = 0
+		answer.AttemptID = attemptID
+		answer.QuestionID = questionID
+		answer.ChosenAnswerID = s.remote("answers", answer.ChosenAnswerID)
+		body, _ := json.Marshal(answer)
+		id, err := s.create("user_answers", localID, "/user-answers", "application/json", body)
+		if err != nil {
+			return err
+		}
+		if err := s.record("user_answers", localID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
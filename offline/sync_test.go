@@ -0,0 +1,197 @@
+package offline
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"letsquiz/server/database"
+	"letsquiz/server/database/databasetest"
+	"letsquiz/server/middleware"
+	"letsquiz/server/models"
+	"letsquiz/server/routes"
+	"letsquiz/server/storage"
+)
+
+// testServer is the server Sync pushes to in the tests: the backend's routes behind the idempotency
+// middleware, with a database and media of their own, which it swaps in while it handles a request.
+// Transport hands it the requests in process, on the goroutine calling Sync.
+type testServer struct {
+	db, offlineDB       *gorm.DB
+	media, offlineMedia storage.Storage
+	handler             http.Handler
+	lose                func(r *http.Request) bool // picks a request handled whose response is then lost
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	database.DB, storage.Media = s.db, s.media
+	defer func() { database.DB, storage.Media = s.offlineDB, s.offlineMedia }()
+	if s.lose != nil && s.lose(r) {
+		s.lose = nil
+		s.handler.ServeHTTP(httptest.NewRecorder(), r)
+		http.Error(w, "connection reset", http.StatusBadGateway)
+		return
+	}
+	s.handler.ServeHTTP(w, r)
+}
+
+// count returns the number of records of model on the server
+func (s *testServer) count(t *testing.T, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := s.db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// openSync opens an offline database and a server to sync it to, leaving the offline one as database.DB
+func openSync(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{}
+	databasetest.Open(t)
+	if err := storage.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	s.db, s.media = database.DB, storage.Media
+	if err := Open(filepath.Join(t.TempDir(), "offline.db")); err != nil {
+		t.Fatal(err)
+	}
+	s.offlineDB, s.offlineMedia = database.DB, storage.Media
+
+	router := routes.NewRouter()
+	routes.RegisterRoutes(router)
+	s.handler = middleware.Idempotency(router)
+	previous := http.DefaultClient.Transport
+	http.DefaultClient.Transport = &Transport{Handler: s}
+	t.Cleanup(func() { http.DefaultClient.Transport = previous })
+	return s
+}
+
+// uploadMedia stores data as media of the offline database, the way the application uploads it
+func uploadMedia(t *testing.T, name string, data []byte) int {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", name)
+	part.Write(data)
+	form.Close()
+	router := routes.NewRouter()
+	routes.RegisterRoutes(router)
+	req := httptest.NewRequest(http.MethodPost, "/media", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var media models.Media
+	if err := database.DB.Last(&media).Error; err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("upload %s = %d %s, %v", name, rec.Code, rec.Body.String(), err)
+	}
+	return media.ID
+}
+
+// create inserts records into the database, failing the test on error
+func create(t *testing.T, db *gorm.DB, records ...interface{}) {
+	t.Helper()
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	s := openSync(t)
+
+	// The server knows ana by another ID and has a quiz already, so no ID is the same on both sides
+	create(t, s.db, &models.User{UserName: "bo"}, &models.User{UserName: "ana"}, &models.Quiz{Title: "Rivers"})
+
+	ana := models.User{UserName: "ana"}
+	geography := models.Category{Name: "Geography"}
+	create(t, database.DB, &ana, &geography)
+	clip := uploadMedia(t, "intro.ogg", append([]byte("OggS\x00\x02"), bytes.Repeat([]byte("clip"), 100)...))
+	image := uploadMedia(t, "map.png", append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte("map"), 100)...))
+	quiz := models.Quiz{Title: "Capitals", CategoryID: geography.ID, CreatorID: ana.ID, MediaID: &image}
+	create(t, database.DB, &quiz)
+	first := models.Question{QuizID: quiz.ID, Text: "Capital of Portugal?", AudioMediaID: &clip}
+	second := models.Question{QuizID: quiz.ID, Position: 1, Text: "Capital of Spain?", AudioMediaID: &image} // not a clip
+	create(t, database.DB, &first, &second)
+	lisbon := models.Answer{QuestionID: first.ID, Text: "Lisbon", IsCorrect: true}
+	attempt := models.UserQuizAttempt{UserID: ana.ID, QuizID: quiz.ID, Score: 1}
+	create(t, database.DB, &lisbon, &attempt,
+		&models.Answer{QuestionID: first.ID, Text: "Porto", Position: 1},
+		&models.Answer{QuestionID: second.ID, Text: "Madrid", IsCorrect: true})
+	create(t, database.DB, &models.UserAnswer{AttemptID: attempt.ID, QuestionID: first.ID, ChosenAnswerID: lisbon.ID, IsCorrect: true})
+
+	// The response to creating the first question is lost, the sync stops and is resumed by the next one
+	s.lose = func(r *http.Request) bool { return r.Method == http.MethodPost && r.URL.Path == "/questions" }
+	if _, err := Sync(BackendURL); err == nil {
+		t.Fatal("Sync() with the response lost succeeded")
+	}
+
+	// The server rejects the image as the second question's clip, and keeps the rejection for its key;
+	// once fixed, the question is sent with another key
+	if _, err := Sync(BackendURL); err == nil || !strings.Contains(err.Error(), "expected an MP3, OGG Vorbis or WAV audio clip") {
+		t.Fatalf("Sync() = %v, want the second question rejected", err)
+	}
+	if err := database.DB.Model(&second).Update("audio_media_id", clip).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Model(&first).Update("text", "Capital of Portugal, again?").Error; err != nil {
+		t.Fatal(err)
+	}
+	summary, err := Sync(BackendURL)
+	if err != nil {
+		t.Fatalf("Sync() after the fix: %v", err)
+	}
+	if summary.Created["questions"] != 1 || summary.Updated["questions"] != 1 || summary.Created["user_answers"] != 1 {
+		t.Errorf("summary = %s, want the second question created, the first updated and the rest synced", summary)
+	}
+
+	// Syncing again creates nothing
+	if summary, err = Sync(BackendURL); err != nil || len(summary.Created) != 0 {
+		t.Errorf("Sync() again = %s, %v, want nothing created", summary, err)
+	}
+	for _, c := range []struct {
+		model interface{}
+		want  int64
+	}{
+		{&models.User{}, 2}, {&models.Category{}, 1}, {&models.Media{}, 2}, {&models.Quiz{}, 2},
+		{&models.Question{}, 2}, {&models.Answer{}, 3}, {&models.UserQuizAttempt{}, 1}, {&models.UserAnswer{}, 1},
+	} {
+		if got := s.count(t, c.model); got != c.want {
+			t.Errorf("server has %d %T, want %d", got, c.model, c.want)
+		}
+	}
+
+	// The records refer to each other by the server's IDs
+	var synced models.Quiz
+	var questions []models.Question
+	var media, picture models.Media
+	var answer models.UserAnswer
+	s.db.Last(&synced)
+	s.db.Order("position").Find(&questions)
+	s.db.First(&media, "content_type = ?", "audio/ogg")
+	s.db.First(&picture, "content_type = ?", "image/png")
+	s.db.First(&answer)
+	var chosen models.Answer
+	s.db.First(&chosen, answer.ChosenAnswerID)
+	if synced.Title != "Capitals" || synced.CreatorID != 2 || synced.MediaID == nil || *synced.MediaID != picture.ID {
+		t.Errorf("quiz = %+v, want Capitals by ana (2) showing the map", synced)
+	}
+	for n, q := range questions {
+		if q.QuizID != synced.ID || q.AudioMediaID == nil || *q.AudioMediaID != media.ID {
+			t.Errorf("question %d = %+v, want it in quiz %d playing media %d", n+1, q, synced.ID, media.ID)
+		}
+	}
+	if questions[0].Text != "Capital of Portugal, again?" {
+		t.Errorf("first question = %q, want the edit synced", questions[0].Text)
+	}
+	if chosen.Text != "Lisbon" || answer.QuestionID != questions[0].ID {
+		t.Errorf("user answer = %+v choosing %q, want Lisbon to the first question", answer, chosen.Text)
+	}
+}
@@ -0,0 +1,504 @@
+// Package outbox sends the client's changes to the backend, queueing them on disk while the backend
+// cannot be reached and replaying them in order once it is back.
+//
+// Every change carries an idempotency key, so a change the backend received before the connection dropped
+// is not applied twice when it is replayed. A queued POST is given a negative provisional ID standing in for
+// the ID the backend will assign, later changes may refer to it in their path or in the id fields of their
+// JSON body and are rewritten with the real ID when they are sent.
+//
+// A change the backend rejects for good, with a client error other than a timeout, a conflict with the
+// same change still being handled or a rate limit, is set aside as rejected so it does not hold up the
+// changes after it, and kept until the user discards it.
+package outbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/requestid"
+)
+
+// Header is the HTTP header carrying the idempotency key of a change
+const Header = "Idempotency-Key"
+
+// Replay backs off exponentially between attempts at the oldest change, up to maxBackoff
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// resolvedKeep is how long the ID assigned to a queued POST is remembered once it was sent, for drafts
+// and later changes still referring to its provisional ID
+const resolvedKeep = 7 * 24 * time.Hour
+
+// ErrUnresolved is returned for a change referring to the provisional ID of a change that was discarded
+// or rejected, the backend never assigning it a real one
+var ErrUnresolved = errors.New("it refers to a change that was discarded or rejected")
+
+// Operation is a change to send to the backend
+type Operation struct {
+	Key         string    `json:"key"` // idempotency key, see Header
+	Method      string    `json:"method"`
+	Path        string    `json:"path"` // relative to backend_url, which may change between sessions
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	Description string    `json:"description"`           // what the change does, for the pending changes screen
+	Provisional int       `json:"provisional,omitempty"` // ID handed out for what a queued POST creates
+	QueuedAt    time.Time `json:"queued_at"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	RejectedAt  time.Time `json:"rejected_at,omitempty"` // set when the backend rejected the change for good
+}
+
+// Response is the backend's answer to a change, or a stand-in when the change was queued
+type Response struct {
+	StatusCode    int
+	Body          []byte
+	Queued        bool // the backend was unreachable, the change is sent later
+	ProvisionalID int  // for a queued POST, the ID to refer to what it creates until it is sent
+}
+
+// state is what the outbox keeps on disk
+type state struct {
+	Operations      []Operation       `json:"operations"`
+	Rejected        []Operation       `json:"rejected,omitempty"` // changes the backend rejected, oldest first
+	Resolved        map[int]int       `json:"resolved"`           // provisional IDs of sent changes to the IDs the backend assigned
+	ResolvedAt      map[int]time.Time `json:"resolved_at"`        // when each provisional ID was resolved, see resolvedKeep
+	NextProvisional int               `json:"next_provisional"`
+}
+
+var (
+	mu    sync.Mutex
+	path  string
+	queue = newState()
+	wake  = make(chan struct{}, 1)
+)
+
+func newState() state {
+	return state{Resolved: map[int]int{}, ResolvedAt: map[int]time.Time{}, NextProvisional: -1}
+}
+
+// Path returns the file the outbox is kept in, outbox_file or letsquiz/outbox.json in the user's data directory
+func Path() (string, error) {
+	if config.AppConfig.OutboxFile != "" {
+		return config.AppConfig.OutboxFile, nil
+	}
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "outbox.json"), nil
+}
+
+// Open loads the changes a previous session could not send from file, which is also where the outbox is saved
+func Open(file string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	path = file
+	queue = newState()
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var loaded state
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	if loaded.Resolved == nil {
+		loaded.Resolved = map[int]int{}
+	}
+	if loaded.ResolvedAt == nil {
+		loaded.ResolvedAt = map[int]time.Time{}
+	}
+	for provisional := range loaded.Resolved {
+		if _, ok := loaded.ResolvedAt[provisional]; !ok {
+			loaded.ResolvedAt[provisional] = time.Now() // resolved before their time was kept
+		}
+	}
+	if loaded.NextProvisional >= 0 {
+		loaded.NextProvisional = -1
+	}
+	queue = loaded
+	prune()
+	if n := len(queue.Operations); n > 0 {
+		logger.Info("Changes waiting for the backend", "count", n, "file", file)
+	}
+	if n := len(queue.Rejected); n > 0 {
+		logger.Warn("Changes rejected by the backend", "count", n, "file", file)
+	}
+	return nil
+}
+
+// prune forgets the IDs assigned to POSTs sent longer than resolvedKeep ago. The caller holds mu.
+func prune() {
+	for provisional, at := range queue.ResolvedAt {
+		if time.Since(at) > resolvedKeep {
+			delete(queue.Resolved, provisional)
+			delete(queue.ResolvedAt, provisional)
+		}
+	}
+}
+
+// save writes the outbox, replacing the file in one step. The caller holds mu.
+func save() {
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+	}
+	var tmp *os.File
+	if err == nil {
+		tmp, err = os.CreateTemp(filepath.Dir(path), ".outbox-*")
+	}
+	if err == nil {
+		defer os.Remove(tmp.Name())
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		logger.Error("Failed to save the outbox, queued changes may be lost on exit", "file", path, "error", err)
+	}
+}
+
+// Send sends a change to the backend, queueing it when the backend cannot be reached or earlier changes
+// are still queued, which keeps changes in order. HTTP error statuses are returned, not queued.
+// A change referring to a provisional ID waits behind the change it refers to while that is queued,
+// and fails with ErrUnresolved otherwise.
+func Send(method, urlPath, contentType string, body []byte, description string) (Response, error) {
+	op := Operation{
+		Key:         requestid.New(),
+		Method:      method,
+		Path:        urlPath,
+		ContentType: contentType,
+		Body:        body,
+		Description: description,
+	}
+
+	mu.Lock()
+	queued := len(queue.Operations) > 0
+	complete := true
+	if !queued {
+		op, complete = resolve(op)
+	}
+	mu.Unlock()
+	if !complete {
+		return Response{}, fmt.Errorf("%s: %w", description, ErrUnresolved)
+	}
+
+	if !queued {
+		resp, err := do(op)
+		if err == nil {
+			return resp, nil
+		}
+		logger.Warn("Backend unreachable, queueing change", "change", description, "error", err)
+		op.LastError = err.Error()
+		op.Attempts = 1
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	op.QueuedAt = time.Now()
+	op.NextAttempt = op.QueuedAt.Add(backoff(op.Attempts))
+	if method == http.MethodPost {
+		op.Provisional = queue.NextProvisional
+		queue.NextProvisional--
+	}
+	queue.Operations = append(queue.Operations, op)
+	save()
+	notify()
+	logger.Info("Change queued", "change", description, "key", op.Key, "provisionalID", op.Provisional, "pending", len(queue.Operations))
+	return Response{StatusCode: http.StatusAccepted, Queued: true, ProvisionalID: op.Provisional}, nil
+}
+
+// do sends op, failing only when the backend could not be reached
+func do(op Operation) (Response, error) {
+	req, err := http.NewRequest(op.Method, config.AppConfig.BackendURL+op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return Response{}, err
+	}
+	if op.ContentType != "" {
+		req.Header.Set("Content-Type", op.ContentType)
+	}
+	req.Header.Set(Header, op.Key)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{StatusCode: resp.StatusCode, Body: data}, nil
+}
+
+// resolve replaces the provisional IDs op refers to with the IDs the backend assigned, reporting false
+// when it still refers to a change that was not sent. The caller holds mu.
+func resolve(op Operation) (Operation, bool) {
+	complete := true
+	segments := strings.Split(op.Path, "/")
+	for i, segment := range segments {
+		if id, err := strconv.Atoi(segment); err == nil && id < 0 {
+			if real, ok := queue.Resolved[id]; ok {
+				segments[i] = strconv.Itoa(real)
+			} else {
+				complete = false
+			}
+		}
+	}
+	op.Path = strings.Join(segments, "/")
+
+	if op.ContentType != "application/json" || len(op.Body) == 0 {
+		return op, complete
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(op.Body, &fields) != nil {
+		return op, complete
+	}
+	changed := false
+	for name, value := range fields {
+		id, ok := value.(float64)
+		if !ok || id >= 0 || (name != "id" && !strings.HasSuffix(name, "_id")) {
+			continue
+		}
+		if real, ok := queue.Resolved[int(id)]; ok {
+			fields[name] = real
+			changed = true
+		} else {
+			complete = false
+		}
+	}
+	if changed {
+		if body, err := json.Marshal(fields); err == nil {
+			op.Body = body
+		}
+	}
+	return op, complete
+}
+
+// backoff is the wait before the next attempt after the given number of failed ones
+func backoff(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+	wait := minBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// permanent reports whether the backend rejected a change for good. Timeouts and rate limits pass,
+// and so does a conflict, which the backend answers while a change with the same key is still handled.
+func permanent(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
+}
+
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run replays the queued changes in order until done is closed. The oldest change is retried with
+// backoff while it fails, the changes after it wait for it.
+func Run(done <-chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-wake:
+		case <-timer.C:
+		}
+		wait := replay()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// replay sends the queued changes that are due, returning how long to wait before trying again
+func replay() time.Duration {
+	for {
+		mu.Lock()
+		if len(queue.Operations) == 0 {
+			mu.Unlock()
+			return maxBackoff
+		}
+		head := queue.Operations[0]
+		if wait := time.Until(head.NextAttempt); wait > 0 {
+			mu.Unlock()
+			return wait
+		}
+		op, complete := resolve(head)
+		mu.Unlock()
+
+		var resp Response
+		err := ErrUnresolved
+		rejected := !complete
+		if complete {
+			resp, err = do(op)
+		}
+		if err == nil && resp.StatusCode >= 400 && !(op.Method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+			err = fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(resp.Body)))
+			rejected = permanent(resp.StatusCode)
+		}
+
+		mu.Lock()
+		if len(queue.Operations) == 0 || queue.Operations[0].Key != head.Key {
+			mu.Unlock() // discarded while it was sent
+			continue
+		}
+		if err != nil && rejected {
+			failed := queue.Operations[0]
+			failed.Attempts++
+			failed.LastError = err.Error()
+			failed.RejectedAt = time.Now()
+			queue.Rejected = append(queue.Rejected, failed)
+			queue.Operations = queue.Operations[1:]
+			save()
+			mu.Unlock()
+			logger.Error("Backend rejected queued change, it is set aside", "change", op.Description, "key", op.Key, "error", err)
+			continue
+		}
+		if err != nil {
+			failed := &queue.Operations[0]
+			failed.Attempts++
+			failed.LastError = err.Error()
+			failed.NextAttempt = time.Now().Add(backoff(failed.Attempts))
+			save()
+			mu.Unlock()
+			logger.Warn("Failed to replay queued change", "change", op.Description, "attempts", failed.Attempts, "error", err)
+			continue
+		}
+		if op.Provisional != 0 {
+			var created struct {
+				ID int `json:"id"`
+			}
+			if json.Unmarshal(resp.Body, &created) == nil && created.ID > 0 {
+				queue.Resolved[op.Provisional] = created.ID
+				queue.ResolvedAt[op.Provisional] = time.Now()
+			}
+		}
+		queue.Operations = queue.Operations[1:]
+		prune()
+		save()
+		pending := len(queue.Operations)
+		mu.Unlock()
+		logger.Info("Replayed queued change", "change", op.Description, "key", op.Key, "pending", pending)
+	}
+}
+
+// Pending returns the queued changes, the oldest first
+func Pending() []Operation {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Operation(nil), queue.Operations...)
+}
+
+// Rejected returns the changes the backend rejected, the oldest first
+func Rejected() []Operation {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Operation(nil), queue.Rejected...)
+}
+
+// Discard drops a queued or rejected change, reporting whether it was still there
+func Discard(key string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, op := range queue.Operations {
+		if op.Key == key {
+			queue.Operations = append(queue.Operations[:i], queue.Operations[i+1:]...)
+			save()
+			logger.Info("Queued change discarded", "change", op.Description, "key", key)
+			notify()
+			return true
+		}
+	}
+	for i, op := range queue.Rejected {
+		if op.Key == key {
+			queue.Rejected = append(queue.Rejected[:i], queue.Rejected[i+1:]...)
+			save()
+			logger.Info("Rejected change discarded", "change", op.Description, "key", key)
+			return true
+		}
+	}
+	return false
+}
+
+// RetryNow sends the oldest queued change without waiting for its backoff
+func RetryNow() {
+	mu.Lock()
+	if len(queue.Operations) > 0 {
+		queue.Operations[0].NextAttempt = time.Time{}
+	}
+	mu.Unlock()
+	notify()
+}
+
+// StatusHint describes the queued and rejected changes for status bars, empty when there are none
+func StatusHint() string {
+	mu.Lock()
+	defer mu.Unlock()
+	var hints []string
+	if n := len(queue.Operations); n > 0 {
+		hint := fmt.Sprintf("%d changes waiting for the backend", n)
+		if n == 1 {
+			hint = "1 change waiting for the backend"
+		}
+		if wait := time.Until(queue.Operations[0].NextAttempt); queue.Operations[0].Attempts > 0 && wait > 0 {
+			hint += fmt.Sprintf(", retrying in %ds", int(wait.Round(time.Second)/time.Second))
+		}
+		hints = append(hints, hint)
+	}
+	if n := len(queue.Rejected); n > 0 {
+		hint := fmt.Sprintf("%d changes rejected by the backend", n)
+		if n == 1 {
+			hint = "1 change rejected by the backend"
+		}
+		hints = append(hints, hint+", review them in the pending changes")
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	return strings.Join(hints, ". ") + "."
+}
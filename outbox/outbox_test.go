@@ -0,0 +1,355 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"letsquiz/config"
+)
+
+// received is a request the test backend was sent
+type received struct {
+	Method, Path, Key, Body string
+}
+
+// backend stands in for the server, answering each request with respond and recording it
+type backend struct {
+	mu       sync.Mutex
+	requests []received
+	respond  func(r *http.Request) (int, string)
+}
+
+func (b *backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	b.mu.Lock()
+	b.requests = append(b.requests, received{Method: r.Method, Path: r.URL.Path, Key: r.Header.Get(Header), Body: string(body)})
+	b.mu.Unlock()
+	status, reply := b.respond(r)
+	w.WriteHeader(status)
+	io.WriteString(w, reply)
+}
+
+func (b *backend) received() []received {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]received(nil), b.requests...)
+}
+
+// setup opens an empty outbox in a temporary directory and returns its file with a running test backend,
+// leaving backend_url pointing at a closed server so changes are queued until the test points it at the backend
+func setup(t *testing.T, respond func(r *http.Request) (int, string)) (string, *backend, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "outbox.json")
+	if err := Open(file); err != nil {
+		t.Fatal(err)
+	}
+	b := &backend{respond: respond}
+	server := httptest.NewServer(b)
+	t.Cleanup(server.Close)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	previous := config.AppConfig.BackendURL
+	config.AppConfig.BackendURL = down.URL
+	t.Cleanup(func() { config.AppConfig.BackendURL = previous })
+	return file, b, server.URL
+}
+
+func send(t *testing.T, method, path string, body interface{}) Response {
+	t.Helper()
+	var data []byte
+	contentType := ""
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+		contentType = "application/json"
+	}
+	resp, err := Send(method, path, contentType, data, method+" "+path)
+	if err != nil {
+		t.Fatalf("Send(%s %s): %v", method, path, err)
+	}
+	return resp
+}
+
+// replayNow replays the queue without waiting for the backoff
+func replayNow() {
+	mu.Lock()
+	for i := range queue.Operations {
+		queue.Operations[i].NextAttempt = time.Time{}
+	}
+	mu.Unlock()
+	replay()
+}
+
+func TestReplayInOrder(t *testing.T) {
+	_, b, url := setup(t, func(r *http.Request) (int, string) {
+		if r.Method == http.MethodPost && r.URL.Path == "/quizzes" {
+			return http.StatusCreated, `{"id":42}`
+		}
+		return http.StatusOK, `{}`
+	})
+
+	created := send(t, http.MethodPost, "/quizzes", map[string]interface{}{"title": "Capitals"})
+	if !created.Queued || created.ProvisionalID >= 0 {
+		t.Fatalf("Send while the backend is down = %+v, want queued with a provisional ID", created)
+	}
+	send(t, http.MethodPut, "/quizzes/"+strconv.Itoa(created.ProvisionalID), map[string]interface{}{"title": "Capitals of Europe"})
+	send(t, http.MethodPost, "/questions", map[string]interface{}{"quiz_id": created.ProvisionalID, "text": "Portugal?"})
+	keys := []string{}
+	for _, op := range Pending() {
+		keys = append(keys, op.Key)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Pending() has %d changes, want 3", len(keys))
+	}
+
+	config.AppConfig.BackendURL = url
+	replayNow()
+	if pending := Pending(); len(pending) != 0 {
+		t.Fatalf("Pending() after replay = %+v, want empty", pending)
+	}
+	got := b.received()
+	want := []received{
+		{Method: http.MethodPost, Path: "/quizzes", Key: keys[0], Body: `{"title":"Capitals"}`},
+		{Method: http.MethodPut, Path: "/quizzes/42", Key: keys[1], Body: `{"title":"Capitals of Europe"}`},
+		{Method: http.MethodPost, Path: "/questions", Key: keys[2], Body: `{"quiz_id":42,"text":"Portugal?"}`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("backend received %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Once the queue is empty changes are sent straight away, still resolving provisional IDs
+	resp := send(t, http.MethodPut, "/quizzes/"+strconv.Itoa(created.ProvisionalID), map[string]interface{}{"title": "Europe"})
+	if resp.Queued || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Send with the backend up = %+v, want sent", resp)
+	}
+	if last := b.received()[3]; last.Path != "/quizzes/42" {
+		t.Errorf("sent to %s, want /quizzes/42", last.Path)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{6, 32 * time.Second},
+		{7, time.Minute},
+		{50, time.Minute},
+	} {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+
+	_, b, url := setup(t, func(r *http.Request) (int, string) { return http.StatusServiceUnavailable, "down for maintenance" })
+	send(t, http.MethodPut, "/quizzes/1", map[string]interface{}{"title": "Capitals"})
+	config.AppConfig.BackendURL = url
+	replayNow()
+
+	pending := Pending()
+	if len(pending) != 1 {
+		t.Fatalf("Pending() = %+v, want the change still queued", pending)
+	}
+	if pending[0].Attempts != 2 || !strings.Contains(pending[0].LastError, "503") {
+		t.Errorf("after a server error attempts = %d, last error = %q, want 2 and the status", pending[0].Attempts, pending[0].LastError)
+	}
+	if wait := time.Until(pending[0].NextAttempt); wait <= 0 || wait > backoff(2) {
+		t.Errorf("next attempt in %v, want within %v", wait, backoff(2))
+	}
+
+	// The change is not sent again before its backoff is over
+	if wait := replay(); wait <= 0 || wait > backoff(2) {
+		t.Errorf("replay() = %v, want to wait up to %v", wait, backoff(2))
+	}
+	if n := len(b.received()); n != 1 {
+		t.Errorf("backend received %d requests, want 1", n)
+	}
+}
+
+func TestOpenRestoresQueue(t *testing.T) {
+	file, b, url := setup(t, func(r *http.Request) (int, string) { return http.StatusCreated, `{"id":7}` })
+	created := send(t, http.MethodPost, "/quizzes", map[string]interface{}{"title": "Capitals"})
+	send(t, http.MethodPost, "/questions", map[string]interface{}{"quiz_id": created.ProvisionalID, "text": "Portugal?"})
+	before := Pending()
+
+	// A new session loads what the previous one could not send
+	if err := Open(filepath.Join(t.TempDir(), "other.json")); err != nil {
+		t.Fatal(err)
+	}
+	if pending := Pending(); len(pending) != 0 {
+		t.Fatalf("Pending() for another file = %+v, want empty", pending)
+	}
+	if err := Open(file); err != nil {
+		t.Fatal(err)
+	}
+	after := Pending()
+	if len(after) != len(before) {
+		t.Fatalf("Pending() after Open = %+v, want %+v", after, before)
+	}
+	for i := range before {
+		if after[i].Key != before[i].Key || after[i].Path != before[i].Path || string(after[i].Body) != string(before[i].Body) {
+			t.Errorf("change %d after Open = %+v, want %+v", i, after[i], before[i])
+		}
+	}
+
+	// Provisional IDs handed out later do not collide with the restored ones
+	next := send(t, http.MethodPost, "/quizzes", map[string]interface{}{"title": "Rivers"})
+	if next.ProvisionalID >= created.ProvisionalID {
+		t.Errorf("provisional ID after Open = %d, want below %d", next.ProvisionalID, created.ProvisionalID)
+	}
+
+	config.AppConfig.BackendURL = url
+	replayNow()
+	if got := b.received(); len(got) != 3 || got[1].Body != `{"quiz_id":7,"text":"Portugal?"}` {
+		t.Fatalf("backend received %+v, want the restored changes with the ID resolved", got)
+	}
+
+	// The IDs assigned by the backend are kept too, and forgotten after resolvedKeep
+	if err := Open(file); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	id := queue.Resolved[created.ProvisionalID]
+	queue.ResolvedAt[created.ProvisionalID] = time.Now().Add(-resolvedKeep - time.Hour)
+	save()
+	mu.Unlock()
+	if id != 7 {
+		t.Errorf("resolved ID after Open = %d, want 7", id)
+	}
+	if err := Open(file); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	_, kept := queue.Resolved[created.ProvisionalID]
+	mu.Unlock()
+	if kept {
+		t.Errorf("provisional ID %d resolved more than %v ago is still kept", created.ProvisionalID, resolvedKeep)
+	}
+}
+
+func TestReplayRejected(t *testing.T) {
+	_, b, url := setup(t, func(r *http.Request) (int, string) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/quizzes":
+			return http.StatusBadRequest, "title is required"
+		case r.URL.Path == "/quizzes/9":
+			return http.StatusTooManyRequests, "slow down"
+		}
+		return http.StatusOK, `{}`
+	})
+	created := send(t, http.MethodPost, "/quizzes", map[string]interface{}{"title": ""})
+	send(t, http.MethodPost, "/questions", map[string]interface{}{"quiz_id": created.ProvisionalID, "text": "Portugal?"})
+	send(t, http.MethodDelete, "/answers/5", nil)
+	send(t, http.MethodPut, "/quizzes/9", map[string]interface{}{"title": "Rivers"})
+
+	config.AppConfig.BackendURL = url
+	replayNow()
+
+	// The rejected change and the one depending on it are set aside, the others are not held up
+	rejected := Rejected()
+	if len(rejected) != 2 {
+		t.Fatalf("Rejected() = %+v, want the rejected POST and the change referring to it", rejected)
+	}
+	if rejected[0].Path != "/quizzes" || !strings.Contains(rejected[0].LastError, "400") || rejected[0].RejectedAt.IsZero() {
+		t.Errorf("first rejected change = %+v, want the POST with its 400", rejected[0])
+	}
+	if rejected[1].Path != "/questions" || !strings.Contains(rejected[1].LastError, "discarded or rejected") {
+		t.Errorf("second rejected change = %+v, want the question referring to the rejected quiz", rejected[1])
+	}
+	paths := []string{}
+	for _, r := range b.received() {
+		paths = append(paths, r.Method+" "+r.Path)
+	}
+	if want := "POST /quizzes,DELETE /answers/5,PUT /quizzes/9"; strings.Join(paths, ",") != want {
+		t.Errorf("backend received %v, want %s", paths, want)
+	}
+
+	// A rate limit is retried
+	pending := Pending()
+	if len(pending) != 1 || pending[0].Path != "/quizzes/9" {
+		t.Fatalf("Pending() = %+v, want the rate limited change", pending)
+	}
+	if hint := StatusHint(); !strings.Contains(hint, "1 change waiting") || !strings.Contains(hint, "2 changes rejected") {
+		t.Errorf("StatusHint() = %q, want the waiting and rejected changes", hint)
+	}
+
+	if !Discard(rejected[0].Key) {
+		t.Errorf("Discard(%s) = false, want the rejected change discarded", rejected[0].Key)
+	}
+	if n := len(Rejected()); n != 1 {
+		t.Errorf("Rejected() has %d changes after Discard, want 1", n)
+	}
+}
+
+func TestSendUnresolved(t *testing.T) {
+	_, b, url := setup(t, func(r *http.Request) (int, string) {
+		if r.Method == http.MethodPost && r.URL.Path == "/quizzes" {
+			return http.StatusBadRequest, "title is required"
+		}
+		return http.StatusCreated, `{"id":1}`
+	})
+	created := send(t, http.MethodPost, "/quizzes", map[string]interface{}{"title": ""})
+
+	// While the quiz is queued, a change referring to it waits behind it
+	queued := send(t, http.MethodPut, "/quizzes/"+strconv.Itoa(created.ProvisionalID), map[string]interface{}{"title": "Capitals"})
+	if !queued.Queued {
+		t.Errorf("change referring to a queued one = %+v, want it queued", queued)
+	}
+
+	// Once the quiz is rejected, nothing refers to it any more and the change is not sent
+	config.AppConfig.BackendURL = url
+	replayNow()
+	body := []byte(`{"quiz_id":` + strconv.Itoa(created.ProvisionalID) + `,"text":"Portugal?"}`)
+	if resp, err := Send(http.MethodPost, "/questions", "application/json", body, "Create question"); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("Send() referring to a rejected change = %+v, %v, want ErrUnresolved", resp, err)
+	}
+	if _, err := Send(http.MethodDelete, "/quizzes/-42", "", nil, "Delete quiz"); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("Send() with an unknown provisional ID in its path = %v, want ErrUnresolved", err)
+	}
+	if pending := Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %+v, want nothing queued", pending)
+	}
+	for _, r := range b.received() {
+		if r.Path != "/quizzes" {
+			t.Errorf("backend received %s %s, want only the rejected POST", r.Method, r.Path)
+		}
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusBadRequest:          true,
+		http.StatusForbidden:           true,
+		http.StatusNotFound:            true,
+		http.StatusUnprocessableEntity: true,
+		http.StatusRequestTimeout:      false,
+		http.StatusConflict:            false,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+		http.StatusServiceUnavailable:  false,
+	} {
+		if got := permanent(status); got != want {
+			t.Errorf("permanent(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"letsquiz/logger"
+	"net/http"
+	"sync/atomic"
+)
+
+// Header is the HTTP header used to carry the request ID between the TUI and the server
+const Header = "X-Request-ID"
+
+var currentAction atomic.Value // ID of the user action currently being handled by the TUI
+
+// New generates a random request ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// BeginAction starts a new user action and returns its request ID.
+// Every backend call made until the next action is tagged with this ID.
+func BeginAction() string {
+	id := New()
+	currentAction.Store(id)
+	return id
+}
+
+// CurrentAction returns the request ID of the current user action, or an empty string
+func CurrentAction() string {
+	if id, ok := currentAction.Load().(string); ok {
+		return id
+	}
+	return ""
+}
+
+// Transport is an http.RoundTripper that sends the current action's request ID with each request
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	id := req.Header.Get(Header)
+	if id == "" {
+		id = CurrentAction()
+		if id == "" {
+			id = New()
+		}
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set(Header, id)
+	}
+
+	logger.Info("Sending backend request", "request_id", id, "method", req.Method, "url", req.URL.String())
+	return base.RoundTrip(req)
+}
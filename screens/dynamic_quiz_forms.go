@@ -13,16 +13,22 @@ type DynamicQuizForms struct {
 	model models.DynamicQuizModel
 }
 
-func InitialDynamicQuizForms(quizID, questionCount int, focused string, buttons []common.Button) DynamicQuizForms {
-	logger.Info("InitialDynamicQuizForms called", "quizID", quizID, "questionsCount", questionCount)
-	model := models.InitialDynamicQuizModel(quizID, questionCount, focused, buttons)
-	logger.Info("Initialized DynamicQuizForms", "quizID", quizID, "questionsCount", questionCount)
+func InitialDynamicQuizForms(quizID int, focused string, buttons []common.Button) DynamicQuizForms {
+	logger.Info("InitialDynamicQuizForms called", "quizID", quizID)
+	model := models.InitialDynamicQuizModel(quizID, focused, buttons)
+	logger.Info("Initialized DynamicQuizForms", "quizID", quizID, "questionsCount", model.TotalFormGroups)
 	return DynamicQuizForms{model: model}
 }
 
+// InitialDynamicQuizFormsFromDraft opens the editor on a draft left by a previous session
+func InitialDynamicQuizFormsFromDraft(draft models.Draft) DynamicQuizForms {
+	logger.Info("InitialDynamicQuizFormsFromDraft called", "quizID", draft.QuizID)
+	return DynamicQuizForms{model: models.InitialDynamicQuizModelFromDraft(draft)}
+}
+
 func (m DynamicQuizForms) Init() tea.Cmd {
 	logger.Info("DynamicQuizForms Init called", "CurrentFormGroup", m.model.CurrentFormGroup)
-	return m.model.QuestionForms[m.model.CurrentFormGroup].Init()
+	return tea.Batch(m.model.QuestionForms[m.model.CurrentFormGroup].Init(), tea.WindowSize(), models.DraftAutosave())
 }
 
 func (m DynamicQuizForms) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -51,10 +51,20 @@ func (m EditQuestionnaire) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				IsActive:        stringToBool(selectedRow[7]), // Is Active
 				QuestionCount:   stringToInt(selectedRow[8]),  // Question Count
 			}
+			// The soundtrack has no table column, take it from the fetched quiz
+			for _, quiz := range m.model.Quizzes {
+				if quiz.ID == Id {
+					quizMetadata.Soundtrack = quiz.Soundtrack
+				}
+			}
 		}
 
 		quizMetadataModel := InitialQuizMetadata(quizMetadata, m.model.Focused, m.model.Buttons)
 		return quizMetadataModel, quizMetadataModel.Init()
+	case "ImportQuiz":
+		logger.Info("Transitioning to ImportQuiz screen")
+		importQuiz := InitialImportQuiz()
+		return importQuiz, importQuiz.Init()
 	default:
 		newModel, cmd := models.UpdateEditQuestionnaire(m.model, msg)
 		if updatedModel, ok := newModel.(models.EditQuestionnaireModel); ok {
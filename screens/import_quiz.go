@@ -0,0 +1,47 @@
+package screens
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/logger"
+	"letsquiz/models"
+	"letsquiz/views"
+)
+
+type ImportQuiz struct {
+	model models.ImportQuizModel
+}
+
+func InitialImportQuiz() ImportQuiz {
+	logger.Info("InitialImportQuiz called")
+	return ImportQuiz{model: models.InitialImportQuizModel()}
+}
+
+func (m ImportQuiz) Init() tea.Cmd {
+	logger.Info("ImportQuiz Init called")
+	return tea.Batch(m.model.Form.Init(), tea.WindowSize())
+}
+
+func (m ImportQuiz) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	logger.Info("ImportQuiz Update called", "importing", m.model.Importing, "msgType", fmt.Sprintf("%T", msg))
+	switch msg.(type) {
+	case common.ImportQuizClosedMsg:
+		editQuestionnaire := InitialEditQuestionnaire()
+		return editQuestionnaire, tea.Batch(editQuestionnaire.Init(), tea.WindowSize())
+	}
+
+	newModel, cmd := models.UpdateImportQuiz(m.model, msg)
+	if updatedModel, ok := newModel.(models.ImportQuizModel); ok {
+		m.model = updatedModel
+	} else {
+		logger.Error("Failed to assert model to ImportQuizModel")
+		return newModel, cmd
+	}
+	return m, cmd
+}
+
+func (m ImportQuiz) View() string {
+	logger.Info("ImportQuiz View called", "importing", m.model.Importing)
+	return views.ViewImportQuiz(m.model)
+}
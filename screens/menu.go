@@ -34,6 +34,28 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.model.Model = newModel.(common.Model)
 		return m, cmd
 
+	case "QuizPlayer":
+		quizPlayer := InitialQuizPlayer()
+		return quizPlayer, quizPlayer.Init()
+
+	case "RestoreDraft":
+		drafts, err := models.ListDrafts()
+		if err != nil || len(drafts) == 0 {
+			logger.Error("No quiz draft to restore", "error", err)
+			m.model.CurrentScreen = "menu"
+			return m, nil
+		}
+		dynamicQuizScreen := InitialDynamicQuizFormsFromDraft(drafts[0])
+		return dynamicQuizScreen, dynamicQuizScreen.Init()
+
+	case "PendingOperations":
+		pendingOperations := InitialPendingOperations()
+		return pendingOperations, pendingOperations.Init()
+
+	case "Setup":
+		setup := InitialSetup()
+		return setup, setup.Init()
+
 	case "EditQuestionnaire":
 		editQuestionnaireModel := InitialEditQuestionnaire()
 		return editQuestionnaireModel, editQuestionnaireModel.Init()
@@ -0,0 +1,47 @@
+package screens
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/logger"
+	"letsquiz/models"
+	"letsquiz/views"
+)
+
+type PendingOperations struct {
+	model models.PendingOperationsModel
+}
+
+func InitialPendingOperations() PendingOperations {
+	logger.Info("InitialPendingOperations called")
+	return PendingOperations{model: models.InitialPendingOperationsModel()}
+}
+
+func (m PendingOperations) Init() tea.Cmd {
+	logger.Info("PendingOperations Init called")
+	return tea.Batch(models.RefreshPendingOperations(), tea.WindowSize())
+}
+
+func (m PendingOperations) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	logger.Info("PendingOperations Update called", "pending", len(m.model.Operations), "msgType", fmt.Sprintf("%T", msg))
+	switch msg.(type) {
+	case common.PendingOperationsClosedMsg:
+		menuModel := InitialMenu()
+		return menuModel, tea.Batch(menuModel.Init(), tea.WindowSize())
+	}
+
+	newModel, cmd := models.UpdatePendingOperations(m.model, msg)
+	if updatedModel, ok := newModel.(models.PendingOperationsModel); ok {
+		m.model = updatedModel
+	} else {
+		logger.Error("Failed to assert model to PendingOperationsModel")
+		return newModel, cmd
+	}
+	return m, cmd
+}
+
+func (m PendingOperations) View() string {
+	logger.Info("PendingOperations View called", "pending", len(m.model.Operations))
+	return views.ViewPendingOperations(m.model)
+}
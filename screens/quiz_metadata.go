@@ -35,8 +35,7 @@ func (m QuizMetadata) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg.(type) {
 	case common.QuizMetaDataFormCompletedMsg:
 		quizID := m.model.QuizData.ID
-		questionCount := m.model.QuizData.QuestionCount
-		dynamicQuizScreen := InitialDynamicQuizForms(quizID, questionCount, m.model.Focused, m.model.Buttons)
+		dynamicQuizScreen := InitialDynamicQuizForms(quizID, m.model.Focused, m.model.Buttons)
 		return dynamicQuizScreen, dynamicQuizScreen.Init()
 	}
 
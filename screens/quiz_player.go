@@ -0,0 +1,47 @@
+package screens
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/logger"
+	"letsquiz/models"
+	"letsquiz/views"
+)
+
+type QuizPlayer struct {
+	model models.QuizPlayerModel
+}
+
+func InitialQuizPlayer() QuizPlayer {
+	logger.Info("InitialQuizPlayer called")
+	return QuizPlayer{model: models.InitialQuizPlayerModel()}
+}
+
+func (m QuizPlayer) Init() tea.Cmd {
+	logger.Info("QuizPlayer Init called")
+	return tea.Batch(models.FetchPlayableQuizzesCmd(), tea.WindowSize())
+}
+
+func (m QuizPlayer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	logger.Info("QuizPlayer Update called", "phase", m.model.Phase, "msgType", fmt.Sprintf("%T", msg))
+	switch msg.(type) {
+	case common.QuizPlayerClosedMsg:
+		menuModel := InitialMenu()
+		return menuModel, tea.Batch(menuModel.Init(), tea.WindowSize())
+	}
+
+	newModel, cmd := models.UpdateQuizPlayer(m.model, msg)
+	if updatedModel, ok := newModel.(models.QuizPlayerModel); ok {
+		m.model = updatedModel
+	} else {
+		logger.Error("Failed to assert model to QuizPlayerModel")
+		return newModel, cmd // Return the new model and cmd instead of m and nil
+	}
+	return m, cmd
+}
+
+func (m QuizPlayer) View() string {
+	logger.Info("QuizPlayer View called", "phase", m.model.Phase)
+	return views.ViewQuizPlayer(m.model)
+}
@@ -0,0 +1,47 @@
+package screens
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"letsquiz/common"
+	"letsquiz/logger"
+	"letsquiz/models"
+	"letsquiz/views"
+)
+
+type Setup struct {
+	model models.SetupModel
+}
+
+func InitialSetup() Setup {
+	logger.Info("InitialSetup called")
+	return Setup{model: models.InitialSetupModel()}
+}
+
+func (m Setup) Init() tea.Cmd {
+	logger.Info("Setup Init called")
+	return tea.Batch(models.FetchPacksCmd(), tea.WindowSize())
+}
+
+func (m Setup) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	logger.Info("Setup Update called", "packs", len(m.model.Packs), "msgType", fmt.Sprintf("%T", msg))
+	switch msg.(type) {
+	case common.SetupClosedMsg:
+		menuModel := InitialMenu()
+		return menuModel, tea.Batch(menuModel.Init(), tea.WindowSize())
+	}
+
+	newModel, cmd := models.UpdateSetup(m.model, msg)
+	if updatedModel, ok := newModel.(models.SetupModel); ok {
+		m.model = updatedModel
+	} else {
+		logger.Error("Failed to assert model to SetupModel")
+		return newModel, cmd
+	}
+	return m, cmd
+}
+
+func (m Setup) View() string {
+	logger.Info("Setup View called", "packs", len(m.model.Packs))
+	return views.ViewSetup(m.model)
+}
@@ -15,7 +15,7 @@ import (
 // GetAnswers handles GET requests to fetch all answers
 func GetAnswers(w http.ResponseWriter, r *http.Request) {
 	var answers []models.Answer
-	if err := database.DB.Find(&answers).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&answers).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -36,7 +36,7 @@ func GetAnswerByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var answer models.Answer
-	if err := database.DB.First(&answer, id).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&answer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Answer not found", http.StatusNotFound)
 		} else {
@@ -62,7 +62,7 @@ func GetAnswersByQuestionID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var answers []models.Answer
-	if err := database.DB.Where("question_id = ?", questionID).Find(&answers).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Where("question_id = ?", questionID).Order("position, id").Find(&answers).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -75,37 +75,37 @@ func GetAnswersByQuestionID(w http.ResponseWriter, r *http.Request) {
 
 // CreateAnswer handles POST requests to create a new answer
 func CreateAnswer(w http.ResponseWriter, r *http.Request) {
-	logger.Info("CreateAnswer called")
+	logger.InfoContext(r.Context(), "CreateAnswer called")
 
 	// Log request details
-	logger.Info("CreateAnswer", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "CreateAnswer", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	var answer models.Answer
 	if err := json.NewDecoder(r.Body).Decode(&answer); err != nil {
-		logger.Error("CreateAnswer", "Error decoding request body:", err)
+		logger.ErrorContext(r.Context(), "CreateAnswer", "Error decoding request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Log decoded answer data
-	logger.Info("CreateAnswer", "Decoded answer data:", answer)
+	logger.DebugContext(r.Context(), "CreateAnswer", "Decoded answer data:", answer)
 
-	if err := database.DB.Create(&answer).Error; err != nil {
-		logger.Error("CreateAnswer", "Error creating answer in database:", err)
+	if err := database.DB.WithContext(r.Context()).Create(&answer).Error; err != nil {
+		logger.ErrorContext(r.Context(), "CreateAnswer", "Error creating answer in database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log created answer
-	logger.Info("CreateAnswer", "Answer created successfully:", answer)
+	logger.DebugContext(r.Context(), "CreateAnswer", "Answer created successfully:", answer)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(answer); err != nil {
-		logger.Error("CreateAnswer", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "CreateAnswer", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
-		logger.Info("CreateAnswer", "Response sent successfully")
+		logger.InfoContext(r.Context(), "CreateAnswer", "Response sent successfully")
 	}
 }
 
@@ -125,12 +125,33 @@ func UpdateAnswer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	answer.ID = id
-	if err := database.DB.Save(&answer).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&answer).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
+
+// DeleteAnswer handles DELETE requests to remove an answer
+func DeleteAnswer(w http.ResponseWriter, r *http.Request) {
+	idParam := strings.TrimPrefix(r.URL.Path, "/answers/")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		http.Error(w, "Invalid answer ID", http.StatusBadRequest)
+		return
+	}
+
+	result := database.DB.WithContext(r.Context()).Delete(&models.Answer{}, id)
+	if result.Error != nil {
+		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Answer not found", http.StatusNotFound)
+		return
+	}
+	logger.InfoContext(r.Context(), "Answer deleted", "answerID", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -16,7 +16,7 @@ import (
 // GetCategories handles GET requests to fetch all categories
 func GetCategories(w http.ResponseWriter, r *http.Request) {
 	var categories []models.Category
-	if err := database.DB.Find(&categories).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&categories).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -37,7 +37,7 @@ func GetCategoryByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var category models.Category
-	if err := database.DB.First(&category, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&category, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Category not found", http.StatusNotFound)
 		} else {
@@ -61,7 +61,7 @@ func GetCategoryIDByName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var category models.Category
-	if err := database.DB.Where("name = ?", name).First(&category).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Where("name = ?", name).First(&category).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Category not found", http.StatusNotFound)
 		} else {
@@ -82,37 +82,37 @@ func GetCategoryIDByName(w http.ResponseWriter, r *http.Request) {
 
 // CreateCategory handles POST requests to create a new category
 func CreateCategory(w http.ResponseWriter, r *http.Request) {
-	logger.Info("CreateCategory called")
+	logger.InfoContext(r.Context(), "CreateCategory called")
 
 	// Log request details
-	logger.Info("CreateCategory", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "CreateCategory", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	var category models.Category
 	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-		logger.Error("CreateCategory", "Error decoding request body:", err)
+		logger.ErrorContext(r.Context(), "CreateCategory", "Error decoding request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Log decoded category data
-	logger.Info("CreateCategory", "Decoded category data:", category)
+	logger.DebugContext(r.Context(), "CreateCategory", "Decoded category data:", category)
 
-	if err := database.DB.Create(&category).Error; err != nil {
-		logger.Error("CreateCategory", "Error creating category in database:", err)
+	if err := database.DB.WithContext(r.Context()).Create(&category).Error; err != nil {
+		logger.ErrorContext(r.Context(), "CreateCategory", "Error creating category in database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log created category
-	logger.Info("CreateCategory", "Category created successfully:", category)
+	logger.DebugContext(r.Context(), "CreateCategory", "Category created successfully:", category)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(category); err != nil {
-		logger.Error("CreateCategory", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "CreateCategory", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
-		logger.Info("CreateCategory", "Response sent successfully")
+		logger.InfoContext(r.Context(), "CreateCategory", "Response sent successfully")
 	}
 }
 
@@ -132,12 +132,10 @@ func UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	category.ID = id
-	if err := database.DB.Save(&category).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&category).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
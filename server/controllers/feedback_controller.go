@@ -15,7 +15,7 @@ import (
 // GetFeedbacks handles GET requests to fetch all feedbacks
 func GetFeedbacks(w http.ResponseWriter, r *http.Request) {
 	var feedbacks []models.Feedback
-	if err := database.DB.Find(&feedbacks).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&feedbacks).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -36,7 +36,7 @@ func GetFeedbackByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var feedback models.Feedback
-	if err := database.DB.First(&feedback, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&feedback, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Feedback not found", http.StatusNotFound)
 		} else {
@@ -53,37 +53,37 @@ func GetFeedbackByID(w http.ResponseWriter, r *http.Request) {
 
 // CreateFeedback handles POST requests to create a new feedback
 func CreateFeedback(w http.ResponseWriter, r *http.Request) {
-	logger.Info("CreateFeedback called")
+	logger.InfoContext(r.Context(), "CreateFeedback called")
 
 	// Log request details
-	logger.Info("CreateFeedback", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "CreateFeedback", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	var feedback models.Feedback
 	if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
-		logger.Error("CreateFeedback", "Error decoding request body:", err)
+		logger.ErrorContext(r.Context(), "CreateFeedback", "Error decoding request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Log decoded feedback data
-	logger.Info("CreateFeedback", "Decoded feedback data:", feedback)
+	logger.DebugContext(r.Context(), "CreateFeedback", "Decoded feedback data:", feedback)
 
-	if err := database.DB.Create(&feedback).Error; err != nil {
-		logger.Error("CreateFeedback", "Error creating feedback in database:", err)
+	if err := database.DB.WithContext(r.Context()).Create(&feedback).Error; err != nil {
+		logger.ErrorContext(r.Context(), "CreateFeedback", "Error creating feedback in database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log created feedback
-	logger.Info("CreateFeedback", "Feedback created successfully:", feedback)
+	logger.DebugContext(r.Context(), "CreateFeedback", "Feedback created successfully:", feedback)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(feedback); err != nil {
-		logger.Error("CreateFeedback", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "CreateFeedback", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
-		logger.Info("CreateFeedback", "Response sent successfully")
+		logger.InfoContext(r.Context(), "CreateFeedback", "Response sent successfully")
 	}
 }
 
@@ -103,12 +103,10 @@ func UpdateFeedback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	feedback.ID = id
-	if err := database.DB.Save(&feedback).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&feedback).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"net/http"
+	"time"
+)
+
+// Healthz handles GET requests for liveness checks
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readyz handles GET requests for readiness checks, verifying the database and migrations
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"database":   "ok",
+		"migrations": "ok",
+	}
+	ready := true
+
+	if database.DB == nil {
+		checks["database"] = "not connected"
+		ready = false
+	} else if sqlDB, err := database.DB.DB(); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := sqlDB.PingContext(ctx); err != nil {
+			logger.ErrorContext(r.Context(), "Readyz", "Database ping failed:", err)
+			checks["database"] = err.Error()
+			ready = false
+		}
+	}
+
+	if migratedAt, ok := database.MigratedAt(); !ok {
+		checks["migrations"] = "pending"
+		ready = false
+	} else {
+		checks["migrations_completed_at"] = migratedAt.Format(time.RFC3339)
+	}
+
+	status := "ready"
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		status = "not ready"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": status, "checks": checks})
+}
@@ -14,7 +14,7 @@ import (
 // GetLeaderboards handles GET requests to fetch all leaderboards
 func GetLeaderboards(w http.ResponseWriter, r *http.Request) {
 	var leaderboards []models.Leaderboard
-	if err := database.DB.Find(&leaderboards).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&leaderboards).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +35,7 @@ func GetLeaderboardByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var leaderboard models.Leaderboard
-	if err := database.DB.First(&leaderboard, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&leaderboard, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Leaderboard not found", http.StatusNotFound)
 		} else {
@@ -58,13 +58,11 @@ func CreateLeaderboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Create(&leaderboard).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Create(&leaderboard).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -84,12 +82,10 @@ func UpdateLeaderboard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	leaderboard.ID = id
-	if err := database.DB.Save(&leaderboard).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&leaderboard).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
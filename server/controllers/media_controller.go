@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"letsquiz/server/storage"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// audioFormat is an audio format the quiz player can play as a question's clip
+type audioFormat struct {
+	contentType string
+	match       func(head []byte) bool
+}
+
+// audioFormats are recognized by their magic bytes, the uploaded Content-Type is not trusted
+var audioFormats = []audioFormat{
+	{"audio/ogg", func(head []byte) bool { return bytes.HasPrefix(head, []byte("OggS")) }},
+	{"audio/wav", func(head []byte) bool {
+		return len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE"
+	}},
+	{"audio/mpeg", func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte("ID3")) || len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 // ID3 tag or MPEG frame sync
+	}},
+}
+
+// IsMediaUpload reports whether r uploads media, which is limited by max_media_bytes instead of max_body_bytes
+func IsMediaUpload(r *http.Request) bool {
+	return r.Method == http.MethodPost && r.URL.Path == "/media"
+}
+
+// mediaContentType detects the type of an upload from its first bytes, the declared type is not trusted
+func mediaContentType(head []byte) string {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	switch contentType {
+	case "application/ogg":
+		return "audio/ogg"
+	case "audio/wave":
+		return "audio/wav"
+	case "application/octet-stream":
+		// MP3 files without an ID3 tag are not recognized by http.DetectContentType
+		for _, format := range audioFormats {
+			if format.match(head) {
+				return format.contentType
+			}
+		}
+	}
+	return contentType
+}
+
+func mediaTypeAllowed(contentType string) bool {
+	for _, allowed := range config.DbConfig.MediaAllowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadMedia handles POST requests with a multipart/form-data "file" part to store media.
+// Files are deduplicated by their SHA-256 hash: uploading a file again returns the existing media.
+func UploadMedia(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart/form-data upload with a \"file\" part", http.StatusBadRequest)
+		return
+	}
+	var part io.Reader
+	var fileName string
+	for part == nil {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Missing \"file\" part", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		if p.FormName() == "file" {
+			part = p
+			if p.FileName() != "" {
+				fileName = filepath.Base(p.FileName())
+			}
+		}
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		writeBodyError(w, err)
+		return
+	}
+	head = head[:n]
+	if n == 0 {
+		http.Error(w, "Empty file", http.StatusBadRequest)
+		return
+	}
+	contentType := mediaContentType(head)
+	if !mediaTypeAllowed(contentType) {
+		http.Error(w, fmt.Sprintf("Unsupported media type %s, expected one of %s", contentType, strings.Join(config.DbConfig.MediaAllowedTypes, ", ")), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Spool the upload to a temporary file while hashing it, the hash decides whether it is stored at all
+	tmp, err := os.CreateTemp("", "letsquiz-media-*")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.MultiReader(bytes.NewReader(head), part))
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	db := database.DB.WithContext(r.Context())
+	var media models.Media
+	err = db.Where("sha256 = ?", sum).First(&media).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	status := http.StatusOK
+	if err == gorm.ErrRecordNotFound {
+		status = http.StatusCreated
+		media = models.Media{
+			SHA256:       sum,
+			ContentType:  contentType,
+			Size:         size,
+			FileName:     fileName,
+			StorageKey:   "sha256/" + sum[:2] + "/" + sum,
+			CreationDate: time.Now().UTC(),
+		}
+	}
+
+	// Store the file unless an earlier upload did, which also repairs media whose file went missing
+	exists, err := storage.Media.Exists(r.Context(), media.StorageKey)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to check stored media", "key", media.StorageKey, "error", err)
+		http.Error(w, "Media storage unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if !exists {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := storage.Media.Put(r.Context(), media.StorageKey, tmp, size, contentType); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to store media", "key", media.StorageKey, "error", err)
+			http.Error(w, "Media storage unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	if status == http.StatusCreated {
+		if err := db.Create(&media).Error; err != nil {
+			// A concurrent upload of the same file may have won the race for the unique hash
+			if db.Where("sha256 = ?", sum).First(&media).Error != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			status = http.StatusOK
+		}
+	}
+	logger.InfoContext(r.Context(), "Media uploaded", "mediaID", media.ID, "contentType", media.ContentType, "size", media.Size, "duplicate", status == http.StatusOK)
+
+	media.URL = fmt.Sprintf("/media/%d", media.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(media); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetMedia handles GET requests to download media, supporting range and conditional requests
+func GetMedia(w http.ResponseWriter, r *http.Request) {
+	idParam := strings.TrimPrefix(r.URL.Path, "/media/")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	var media models.Media
+	if err := database.DB.WithContext(r.Context()).First(&media, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Media not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	object, err := storage.Media.Open(r.Context(), media.StorageKey)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to open stored media", "mediaID", media.ID, "key", media.StorageKey, "error", err)
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "Media not available", http.StatusNotFound)
+		} else {
+			http.Error(w, "Media storage unavailable", http.StatusServiceUnavailable)
+		}
+		return
+	}
+	defer object.Close()
+
+	// The content of a media ID never changes, so it can be cached indefinitely
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("ETag", `"`+media.SHA256+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if media.FileName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": media.FileName}))
+	}
+	http.ServeContent(w, r, "", object.ModTime(), object)
+}
+
+// checkMediaRef verifies that the media a quiz or question refers to exists, writing the error response
+// when it does not. A nil reference is valid.
+func checkMediaRef(w http.ResponseWriter, r *http.Request, mediaID *int) bool {
+	if mediaID == nil {
+		return true
+	}
+	var count int64
+	if err := database.DB.WithContext(r.Context()).Model(&models.Media{}).Where("id = ?", *mediaID).Count(&count).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if count == 0 {
+		http.Error(w, fmt.Sprintf("Media %d not found", *mediaID), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkAudioRef verifies that the audio clip a question refers to is media the quiz player can play,
+// writing the error response when it is not. A nil reference is valid.
+func checkAudioRef(w http.ResponseWriter, r *http.Request, mediaID *int) bool {
+	if mediaID == nil {
+		return true
+	}
+	var media models.Media
+	if err := database.DB.WithContext(r.Context()).First(&media, *mediaID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, fmt.Sprintf("Media %d not found", *mediaID), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return false
+	}
+	for _, format := range audioFormats {
+		if format.contentType == media.ContentType {
+			return true
+		}
+	}
+	http.Error(w, fmt.Sprintf("Media %d is %s, expected an MP3, OGG Vorbis or WAV audio clip", *mediaID, media.ContentType), http.StatusBadRequest)
+	return false
+}
+
+// writeBodyError reports a failure to read an uploaded file, answering 413 when it exceeded the size limit
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
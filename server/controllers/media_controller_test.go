@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"letsquiz/config"
+	"letsquiz/server/database"
+	"letsquiz/server/database/databasetest"
+	"letsquiz/server/models"
+	"letsquiz/server/storage"
+)
+
+// oggClip starts like an Ogg file, which is how uploads are recognized as audio/ogg
+var oggClip = append([]byte("OggS\x00\x02"), bytes.Repeat([]byte("clip"), 300)...)
+
+// openMedia opens the test database with media stored in its directory
+func openMedia(t *testing.T) {
+	t.Helper()
+	databasetest.Open(t)
+	if err := storage.Connect(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// uploadMedia posts data to UploadMedia as the "file" part of a form, decoding the media on success
+func uploadMedia(t *testing.T, name string, data []byte) (int, models.Media) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", name)
+	part.Write(data)
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/media", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	UploadMedia(rec, req)
+
+	var media models.Media
+	if rec.Code == http.StatusOK || rec.Code == http.StatusCreated {
+		if err := json.Unmarshal(rec.Body.Bytes(), &media); err != nil {
+			t.Fatalf("upload response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, media
+}
+
+// storageKey is where the file of media is stored, which the API does not tell
+func storageKey(media models.Media) string {
+	return "sha256/" + media.SHA256[:2] + "/" + media.SHA256
+}
+
+// getMedia requests a media ID from GetMedia with the given headers
+func getMedia(id string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/media/"+id, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	GetMedia(rec, req)
+	return rec
+}
+
+func TestUploadMediaDeduplicates(t *testing.T) {
+	openMedia(t)
+	status, first := uploadMedia(t, "intro.ogg", oggClip)
+	if status != http.StatusCreated || first.ContentType != "audio/ogg" || first.Size != int64(len(oggClip)) || first.FileName != "intro.ogg" {
+		t.Fatalf("upload = %d %+v, want 201 with an audio/ogg media of %d bytes", status, first, len(oggClip))
+	}
+	if first.URL != "/media/"+strconv.Itoa(first.ID) {
+		t.Errorf("URL = %q, want the media's GET path", first.URL)
+	}
+
+	// The same file under another name is the same media, stored once
+	status, again := uploadMedia(t, "copy.ogg", oggClip)
+	if status != http.StatusOK || again.ID != first.ID {
+		t.Errorf("upload again = %d media %d, want 200 with media %d", status, again.ID, first.ID)
+	}
+	var count int64
+	if err := database.DB.Model(&models.Media{}).Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("%d media rows, %v, want 1", count, err)
+	}
+	stored, _ := filepath.Glob(filepath.Join(config.DbConfig.MediaDir, "sha256", "*", "*"))
+	if len(stored) != 1 || filepath.Base(stored[0]) != first.SHA256 {
+		t.Errorf("stored files %v, want one named by the hash %s", stored, first.SHA256)
+	}
+
+	// An upload of a file that went missing from storage stores it again
+	if err := storage.Media.Delete(context.Background(), storageKey(first)); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := uploadMedia(t, "intro.ogg", oggClip); status != http.StatusOK {
+		t.Errorf("upload after losing the file = %d, want 200", status)
+	}
+	if exists, _ := storage.Media.Exists(context.Background(), storageKey(first)); !exists {
+		t.Errorf("the missing file was not stored again")
+	}
+}
+
+func TestUploadMediaRejects(t *testing.T) {
+	openMedia(t)
+	if status, _ := uploadMedia(t, "notes.txt", []byte("plain text, not media")); status != http.StatusUnsupportedMediaType {
+		t.Errorf("upload of text = %d, want 415", status)
+	}
+	if status, _ := uploadMedia(t, "empty.ogg", nil); status != http.StatusBadRequest {
+		t.Errorf("upload of an empty file = %d, want 400", status)
+	}
+	rec := httptest.NewRecorder()
+	UploadMedia(rec, httptest.NewRequest(http.MethodPost, "/media", bytes.NewReader(oggClip)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("upload without a form = %d, want 400", rec.Code)
+	}
+}
+
+func TestGetMedia(t *testing.T) {
+	openMedia(t)
+	_, media := uploadMedia(t, "intro.ogg", oggClip)
+	id := strconv.Itoa(media.ID)
+
+	rec := getMedia(id, nil)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), oggClip) {
+		t.Fatalf("GET = %d with %d bytes, want 200 with the %d bytes uploaded", rec.Code, rec.Body.Len(), len(oggClip))
+	}
+	for key, want := range map[string]string{
+		"Content-Type":        "audio/ogg",
+		"ETag":                `"` + media.SHA256 + `"`,
+		"Content-Disposition": `inline; filename=intro.ogg`,
+		"Accept-Ranges":       "bytes",
+	} {
+		if got := rec.Header().Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if rec := getMedia(id, map[string]string{"Range": "bytes=0-3"}); rec.Code != http.StatusPartialContent || rec.Body.String() != "OggS" {
+		t.Errorf("GET bytes 0-3 = %d %q, want 206 \"OggS\"", rec.Code, rec.Body.String())
+	}
+	if rec := getMedia(id, map[string]string{"If-None-Match": `"` + media.SHA256 + `"`}); rec.Code != http.StatusNotModified {
+		t.Errorf("GET with the ETag = %d, want 304", rec.Code)
+	}
+
+	tests := []struct {
+		name, id string
+		want     int
+	}{
+		{"unknown", "999", http.StatusNotFound},
+		{"not a number", "intro", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := getMedia(tt.id, nil); rec.Code != tt.want {
+			t.Errorf("GET %s media = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	// Media whose file went missing is reported as not available
+	storage.Media.Delete(context.Background(), storageKey(media))
+	if rec := getMedia(id, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GET media without its file = %d, want 404", rec.Code)
+	}
+}
+
+func TestQuestionAudioClip(t *testing.T) {
+	openMedia(t)
+	_, clip := uploadMedia(t, "intro.ogg", oggClip)
+	_, image := uploadMedia(t, "dot.png", []byte("\x89PNG\r\n\x1a\nnot much of an image"))
+	missing := 999
+	tests := []struct {
+		name  string
+		audio *int
+		want  int
+	}{
+		{"audio", &clip.ID, http.StatusCreated},
+		{"no clip", nil, http.StatusCreated},
+		{"image", &image.ID, http.StatusBadRequest},
+		{"missing", &missing, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{"quiz_id": 1, "text": "Which melody is this?", "audio_media_id": tt.audio})
+			rec := httptest.NewRecorder()
+			CreateQuestion(rec, httptest.NewRequest(http.MethodPost, "/questions", bytes.NewReader(body)))
+			if rec.Code != tt.want {
+				t.Fatalf("create = %d %q, want %d", rec.Code, rec.Body.String(), tt.want)
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+			var created struct{ ID int }
+			json.Unmarshal(rec.Body.Bytes(), &created)
+			var question models.Question
+			if err := database.DB.First(&question, created.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if (question.AudioMediaID == nil) != (tt.audio == nil) || tt.audio != nil && *question.AudioMediaID != *tt.audio {
+				t.Errorf("audio_media_id = %v, want %v", question.AudioMediaID, tt.audio)
+			}
+		})
+	}
+}
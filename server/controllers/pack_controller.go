@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"letsquiz/server/packs"
+)
+
+// IsPackUpload reports whether r uploads a quiz pack, which is limited by max_media_bytes instead of max_body_bytes
+func IsPackUpload(r *http.Request) bool {
+	return r.Method == http.MethodPost && r.URL.Path == "/packs"
+}
+
+// GetPacks handles GET requests to list the sample packs and the installed packs with their versions
+func GetPacks(w http.ResponseWriter, r *http.Request) {
+	listings, err := packs.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(listings); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// InstallPack handles POST requests to install a pack, or update it in place when it is installed.
+// The body is a zip archive of the pack or its pack.json manifest, or empty with ?sample=<name> to install
+// a sample pack.
+func InstallPack(w http.ResponseWriter, r *http.Request) {
+	var p *packs.Pack
+	if sample := r.URL.Query().Get("sample"); sample != "" {
+		var err error
+		if p, err = packs.Sample(sample); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				http.Error(w, "Sample pack not found", http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+	} else {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		if len(data) == 0 {
+			http.Error(w, "Expected a zip archive or pack.json manifest, or ?sample=<name>", http.StatusBadRequest)
+			return
+		}
+		if p, err = packs.Load(data); err != nil {
+			http.Error(w, "Invalid pack: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	result, err := packs.Install(r.Context(), p)
+	if err != nil {
+		if errors.Is(err, packs.ErrMediaRejected) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if result.PreviousVersion == "" {
+		status = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// UninstallPack handles DELETE requests to remove an installed pack and its quizzes
+func UninstallPack(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/packs/")
+	if _, err := packs.Uninstall(r.Context(), name); err != nil {
+		if errors.Is(err, packs.ErrNotInstalled) {
+			http.Error(w, "Pack not installed", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
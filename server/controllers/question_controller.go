@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"letsquiz/logger"
 	"letsquiz/server/database"
 	"letsquiz/server/models"
 	"net/http"
@@ -14,7 +15,7 @@ import (
 // GetQuestions handles GET requests to fetch all questions
 func GetQuestions(w http.ResponseWriter, r *http.Request) {
 	var questions []models.Question
-	if err := database.DB.Find(&questions).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&questions).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +36,7 @@ func GetQuestionByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var question models.Question
-	if err := database.DB.First(&question, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&question, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Question not found", http.StatusNotFound)
 		} else {
@@ -61,7 +62,7 @@ func GetQuestionsByQuizID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var questions []models.Question
-	if err := database.DB.Where("quiz_id = ?", quizID).Find(&questions).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Where("quiz_id = ?", quizID).Order("position, id").Find(&questions).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -80,7 +81,16 @@ func CreateQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Create(&question).Error; err != nil {
+	if !checkMediaRef(w, r, question.MediaID) || !checkAudioRef(w, r, question.AudioMediaID) {
+		return
+	}
+	err := database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		return updateQuestionCount(tx, question.QuizID)
+	})
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -113,12 +123,74 @@ func UpdateQuestion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	question.ID = id
-	if err := database.DB.Save(&question).Error; err != nil {
+	if !checkMediaRef(w, r, question.MediaID) || !checkAudioRef(w, r, question.AudioMediaID) {
+		return
+	}
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		// Moving a question to another quiz changes the count of both
+		var previous models.Question
+		if err := tx.Select("quiz_id").Limit(1).Find(&previous, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&question).Error; err != nil {
+			return err
+		}
+		if previous.QuizID != 0 && previous.QuizID != question.QuizID {
+			if err := updateQuestionCount(tx, previous.QuizID); err != nil {
+				return err
+			}
+		}
+		return updateQuestionCount(tx, question.QuizID)
+	})
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
+
+// DeleteQuestion handles DELETE requests to remove a question along with its answers
+func DeleteQuestion(w http.ResponseWriter, r *http.Request) {
+	idParam := strings.TrimPrefix(r.URL.Path, "/questions/")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		http.Error(w, "Invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	var question models.Question
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&question, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id = ?", id).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&question).Error; err != nil {
+			return err
+		}
+		return updateQuestionCount(tx, question.QuizID)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Question not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	logger.InfoContext(r.Context(), "Question deleted", "questionID", id, "quizID", question.QuizID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateQuestionCount sets the question count of a quiz to the number of its questions,
+// the count is never taken from clients
+func updateQuestionCount(tx *gorm.DB, quizID int) error {
+	var count int64
+	if err := tx.Model(&models.Question{}).Where("quiz_id = ?", quizID).Count(&count).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Quiz{}).Where("id = ?", quizID).Update("question_count", count).Error
+}
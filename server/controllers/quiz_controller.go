@@ -13,92 +13,97 @@ import (
 
 // GetQuizzes handles GET requests to fetch all quizzes
 func GetQuizzes(w http.ResponseWriter, r *http.Request) {
-	logger.Info("GetQuizzes called")
+	logger.InfoContext(r.Context(), "GetQuizzes called")
 
 	// Log request details
-	logger.Info("GetQuizzes", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "GetQuizzes", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	var quizzes []models.Quiz
-	if err := database.DB.Find(&quizzes).Error; err != nil {
-		logger.Error("GetQuizzes", "Error fetching quizzes from database:", err)
+	if err := database.DB.WithContext(r.Context()).Find(&quizzes).Error; err != nil {
+		logger.ErrorContext(r.Context(), "GetQuizzes", "Error fetching quizzes from database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log fetched quizzes
-	logger.Info("GetQuizzes", "Fetched quizzes from database:", quizzes)
+	logger.DebugContext(r.Context(), "GetQuizzes", "Fetched quizzes from database:", quizzes)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(quizzes); err != nil {
-		logger.Error("GetQuizzes", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "GetQuizzes", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Info("GetQuizzes", "Response sent successfully")
+	logger.InfoContext(r.Context(), "GetQuizzes", "Response sent successfully")
 }
 
 // GetQuizByID handles GET requests to fetch a single quiz by ID
 func GetQuizByID(w http.ResponseWriter, r *http.Request) {
-	logger.Info("GetQuizByID called")
+	logger.InfoContext(r.Context(), "GetQuizByID called")
 
 	// Log request details
-	logger.Info("GetQuizByID", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "GetQuizByID", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	idParam := strings.TrimPrefix(r.URL.Path, "/quizzes/")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		logger.Error("GetQuizByID", "Invalid quiz ID:", idParam)
+		logger.ErrorContext(r.Context(), "GetQuizByID", "Invalid quiz ID:", idParam)
 		http.Error(w, "Invalid quiz ID", http.StatusBadRequest)
 		return
 	}
 
-	logger.Info("GetQuizByID", "Fetching quiz with ID:", id)
+	logger.InfoContext(r.Context(), "GetQuizByID", "Fetching quiz with ID:", id)
 
 	var quiz models.Quiz
-	if err := database.DB.First(&quiz, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&quiz, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			logger.Error("GetQuizByID", "Quiz not found:", id)
+			logger.ErrorContext(r.Context(), "GetQuizByID", "Quiz not found:", id)
 			http.Error(w, "Quiz not found", http.StatusNotFound)
 		} else {
-			logger.Error("GetQuizByID", "Error fetching quiz from database:", err)
+			logger.ErrorContext(r.Context(), "GetQuizByID", "Error fetching quiz from database:", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
 	// Log fetched quiz
-	logger.Info("GetQuizByID", "Fetched quiz from database:", quiz)
+	logger.DebugContext(r.Context(), "GetQuizByID", "Fetched quiz from database:", quiz)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(quiz); err != nil {
-		logger.Error("GetQuizByID", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "GetQuizByID", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	logger.Info("GetQuizByID", "Response sent successfully")
+	logger.InfoContext(r.Context(), "GetQuizByID", "Response sent successfully")
 }
 
 // CreateQuiz handles POST requests to create a new quiz
 func CreateQuiz(w http.ResponseWriter, r *http.Request) {
-	logger.Info("CreateQuiz called")
+	logger.InfoContext(r.Context(), "CreateQuiz called")
 
 	// Log request details
-	logger.Info("CreateQuiz", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "CreateQuiz", "Request Method:", r.Method, "Request URL:", r.URL.String())
 
 	var quiz models.Quiz
 	if err := json.NewDecoder(r.Body).Decode(&quiz); err != nil {
-		logger.Error("CreateQuiz", "Error decoding request body:", err)
+		logger.ErrorContext(r.Context(), "CreateQuiz", "Error decoding request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	quiz.QuestionCount = 0 // Counted as questions are added, see updateQuestionCount
+
 	// Log decoded quiz data
-	logger.Info("CreateQuiz", "Decoded quiz data:", quiz)
+	logger.DebugContext(r.Context(), "CreateQuiz", "Decoded quiz data:", quiz)
+	if !checkMediaRef(w, r, quiz.MediaID) {
+		return
+	}
 
-	if err := database.DB.Create(&quiz).Error; err != nil {
-		logger.Error("CreateQuiz", "Error creating quiz in database:", err)
+	if err := database.DB.WithContext(r.Context()).Create(&quiz).Error; err != nil {
+		logger.ErrorContext(r.Context(), "CreateQuiz", "Error creating quiz in database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -109,49 +114,53 @@ func CreateQuiz(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log created quiz
-	logger.Info("CreateQuiz", "Quiz created successfully:", quiz)
+	logger.DebugContext(r.Context(), "CreateQuiz", "Quiz created successfully:", quiz)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logger.Error("CreateQuiz", "Error encoding response:", err)
+		logger.ErrorContext(r.Context(), "CreateQuiz", "Error encoding response:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 // UpdateQuiz handles PUT requests to update an existing quiz
 func UpdateQuiz(w http.ResponseWriter, r *http.Request) {
-	logger.Info("UpdateQuiz called")
+	logger.InfoContext(r.Context(), "UpdateQuiz called")
 
 	// Log request details
-	logger.Info("UpdateQuiz", "Request Method:", r.Method, "Request URL:", r.URL.String())
+	logger.InfoContext(r.Context(), "UpdateQuiz", "Request Method:", r.Method, "Request URL:", r.URL.String())
 	idParam := strings.TrimPrefix(r.URL.Path, "/quizzes/")
 	id, err := strconv.Atoi(idParam)
 	if err != nil {
-		logger.Error("UpdateQuiz", "Invalid quiz ID:", idParam)
+		logger.ErrorContext(r.Context(), "UpdateQuiz", "Invalid quiz ID:", idParam)
 		http.Error(w, "Invalid quiz ID", http.StatusBadRequest)
 		return
 	}
 
-	logger.Info("UpdateQuiz", "Updating quiz with ID:", id)
+	logger.InfoContext(r.Context(), "UpdateQuiz", "Updating quiz with ID:", id)
 
 	var quiz models.Quiz
 	if err := json.NewDecoder(r.Body).Decode(&quiz); err != nil {
-		logger.Error("UpdateQuiz", "Error decoding request body:", err)
+		logger.ErrorContext(r.Context(), "UpdateQuiz", "Error decoding request body:", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	quiz.ID = id
-	logger.Info("UpdateQuiz", "Quiz data to update:", quiz)
+	logger.DebugContext(r.Context(), "UpdateQuiz", "Quiz data to update:", quiz)
+	if !checkMediaRef(w, r, quiz.MediaID) {
+		return
+	}
 
-	if err := database.DB.Save(&quiz).Error; err != nil {
-		logger.Error("UpdateQuiz", "Error updating quiz in database:", err)
+	// The question count is kept by the question endpoints, see updateQuestionCount
+	if err := database.DB.WithContext(r.Context()).Omit("QuestionCount").Save(&quiz).Error; err != nil {
+		logger.ErrorContext(r.Context(), "UpdateQuiz", "Error updating quiz in database:", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Log updated quiz
-	logger.Info("UpdateQuiz", "Quiz updated successfully:", quiz)
+	logger.DebugContext(r.Context(), "UpdateQuiz", "Quiz updated successfully:", quiz)
 	w.WriteHeader(http.StatusOK)
 }
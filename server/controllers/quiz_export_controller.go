@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"letsquiz/server/quizfile"
+
+	"gorm.io/gorm"
+)
+
+// ExportQuiz handles GET requests to export a quiz's questions as a spreadsheet, ?format=csv, the default,
+// or ?format=tsv, in the layout ImportQuizzes reads back
+func ExportQuiz(w http.ResponseWriter, r *http.Request) {
+	idParam := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/quizzes/"), "/export")
+	quizID, err := strconv.Atoi(idParam)
+	if err != nil {
+		http.Error(w, "Invalid quiz ID", http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	comma, contentType := ',', "text/csv; charset=utf-8"
+	switch format {
+	case "", quizfile.CSV:
+		format = quizfile.CSV
+	case quizfile.TSV:
+		comma, contentType = '\t', "text/tab-separated-values; charset=utf-8"
+	default:
+		http.Error(w, fmt.Sprintf("Unknown format %q, expected %s or %s", format, quizfile.CSV, quizfile.TSV), http.StatusBadRequest)
+		return
+	}
+
+	db := database.DB.WithContext(r.Context())
+	var quiz models.Quiz
+	if err := db.First(&quiz, quizID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			http.Error(w, "Quiz not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	var questions []models.Question
+	if err := db.Where("quiz_id = ?", quizID).Order("position, id").Find(&questions).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	questionIDs := make([]int, len(questions))
+	for i, question := range questions {
+		questionIDs[i] = question.ID
+	}
+	var answers []models.Answer
+	if len(questionIDs) > 0 {
+		if err := db.Where("question_id IN ?", questionIDs).Order("position, id").Find(&answers).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	answersByQuestion := map[int][]quizfile.Answer{}
+	for _, answer := range answers {
+		answersByQuestion[answer.QuestionID] = append(answersByQuestion[answer.QuestionID], quizfile.Answer{Text: answer.Text, Correct: answer.IsCorrect})
+	}
+
+	exported := make([]quizfile.Question, len(questions))
+	for i, question := range questions {
+		exported[i] = quizfile.Question{
+			Text:            question.Text,
+			Type:            question.Type,
+			HintExplanation: question.HintExplanation,
+			DifficultyLevel: question.DifficultyLevel,
+			Points:          question.Points,
+			Answers:         answersByQuestion[question.ID],
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"quiz-%d.%s\"", quiz.ID, format))
+	if err := quizfile.WriteSheet(w, comma, exported); err != nil {
+		// The header is sent, the client sees a truncated file
+		logger.ErrorContext(r.Context(), "Error writing quiz export", "quiz_id", quizID, "error", err)
+		return
+	}
+	logger.InfoContext(r.Context(), "Quiz exported", "quiz_id", quizID, "format", format, "questions", len(exported))
+}
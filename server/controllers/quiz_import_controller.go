@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"letsquiz/server/quizfile"
+
+	"gorm.io/gorm"
+)
+
+// Defaults for the quiz of imported questions that were not filed under a category
+const (
+	defaultImportTitle    = "Imported questions"
+	defaultImportCategory = "Imported"
+)
+
+// importedQuiz describes a quiz created by an import
+type importedQuiz struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Category  string `json:"category"`
+	Questions int    `json:"questions"`
+
+	questions []quizfile.Question
+}
+
+// importResult is the response to an import, the quizzes created and what could not be mapped
+type importResult struct {
+	Quizzes     []importedQuiz        `json:"quizzes"`
+	Imported    int                   `json:"imported"` // questions
+	Skipped     int                   `json:"skipped"`  // questions
+	Diagnostics []quizfile.Diagnostic `json:"diagnostics"`
+	Questions   []quizfile.Question   `json:"questions,omitempty"` // the questions read, for a dry run
+}
+
+// ImportQuizzes handles POST requests to import questions from a Moodle GIFT or Aiken file or a spreadsheet,
+// ?format=gift, aiken, csv or tsv. Questions filed under a GIFT category go into a new quiz titled after the
+// category's last name, in the category of its first name. The others go into a new quiz titled by ?title= in
+// the category named by ?category=. ?creator_id= sets the creator of the quizzes. Whatever could not be mapped
+// is reported with its line, and nothing is created when no question could be. With ?dry_run=true nothing is
+// created either, the response lists the questions that would be imported.
+func ImportQuizzes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	creatorID := 0
+	dryRun := false
+	if param := query.Get("dry_run"); param != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(param); err != nil {
+			http.Error(w, "Invalid dry_run, expected true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	if param := query.Get("creator_id"); param != "" {
+		var err error
+		if creatorID, err = strconv.Atoi(param); err != nil {
+			http.Error(w, "Invalid creator ID", http.StatusBadRequest)
+			return
+		}
+	}
+	questions, diagnostics, err := quizfile.Parse(query.Get("format"), r.Body)
+	if err != nil {
+		if errors.Is(err, quizfile.ErrUnknownFormat) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			writeBodyError(w, err)
+		}
+		return
+	}
+
+	result := importResult{Quizzes: []importedQuiz{}, Imported: len(questions), Diagnostics: diagnostics}
+	if result.Diagnostics == nil {
+		result.Diagnostics = []quizfile.Diagnostic{}
+	}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Skipped {
+			result.Skipped++
+		}
+	}
+
+	// One quiz per category, in the order the categories first appear
+	quizByCategory := map[string]int{}
+	for _, question := range questions {
+		i, ok := quizByCategory[question.Category]
+		if !ok {
+			category, title := quizfile.SplitCategory(question.Category)
+			if category == "" {
+				category, title = query.Get("category"), query.Get("title")
+				if category == "" {
+					category = defaultImportCategory
+				}
+				if title == "" {
+					title = defaultImportTitle
+				}
+			}
+			i = len(result.Quizzes)
+			quizByCategory[question.Category] = i
+			result.Quizzes = append(result.Quizzes, importedQuiz{Title: truncate(title, 100), Category: truncate(category, 70)})
+		}
+		result.Quizzes[i].questions = append(result.Quizzes[i].questions, question)
+		result.Quizzes[i].Questions++
+	}
+
+	status := http.StatusCreated
+	switch {
+	case dryRun:
+		status = http.StatusOK
+		result.Questions = questions
+	case len(questions) == 0:
+		status = http.StatusUnprocessableEntity
+	default:
+		err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			for i := range result.Quizzes {
+				if err := createImportedQuiz(tx, &result.Quizzes[i], creatorID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	logger.InfoContext(r.Context(), "Quizzes imported", "format", query.Get("format"), "dry_run", dryRun, "quizzes", len(result.Quizzes),
+		"imported", result.Imported, "skipped", result.Skipped, "diagnostics", len(result.Diagnostics))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// createImportedQuiz creates an imported quiz with its questions and answers, in its category created when missing
+func createImportedQuiz(tx *gorm.DB, imported *importedQuiz, creatorID int) error {
+	var category models.Category
+	if err := tx.Where("name = ?", imported.Category).Limit(1).Find(&category).Error; err != nil {
+		return err
+	}
+	if category.ID == 0 {
+		category.Name = imported.Category
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	quiz := models.Quiz{
+		Title:            imported.Title,
+		CategoryID:       category.ID,
+		CreatorID:        creatorID,
+		CreationDate:     now,
+		LastModifiedDate: now,
+		IsActive:         true,
+	}
+	if err := tx.Create(&quiz).Error; err != nil {
+		return err
+	}
+	imported.ID = quiz.ID
+
+	for position, q := range imported.questions {
+		question := models.Question{
+			QuizID:              quiz.ID,
+			Position:            position,
+			Text:                q.Text,
+			Type:                q.Type,
+			HintExplanation:     q.HintExplanation,
+			DifficultyLevel:     q.DifficultyLevel,
+			Points:              q.Points,
+			MultiChoiceAnsLimit: 1,
+			CreationDate:        now,
+			LastModifiedDate:    now,
+		}
+		if q.Type == quizfile.MultipleChoice {
+			question.MultiChoiceAnsLimit = 0
+			for _, answer := range q.Answers {
+				if answer.Correct {
+					question.MultiChoiceAnsLimit++
+				}
+			}
+		}
+		if err := tx.Create(&question).Error; err != nil {
+			return err
+		}
+		for answerPosition, a := range q.Answers {
+			answer := models.Answer{
+				QuestionID:       question.ID,
+				Text:             a.Text,
+				IsCorrect:        a.Correct,
+				Position:         answerPosition,
+				CreationDate:     now,
+				LastModifiedDate: now,
+			}
+			if err := tx.Create(&answer).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return updateQuestionCount(tx, quiz.ID)
+}
+
+// truncate shortens s to at most n characters to fit a column
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"letsquiz/server/database"
+	"letsquiz/server/database/databasetest"
+	"letsquiz/server/quizfile"
+)
+
+// importSheet posts a spreadsheet to ImportQuizzes, decoding the result
+func importSheet(t *testing.T, query, sheet string) (int, importResult) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ImportQuizzes(rec, httptest.NewRequest(http.MethodPost, "/quizzes/import?"+query, strings.NewReader(sheet)))
+	var result importResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("import response %d %q: %v", rec.Code, rec.Body.String(), err)
+	}
+	return rec.Code, result
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	databasetest.Open(t)
+	questions := []quizfile.Question{
+		{Text: "What is the capital of Portugal?", Type: quizfile.SingleChoice, DifficultyLevel: "easy", Points: 10, HintExplanation: "It is on the coast.",
+			Answers: []quizfile.Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}, {Text: "Faro"}}},
+		{Text: "Which are planets?", Type: quizfile.MultipleChoice, DifficultyLevel: "medium", Points: 20,
+			Answers: []quizfile.Answer{{Text: "Mars", Correct: true}, {Text: "Moon"}, {Text: "Venus", Correct: true}, {Text: "Sun, the star"}}},
+		{Text: "Which river flows through Lisbon?", Type: quizfile.SingleChoice, DifficultyLevel: "hard", Points: 1,
+			Answers: []quizfile.Answer{{Text: "Tagus", Correct: true}, {Text: "Douro"}}},
+	}
+
+	for _, format := range []string{quizfile.CSV, quizfile.TSV} {
+		t.Run(format, func(t *testing.T) {
+			comma := ','
+			if format == quizfile.TSV {
+				comma = '\t'
+			}
+			var sheet strings.Builder
+			if err := quizfile.WriteSheet(&sheet, comma, questions); err != nil {
+				t.Fatal(err)
+			}
+			status, result := importSheet(t, "format="+format+"&title=Capitals&category=Geography", sheet.String())
+			if status != http.StatusCreated || len(result.Quizzes) != 1 || result.Imported != len(questions) {
+				t.Fatalf("import = %d %+v, want one quiz of %d questions created", status, result, len(questions))
+			}
+
+			rec := httptest.NewRecorder()
+			ExportQuiz(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/quizzes/%d/export?format=%s", result.Quizzes[0].ID, format), nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("export = %d %q", rec.Code, rec.Body.String())
+			}
+			if exported := rec.Body.String(); exported != sheet.String() {
+				t.Errorf("exported sheet =\n%s\nwant the imported one\n%s", exported, sheet.String())
+			}
+
+			// The exported sheet imports the same questions again
+			status, again := importSheet(t, "format="+format+"&dry_run=true", rec.Body.String())
+			if status != http.StatusOK || len(again.Diagnostics) != 0 {
+				t.Fatalf("dry run of the export = %d %+v, want no diagnostics", status, again)
+			}
+			want := append([]quizfile.Question(nil), questions...)
+			for i := range want {
+				want[i].Line = i + 2
+			}
+			if !reflect.DeepEqual(again.Questions, want) {
+				t.Errorf("questions read from the export =\n%+v\nwant\n%+v", again.Questions, want)
+			}
+		})
+	}
+}
+
+func TestImportDryRunDiagnostics(t *testing.T) {
+	databasetest.Open(t)
+	sheet := "text,type,difficulty,points,hint,correct,answer 1,answer 2\n" +
+		"Q,,easy,2,,;,a,b\n" +
+		"Q,,,,,3,a,b\n" +
+		",,,,,1,a,b\n" +
+		"What is the capital of Portugal?,,,,,2,Porto,Lisbon\n"
+	status, result := importSheet(t, "format=csv&dry_run=true", sheet)
+	if status != http.StatusOK {
+		t.Fatalf("dry run = %d, want 200", status)
+	}
+	want := []struct {
+		line    int
+		message string
+	}{
+		{2, "no correct answer"},
+		{3, "correct 3 has no answer"},
+		{4, "the text is empty"},
+	}
+	if len(result.Diagnostics) != len(want) {
+		t.Fatalf("diagnostics = %+v, want %d", result.Diagnostics, len(want))
+	}
+	for i, d := range result.Diagnostics {
+		if d.Line != want[i].line || !d.Skipped || !strings.Contains(d.Message, want[i].message) {
+			t.Errorf("diagnostic %d = %+v, want line %d skipped with %q", i, d, want[i].line, want[i].message)
+		}
+	}
+	if result.Imported != 1 || result.Skipped != 3 || len(result.Questions) != 1 || result.Questions[0].Line != 5 {
+		t.Errorf("dry run = %+v, want the question on line 5 imported and 3 skipped", result)
+	}
+	if len(result.Quizzes) != 1 || result.Quizzes[0].ID != 0 {
+		t.Errorf("dry run quizzes = %+v, want one that is not created", result.Quizzes)
+	}
+
+	// Nothing is created by a dry run
+	var count int64
+	if err := database.DB.Table("quizzes").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d quizzes created by a dry run, want none", count)
+	}
+}
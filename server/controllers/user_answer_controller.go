@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"letsquiz/server/database"
+	"letsquiz/server/metrics"
 	"letsquiz/server/models"
 	"net/http"
 	"strconv"
@@ -14,7 +15,7 @@ import (
 // GetUserAnswers handles GET requests to fetch all user answers
 func GetUserAnswers(w http.ResponseWriter, r *http.Request) {
 	var answers []models.UserAnswer
-	if err := database.DB.Find(&answers).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&answers).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +36,7 @@ func GetUserAnswerByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var answer models.UserAnswer
-	if err := database.DB.First(&answer, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&answer, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "User answer not found", http.StatusNotFound)
 		} else {
@@ -58,14 +59,18 @@ func CreateUserAnswer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Create(&answer).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Create(&answer).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	metrics.UserAnswersRecorded.Inc()
 
-	database.DB.Commit()
-
+	// Return the created user answer's ID, e.g. for the offline sync to refer to it
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": answer.ID}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 // UpdateUserAnswer handles PUT requests to update an existing user answer
@@ -84,12 +89,10 @@ func UpdateUserAnswer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	answer.ID = id
-	if err := database.DB.Save(&answer).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&answer).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
@@ -14,7 +14,7 @@ import (
 // GetUsers handles GET requests to fetch all users
 func GetUsers(w http.ResponseWriter, r *http.Request) {
 	var users []models.User
-	if err := database.DB.Find(&users).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&users).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +35,7 @@ func GetUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user models.User
-	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&user, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "User not found", http.StatusNotFound)
 		} else {
@@ -59,7 +59,7 @@ func GetUserIDByName(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user models.User
-	if err := database.DB.Where("user_name = ?", name).First(&user).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Where("user_name = ?", name).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Category not found", http.StatusNotFound)
 		} else {
@@ -86,14 +86,17 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Create(&user).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
+	// Return the created user's ID, e.g. for the offline sync to refer to it
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": user.ID}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 // UpdateUser handles PUT requests to update an existing user
@@ -112,12 +115,10 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user.ID = id
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Save(&user).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	database.DB.Commit()
-
 	w.WriteHeader(http.StatusOK)
 }
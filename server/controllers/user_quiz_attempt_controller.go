@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"letsquiz/server/database"
+	"letsquiz/server/metrics"
 	"letsquiz/server/models"
 	"net/http"
 	"strconv"
@@ -14,7 +15,7 @@ import (
 // GetUserQuizAttempts handles GET requests to fetch all user quiz attempts
 func GetUserQuizAttempts(w http.ResponseWriter, r *http.Request) {
 	var attempts []models.UserQuizAttempt
-	if err := database.DB.Find(&attempts).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Find(&attempts).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -35,7 +36,7 @@ func GetUserQuizAttemptByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var attempt models.UserQuizAttempt
-	if err := database.DB.First(&attempt, uint(id)).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).First(&attempt, uint(id)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			http.Error(w, "Attempt not found", http.StatusNotFound)
 		} else {
@@ -58,14 +59,22 @@ func CreateUserQuizAttempt(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := database.DB.Create(&attempt).Error; err != nil {
+	if err := database.DB.WithContext(r.Context()).Create(&attempt).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	metrics.AttemptsStarted.Inc()
+	if !attempt.EndTime.IsZero() {
+		// Created already finished, e.g. by the offline sync
+		metrics.AttemptsSubmitted.Inc()
+	}
 
-	database.DB.Commit()
-
+	// Return the created attempt's ID, e.g. for the offline sync to refer to it
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"id": attempt.ID}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 // UpdateUserQuizAttempt handles PUT requests to update an existing user quiz attempt
@@ -84,12 +93,23 @@ func UpdateUserQuizAttempt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	attempt.ID = id
-	if err := database.DB.Save(&attempt).Error; err != nil {
+	submitted := false
+	err = database.DB.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		// An attempt is submitted once, when it gets its end time; resubmitting it is not counted again
+		var previous models.UserQuizAttempt
+		if err := tx.Select("end_time").Where("id = ?", id).Limit(1).Find(&previous).Error; err != nil {
+			return err
+		}
+		submitted = previous.EndTime.IsZero() && !attempt.EndTime.IsZero()
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	database.DB.Commit()
+	if submitted {
+		metrics.AttemptsSubmitted.Inc()
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
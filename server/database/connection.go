@@ -1,9 +1,13 @@
 package database
 
 import (
+	"fmt"
 	"letsquiz/server/models"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/glebarez/sqlite" // pure Go, the application is built without cgo
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
@@ -12,26 +16,66 @@ import (
 
 var DB *gorm.DB
 
+var (
+	migrationMu sync.RWMutex
+	migratedAt  time.Time
+)
+
+// MigratedAt reports when the schema migration completed, and whether it has
+func MigratedAt() (time.Time, bool) {
+	migrationMu.RLock()
+	defer migrationMu.RUnlock()
+	return migratedAt, !migratedAt.IsZero()
+}
+
+// ConnectDatabase opens the database and migrates the schema, exiting when that fails
 func ConnectDatabase(dbType, dsn string) {
+	log.Println("dbType:", dbType) // the DSN is not logged, it carries the database password
+	if err := Open(dbType, dsn); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Println("Database connection established and schema migrated")
+}
+
+// Open connects to the database and migrates the schema. Besides MySQL for the server, it supports
+// an SQLite file for the backend the application runs in process in offline mode.
+func Open(dbType, dsn string) error {
 	var err error
-	log.Println("dbType:", dbType)
-	log.Println("dsn:", dsn)
 	switch dbType {
 	case "mysql":
 		DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	case "sqlite":
+		DB, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	default:
-		log.Fatalf("Unsupported database type: %s", dbType)
+		return fmt.Errorf("unsupported database type: %s", dbType)
 	}
 
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// SQLite allows one writer at a time, queuing the connections avoids "database is locked" errors
+	if dbType == "sqlite" {
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	// Trace every query as a child span of the calling handler
+	if err := registerTracingCallbacks(DB, dbType); err != nil {
+		return fmt.Errorf("failed to register tracing callbacks: %w", err)
 	}
 
 	// Auto migrate the schema
-	err = DB.AutoMigrate(&models.User{}, &models.Category{}, &models.Quiz{}, &models.Question{}, &models.Answer{}, &models.UserQuizAttempt{}, &models.UserAnswer{}, &models.Leaderboard{}, &models.Feedback{})
+	err = DB.AutoMigrate(&models.User{}, &models.Category{}, &models.Quiz{}, &models.Question{}, &models.Answer{}, &models.UserQuizAttempt{}, &models.UserAnswer{}, &models.Leaderboard{}, &models.Feedback{}, &models.Media{}, &models.IdempotencyKey{}, &models.QuizPack{}, &models.QuizPackQuiz{})
 	if err != nil {
-		log.Fatalf("Failed to auto-migrate schema: %v", err)
+		return fmt.Errorf("failed to auto-migrate schema: %w", err)
 	}
 
-	log.Println("Database connection established and schema migrated")
+	migrationMu.Lock()
+	migratedAt = time.Now().UTC()
+	migrationMu.Unlock()
+	return nil
 }
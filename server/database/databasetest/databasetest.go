@@ -0,0 +1,24 @@
+// Package databasetest opens the server database for tests, the way the offline backend does
+package databasetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"letsquiz/config"
+	"letsquiz/server/database"
+)
+
+// Open configures the server with its defaults for an SQLite database in a temporary directory,
+// and opens it with the schema migrated as database.DB
+func Open(t testing.TB) {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "letsquiz.db")
+	if err := config.UseOfflineServer(file, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Open("sqlite", file); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,34 +4,80 @@ import (
 	"context"
 	"letsquiz/config"
 	"letsquiz/logger"
+	"letsquiz/server/controllers"
 	"letsquiz/server/database"
+	"letsquiz/server/metrics"
 	"letsquiz/server/middleware"
+	"letsquiz/server/packs"
 	"letsquiz/server/routes"
+	"letsquiz/server/storage"
+	"letsquiz/tracing"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func main() {
 	// start backend HTTP server
 	log.Println("Starting backend HTTP server")
 
-	// Load the database configuration from a JSON file
+	// Load the server configuration from dbconfig.json, LETSQUIZ_* environment variables and flags
 	log.Println("loading dbconfig.json")
-	if err := config.LoadConfig("dbconfig.json", true); err != nil {
-		logger.Error("Error loading dbconfig.json", "error", err) // Log error if configuration loading fails
-		log.Fatalf("could not load config: %v\n", err)            // Exit the program with an error message
+	args, err := config.Load(config.Server, os.Args[1:])
+	if err != nil {
+		log.Fatalf("could not load config: %v\n", err) // Exit the program with an error message
+	}
+
+	// "config print" shows the effective configuration and exits
+	if config.IsPrintCommand(args) {
+		if err := config.Print(os.Stdout, config.Server); err != nil {
+			log.Fatalf("could not print config: %v\n", err)
+		}
+		return
 	}
 
 	// Initialize the logger
 	logger.InitLogger(true)
 
+	// Initialize tracing before the database so query callbacks use the configured provider
+	logger.Info("initializing tracing", "exporter", config.DbConfig.TraceExporter)
+	shutdownTracing, err := tracing.Init(tracing.Options{
+		ServiceName:  "letsquiz-server",
+		Exporter:     config.DbConfig.TraceExporter,
+		File:         config.DbConfig.TraceFile,
+		OTLPEndpoint: config.DbConfig.OtlpEndpoint,
+	})
+	if err != nil {
+		logger.Error("Error initializing tracing", "error", err)
+		log.Fatalf("could not initialize tracing: %v\n", err)
+	}
+
 	// Connect to the database using the loaded configuration
 	logger.Info("connecting to database")
 	database.ConnectDatabase(config.DbConfig.DbType, config.DbConfig.DbDsn)
+	if err := metrics.RegisterDBStats(database.DB, config.DbConfig.DbType); err != nil {
+		logger.Error("Could not register database pool metrics", "error", err)
+	}
+
+	// Set up the storage for uploaded media
+	logger.Info("setting up media storage", "storage", config.DbConfig.MediaStorage)
+	if err := storage.Connect(); err != nil {
+		logger.Error("Error setting up media storage", "error", err)
+		log.Fatalf("could not set up media storage: %v\n", err)
+	}
+
+	// "packs list|install|uninstall" manages quiz packs and exits
+	if packs.IsCommand(args) {
+		if err := packs.RunCommand(context.Background(), os.Stdout, args); err != nil {
+			log.Fatalf("packs: %v\n", err)
+		}
+		return
+	}
 
 	// Set up the router for handling HTTP requests
 	logger.Info("setting up router")
@@ -41,30 +87,69 @@ func main() {
 
 	// Apply middleware to the router
 	logger.Info("Applying middleware: rate limiter")
+	mediaUploads := middleware.BodyLimit{Match: controllers.IsMediaUpload, MaxBytes: config.DbConfig.MaxMediaBytes}
+	packUploads := middleware.BodyLimit{Match: controllers.IsPackUpload, MaxBytes: config.DbConfig.MaxMediaBytes}
 	var handler http.Handler = router
-	handler = middleware.RateLimiter(middleware.Logger(handler)) // Apply rate limiting and logging middleware
-
-	// Apply Okta authentication middleware if enabled in configuration
-	if config.AppConfig.EnableOktaAuth {
-		logger.Info("Applying middleware: Okta authentication")
-		handler = middleware.OktaAuth(handler)
-	}
-
-	// Create an HTTP server with the specified address and handler
+	handler = middleware.Idempotency(handler)                                                          // Replay the response to a retried POST instead of handling it again
+	handler = middleware.Compression(config.DbConfig.Compression)(handler)                             // Compress responses when the client accepts it
+	handler = middleware.MaxBodySize(config.DbConfig.MaxBodyBytes, mediaUploads, packUploads)(handler) // Reject oversized request bodies, uploads may be larger
+	handler = middleware.RateLimiter(middleware.Logger(handler))                                       // Apply rate limiting and logging middleware
+
+	// Apply Okta authentication middleware, which checks enable_okta_auth per request so it can be hot-reloaded
+	logger.Info("Applying middleware: Okta authentication", "enabled", config.DbConfig.EnableOktaAuth)
+	handler = middleware.OktaAuth(handler)
+	handler = middleware.CORS(func() []string { return config.LiveDbConfig().CorsAllowedOrigins })(handler) // Answer CORS preflights before authentication
+	handler = middleware.RequestID(handler)                                                                 // Accept or generate X-Request-ID for log correlation
+	handler = otelhttp.NewHandler(handler, "http.server")                                                   // Start a server span, continuing any incoming trace context
+	handler = middleware.Metrics(handler)                                                                   // Record request metrics per route pattern
+
+	// Serve health, readiness and metrics endpoints outside of rate limiting and authentication
+	logger.Info("registering health, readiness and metrics endpoints")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", controllers.Healthz)
+	mux.HandleFunc("/readyz", controllers.Readyz)
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/", handler)
+
+	// Create an HTTP server with the configured address, limits and timeouts
 	srv := &http.Server{
-		Addr:    ":8086", // Server will listen on port 8086
-		Handler: handler,
+		Addr:              config.DbConfig.ListenAddr,
+		Handler:           mux,
+		ReadTimeout:       config.DbConfig.ReadTimeout,
+		ReadHeaderTimeout: config.DbConfig.ReadHeaderTimeout,
+		WriteTimeout:      config.DbConfig.WriteTimeout,
+		IdleTimeout:       config.DbConfig.IdleTimeout,
+		MaxHeaderBytes:    config.DbConfig.MaxHeaderBytes,
 	}
 
 	// Start the server in a separate goroutine to avoid blocking
 	go func() {
-		logger.Info("Starting server on port:8086")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if config.DbConfig.TLSEnabled() {
+			logger.Info("Starting HTTPS server", "addr", srv.Addr)
+			err = srv.ListenAndServeTLS(config.DbConfig.TLSCertFile, config.DbConfig.TLSKeyFile)
+		} else {
+			logger.Info("Starting HTTP server", "addr", srv.Addr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Error("Could not start server", "error", err) // Log error if server fails to start
 			log.Fatalf("could not start server: %v\n", err)      // Exit the program with an error message
 		}
 	}()
 
+	// Reload safe-to-change settings when dbconfig.json changes or on SIGHUP
+	config.WatchServer(logReload)
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go func() {
+		for range reloadChan {
+			logger.Info("Received SIGHUP, reloading configuration")
+			changed, err := config.ReloadServer()
+			logReload(changed, err)
+		}
+	}()
+
 	// Set up a channel to catch OS signals for graceful shutdown
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM) // Notify on interrupt or termination signals
@@ -83,6 +168,28 @@ func main() {
 		log.Fatalf("server forced to shutdown: %v", err)        // Exit the program with an error message
 	}
 
+	// Flush any spans that are still buffered
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Error shutting down tracing", "error", err)
+	}
+
 	// server stopped, exiting program
 	logger.Info("Server exiting")
 }
+
+// logReload applies the log level of a reloaded configuration and logs its outcome
+func logReload(changed []string, err error) {
+	if err != nil {
+		logger.Error("Configuration reload rejected, keeping the current settings", "changed", changed, "error", err)
+		return
+	}
+	if len(changed) == 0 {
+		logger.Info("Configuration reloaded, no settings changed")
+		return
+	}
+	if err := logger.SetLevel(config.LiveDbConfig().LogLevel); err != nil {
+		logger.Error("Could not apply reloaded log level", "error", err)
+	}
+	logger.RedactKeys(config.LiveDbConfig().LogRedactKeys...)
+	logger.Info("Configuration reloaded", "changed", changed)
+}
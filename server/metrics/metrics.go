@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "letsquiz"
+
+// UnmatchedRoute is the route label used for requests that no route pattern matched
+const UnmatchedRoute = "unmatched"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts handled requests by method, route pattern and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests handled, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latencies by method and route pattern
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latencies in seconds, by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPInFlight tracks the number of requests currently being served
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	})
+
+	// RateLimitRejections counts requests rejected by the rate limiter
+	RateLimitRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Total number of requests rejected by the rate limiter.",
+	})
+
+	// AttemptsStarted counts quiz attempts created
+	AttemptsStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quiz_attempts_started_total",
+		Help:      "Total number of quiz attempts started.",
+	})
+
+	// AttemptsSubmitted counts quiz attempts that were completed with an end time
+	AttemptsSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quiz_attempts_submitted_total",
+		Help:      "Total number of quiz attempts submitted.",
+	})
+
+	// UserAnswersRecorded counts answers recorded against quiz attempts
+	UserAnswersRecorded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_answers_recorded_total",
+		Help:      "Total number of user answers recorded.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		RateLimitRejections,
+		AttemptsStarted,
+		AttemptsSubmitted,
+		UserAnswersRecorded,
+	)
+}
+
+// RegisterDBStats exposes the connection pool statistics of the given database
+func RegisterDBStats(db *gorm.DB, dbName string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return registry.Register(collectors.NewDBStatsCollector(sqlDB, dbName))
+}
+
+// Handler returns the HTTP handler serving metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+type routeKey struct{}
+
+// routeHolder is filled in by the router once a route pattern has matched
+type routeHolder struct {
+	pattern string
+}
+
+// WithRouteHolder returns a copy of ctx that can carry the matched route pattern
+func WithRouteHolder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, &routeHolder{pattern: UnmatchedRoute})
+}
+
+// SetRoute records the route pattern that matched the request
+func SetRoute(r *http.Request, pattern string) {
+	if h, ok := r.Context().Value(routeKey{}).(*routeHolder); ok {
+		h.pattern = pattern
+	}
+}
+
+// Route returns the route pattern recorded for ctx, or UnmatchedRoute
+func Route(ctx context.Context) string {
+	if h, ok := ctx.Value(routeKey{}).(*routeHolder); ok {
+		return h.pattern
+	}
+	return UnmatchedRoute
+}
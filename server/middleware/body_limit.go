@@ -0,0 +1,31 @@
+package middleware
+
+import "net/http"
+
+// BodyLimit raises or lowers the body size limit for the requests it matches, e.g. file uploads
+type BodyLimit struct {
+	Match    func(r *http.Request) bool
+	MaxBytes int64
+}
+
+// MaxBodySize middleware to reject request bodies larger than maxBytes, or than the limit
+// of the first override matching the request
+func MaxBodySize(maxBytes int64, overrides ...BodyLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := maxBytes
+			for _, override := range overrides {
+				if override.Match(r) {
+					limit = override.MaxBytes
+					break
+				}
+			}
+			if r.ContentLength > limit {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"letsquiz/config"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoders are pooled, allocating one per response is costly, zstd's in particular.
+// A response is encoded by a single goroutine, so zstd does not start more per encoder.
+var (
+	zstdEncoders = sync.Pool{New: func() interface{} {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil
+		}
+		return encoder
+	}}
+	gzipEncoders = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+)
+
+// compressResponseWriter compresses the response body with the negotiated encoding
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	// Bodiless responses, already encoded bodies, byte ranges and media, which is compressed already, are passed through
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent ||
+		cw.Header().Get("Content-Encoding") != "" || isCompressedMedia(cw.Header().Get("Content-Type")) {
+		cw.encoding = ""
+	} else {
+		cw.Header().Set("Content-Encoding", cw.encoding)
+		cw.Header().Del("Content-Length")
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoding == "" {
+		return cw.ResponseWriter.Write(b)
+	}
+	if err := cw.initEncoder(); err != nil {
+		return 0, err
+	}
+	return cw.encoder.Write(b)
+}
+
+func (cw *compressResponseWriter) initEncoder() error {
+	if cw.encoder != nil {
+		return nil
+	}
+	switch cw.encoding {
+	case config.CompressionZstd:
+		encoder, ok := zstdEncoders.Get().(*zstd.Encoder)
+		if !ok {
+			return errors.New("cannot create a zstd encoder")
+		}
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	default:
+		encoder := gzipEncoders.Get().(*gzip.Writer)
+		encoder.Reset(cw.ResponseWriter)
+		cw.encoder = encoder
+	}
+	return nil
+}
+
+func (cw *compressResponseWriter) close() error {
+	if !cw.wroteHeader || cw.encoding == "" {
+		return nil
+	}
+	// An encoded response with an empty body still needs a valid, empty stream
+	if err := cw.initEncoder(); err != nil {
+		return err
+	}
+	err := cw.encoder.Close()
+	// The encoder is reset for its next response, not to keep this one's writer
+	switch encoder := cw.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		zstdEncoders.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		gzipEncoders.Put(encoder)
+	}
+	cw.encoder = nil
+	return err
+}
+
+// isCompressedMedia reports whether a content type is stored in a compressed format
+func isCompressedMedia(contentType string) bool {
+	for _, prefix := range []string{"audio/", "image/", "video/"} {
+		if strings.HasPrefix(contentType, prefix) && !strings.HasPrefix(contentType, "image/svg") {
+			return true
+		}
+	}
+	return false
+}
+
+// Compression middleware to compress responses using the first configured encoding the client accepts
+func Compression(encodings []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(encodings) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the first server-preferred encoding listed in the Accept-Encoding header,
+// or matched by "*" when it is not refused by name
+func negotiateEncoding(acceptEncoding string, preferred []string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+		q, ok := quality(fields[1:])
+		if !ok {
+			continue
+		}
+		// Honour explicit refusals such as "gzip;q=0" or "gzip;q=0.000"
+		accepted[name] = q > 0
+	}
+
+	for _, encoding := range preferred {
+		encoding = strings.ToLower(encoding)
+		if ok, listed := accepted[encoding]; ok || (!listed && accepted["*"]) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// quality returns the q value among the parameters of an Accept-Encoding entry, 1 when it has none.
+// It reports false for a q value that is not a number from 0 to 1, the entry is then ignored.
+func quality(params []string) (float64, bool) {
+	for _, param := range params {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || !(q >= 0 && q <= 1) {
+			return 0, false
+		}
+		return q, true
+	}
+	return 1, true
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	preferred := []string{"zstd", "gzip"}
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"gzip, deflate, br, zstd", "zstd"},
+		{"gzip", "gzip"},
+		{"GZIP;q=0.5", "gzip"},
+		{"zstd;q=0, gzip", "gzip"},
+		{"zstd;q=0.0, gzip", "gzip"},
+		{"zstd; q=0.000, gzip;q=0.1", "gzip"},
+		{"zstd;q=0., gzip;q=0", ""},
+		{"zstd;q=abc, gzip", "gzip"},
+		{"zstd;q=NaN, gzip;q=2", ""},
+		{"*", "zstd"},
+		{"zstd;q=0, *", "gzip"},
+		{"*;q=0", ""},
+		{"br", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding, preferred); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	body := strings.Repeat(`{"text":"What is the capital of Portugal?"}`, 100)
+	handler := Compression([]string{"zstd", "gzip"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/empty" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+
+	// Several responses in a row reuse the pooled encoders
+	for i := 0; i < 3; i++ {
+		for encoding, decode := range decoders {
+			for path, want := range map[string]string{"/quizzes": body, "/empty": ""} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Accept-Encoding", encoding)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if got := rec.Header().Get("Content-Encoding"); got != encoding {
+					t.Fatalf("GET %s Content-Encoding = %q, want %q", path, got, encoding)
+				}
+				r, err := decode(rec.Body)
+				if err != nil {
+					t.Fatalf("GET %s with %s: %v", path, encoding, err)
+				}
+				decoded, err := io.ReadAll(r)
+				if err != nil || string(decoded) != want {
+					t.Errorf("GET %s with %s decodes to %d bytes, %v, want %d", path, encoding, len(decoded), err, len(want))
+				}
+			}
+		}
+	}
+}
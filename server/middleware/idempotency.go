@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"net/http"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	idempotencyHeader         = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 128
+	idempotencyPurgeInterval  = time.Minute
+)
+
+// responseRecorder keeps a copy of the response written by the wrapped handler
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotency middleware to handle a POST sent with an Idempotency-Key header at most once.
+// The response is kept for idempotency_ttl, which may be hot-reloaded, and replayed when the key is sent again
+// with the same request; a different request gets 422 and a retry while the first is handled gets 409.
+// Server errors are not kept, so the request can be retried. Keys are scoped to the caller, so one client
+// cannot be answered with the response to another's request.
+func Idempotency(next http.Handler) http.Handler {
+	var (
+		purgeMu   sync.Mutex
+		lastPurge time.Time
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key too long", http.StatusBadRequest)
+			return
+		}
+
+		key = scopedIdempotencyKey(r, key)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+		ttl := config.LiveDbConfig().IdempotencyTTL
+
+		purgeMu.Lock()
+		if time.Since(lastPurge) > idempotencyPurgeInterval {
+			lastPurge = time.Now()
+			go purgeIdempotencyKeys(database.DB, ttl)
+		}
+		purgeMu.Unlock()
+
+		// Claim the key, or find the request that claimed it first
+		db := database.DB.WithContext(r.Context())
+		if err := db.Where("idempotency_key = ? AND created_at < ?", key, time.Now().Add(-ttl)).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			logger.ErrorContext(r.Context(), "Failed to expire idempotency key", "key", key, "error", err)
+		}
+		var existing models.IdempotencyKey
+		if found := db.Limit(1).Find(&existing, "idempotency_key = ?", key); found.Error == nil && found.RowsAffected > 0 {
+			replay(w, r, existing, hash)
+			return
+		}
+		record := models.IdempotencyKey{Key: key, RequestHash: hash, CreatedAt: time.Now()}
+		if err := db.Create(&record).Error; err != nil {
+			// Another request with the key may have claimed it in the meantime
+			if found := db.Limit(1).Find(&existing, "idempotency_key = ?", key); found.Error == nil && found.RowsAffected > 0 {
+				replay(w, r, existing, hash)
+				return
+			}
+			logger.ErrorContext(r.Context(), "Failed to store idempotency key", "key", key, "error", err)
+			http.Error(w, "Failed to store idempotency key", http.StatusInternalServerError)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// Let the client retry after a server error or a panic
+			if err := database.DB.Delete(&models.IdempotencyKey{}, "idempotency_key = ?", key).Error; err != nil {
+				logger.ErrorContext(r.Context(), "Failed to release idempotency key", "key", key, "error", err)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			return
+		}
+		err = db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", key).Updates(map[string]interface{}{
+			"status_code":   rec.status,
+			"content_type":  rec.Header().Get("Content-Type"),
+			"response_body": rec.body.Bytes(),
+		}).Error
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to store idempotent response", "key", key, "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// replay answers a request whose key was already used, with the response to it when it is the same request
+func replay(w http.ResponseWriter, r *http.Request, existing models.IdempotencyKey, hash string) {
+	switch {
+	case existing.RequestHash != hash:
+		logger.WarnContext(r.Context(), "Idempotency key reused for a different request", "key", existing.Key, "method", r.Method, "uri", r.RequestURI)
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+	case existing.StatusCode == 0:
+		http.Error(w, "A request with this Idempotency-Key is still being handled", http.StatusConflict)
+	default:
+		logger.InfoContext(r.Context(), "Replaying idempotent response", "key", existing.Key, "status", existing.StatusCode)
+		if existing.ContentType != "" {
+			w.Header().Set("Content-Type", existing.ContentType)
+		}
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(existing.StatusCode)
+		w.Write(existing.ResponseBody)
+	}
+}
+
+// scopedIdempotencyKey prefixes key with the caller, known by a hash of its Authorization header as the
+// bearer token is not decoded, or "anonymous" for a request without one
+func scopedIdempotencyKey(r *http.Request, key string) string {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "anonymous:" + key
+	}
+	sum := sha256.Sum256([]byte(auth))
+	return hex.EncodeToString(sum[:]) + ":" + key
+}
+
+// requestHash identifies a request by its method, path, query and body. The query is part of it
+// as it changes what some requests do, an import with dry_run=true creating nothing.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// purgeIdempotencyKeys removes the keys older than ttl from db, the database the request was handled with
+func purgeIdempotencyKeys(db *gorm.DB, ttl time.Duration) {
+	result := db.Where("created_at < ?", time.Now().Add(-ttl)).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		logger.Error("Failed to purge expired idempotency keys", "error", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		logger.Info("Purged expired idempotency keys", "count", result.RowsAffected)
+	}
+}
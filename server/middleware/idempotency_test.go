@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"letsquiz/config"
+	"letsquiz/server/database"
+	"letsquiz/server/database/databasetest"
+	"letsquiz/server/models"
+)
+
+// post sends a POST with the given idempotency key through handler
+func post(handler http.Handler, key, auth, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/quizzes", strings.NewReader(body))
+	req.Header.Set(idempotencyHeader, key)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// creating answers 201 with the number of the call, counting the calls
+func creating(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":%d}`, n)
+	})
+}
+
+func TestIdempotencyReplays(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(creating(&calls))
+
+	first := post(handler, "create-capitals", "", `{"title":"Capitals"}`)
+	second := post(handler, "create-capitals", "", `{"title":"Capitals"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"id":1}` {
+		t.Fatalf("first response = %d %q, want 201 {\"id\":1}", first.Code, first.Body.String())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != `{"id":1}` {
+		t.Errorf("retried response = %d %q, want the first one replayed", second.Code, second.Body.String())
+	}
+	if second.Header().Get(idempotencyReplayedHeader) != "true" || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("retried response headers = %v, want replayed JSON", second.Header())
+	}
+	if first.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Errorf("first response is marked replayed")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+
+	// A request without a key, or not a POST, is handled every time
+	post(handler, "", "", `{"title":"Capitals"}`)
+	req := httptest.NewRequest(http.MethodPut, "/quizzes/1", strings.NewReader(`{}`))
+	req.Header.Set(idempotencyHeader, "create-capitals")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("handler called %d times, want 3", n)
+	}
+}
+
+func TestIdempotencyDifferentRequest(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(creating(&calls))
+
+	post(handler, "create-quiz", "", `{"title":"Capitals"}`)
+	rec := post(handler, "create-quiz", "", `{"title":"Rivers"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body under the same key = %d, want 422", rec.Code)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyDifferentQuery(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(creating(&calls))
+
+	importing := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/quizzes/import?"+query, strings.NewReader("text,correct,answer 1,answer 2\n"))
+		req.Header.Set(idempotencyHeader, "import-capitals")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	importing("format=csv&dry_run=true")
+	if rec := importing("format=csv"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("import after a dry run under the same key = %d, want 422", rec.Code)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	databasetest.Open(t)
+	entered, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(handler, "slow", "", `{}`) }()
+	<-entered
+	if rec := post(handler, "slow", "", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("retry while the first request is handled = %d, want 409", rec.Code)
+	}
+	close(release)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Errorf("first request = %d, want 201", rec.Code)
+	}
+	if rec := post(handler, "slow", "", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Errorf("retry once handled = %d, want 201 replayed", rec.Code)
+	}
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "database unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":1}`)
+	}))
+
+	if rec := post(handler, "retry-me", "", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("first request = %d, want 500", rec.Code)
+	}
+	rec := post(handler, "retry-me", "", `{}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Errorf("retry after a server error = %d replayed %q, want 201 handled again", rec.Code, rec.Header().Get(idempotencyReplayedHeader))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler called %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyExpires(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(creating(&calls))
+
+	post(handler, "old", "", `{}`)
+	expired := time.Now().Add(-config.LiveDbConfig().IdempotencyTTL - time.Minute)
+	if err := database.DB.Model(&models.IdempotencyKey{}).Where("1 = 1").Update("created_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec := post(handler, "old", "", `{}`)
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"id":2}` || rec.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Errorf("request after the key expired = %d %q, want it handled again", rec.Code, rec.Body.String())
+	}
+}
+
+func TestIdempotencyScopedToCaller(t *testing.T) {
+	databasetest.Open(t)
+	var calls atomic.Int32
+	handler := Idempotency(creating(&calls))
+
+	alice := post(handler, "shared", "Bearer alice", `{}`)
+	bob := post(handler, "shared", "Bearer bob", `{}`)
+	anonymous := post(handler, "shared", "", `{}`)
+	for name, rec := range map[string]*httptest.ResponseRecorder{"alice": alice, "bob": bob, "anonymous": anonymous} {
+		if rec.Code != http.StatusCreated || rec.Header().Get(idempotencyReplayedHeader) != "" {
+			t.Errorf("%s's request = %d replayed %q, want it handled", name, rec.Code, rec.Header().Get(idempotencyReplayedHeader))
+		}
+	}
+	if rec := post(handler, "shared", "Bearer alice", `{}`); rec.Body.String() != alice.Body.String() {
+		t.Errorf("alice's retry = %q, want the first response %q", rec.Body.String(), alice.Body.String())
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("handler called %d times, want 3", n)
+	}
+}
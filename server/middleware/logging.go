@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"log"
+	"letsquiz/logger"
 	"net/http"
 	"time"
 )
@@ -10,7 +10,8 @@ import (
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("Method: %s, URI: %s, Time: %v", r.Method, r.RequestURI, time.Since(start))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.InfoContext(r.Context(), "Request handled", "method", r.Method, "uri", r.RequestURI, "status", rec.status, "duration", time.Since(start))
 	})
 }
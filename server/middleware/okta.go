@@ -6,12 +6,14 @@ import (
 )
 
 // OktaAuth middleware to check authorization using Okta
+// The enable_okta_auth setting is checked per request so it can be toggled by a config reload.
 func OktaAuth(next http.Handler) http.Handler {
-	if !config.AppConfig.EnableOktaAuth {
-		return next
-	}
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !config.LiveDbConfig().EnableOktaAuth {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			http.Error(w, "Authorization header required", http.StatusUnauthorized)
@@ -32,8 +34,8 @@ func OktaAuth(next http.Handler) http.Handler {
 func validateOktaToken(token string) bool {
 	// TODO Okta's API to validate the token here
 	// TODO Using the issuer and client ID from the config
-	// TODO issuer := config.AppConfig.OktaIssuer
-	// TODO clientID := config.AppConfig.OktaClientID
+	// TODO issuer := config.LiveDbConfig().OktaIssuer
+	// TODO clientID := config.LiveDbConfig().OktaClientID
 
 	return token != ""
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"letsquiz/config"
+	"letsquiz/server/metrics"
 	"net/http"
 	"sync"
 	"time"
@@ -12,7 +13,6 @@ func RateLimiter(next http.Handler) http.Handler {
 	var (
 		mu      sync.Mutex
 		visits  = make(map[string]int)
-		limit   = config.DbConfig.RateLimit // limit of requests per minute per IP
 		timeout = time.Minute
 	)
 
@@ -24,7 +24,9 @@ func RateLimiter(next http.Handler) http.Handler {
 		count := visits[ip]
 		mu.Unlock()
 
+		limit := config.LiveDbConfig().RateLimit // limit of requests per minute per IP, may be hot-reloaded
 		if count > limit {
+			metrics.RateLimitRejections.Inc()
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
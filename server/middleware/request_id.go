@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"letsquiz/logger"
+	"letsquiz/requestid"
+	"net/http"
+)
+
+// maxRequestIDLength bounds client supplied request IDs so they can't flood the logs
+const maxRequestIDLength = 128
+
+// RequestID middleware to accept or generate an X-Request-ID and store it in the request context
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
@@ -5,8 +5,9 @@ import "time"
 type Answer struct {
 	ID               int       `gorm:"primaryKey" json:"id"`
 	QuestionID       int       `gorm:"not null" json:"question_id"`
-	Text             string    `gorm:"type:varchar(350);not null" json:"text"`
+	Text             string    `gorm:"type:text;not null" json:"text"`
 	IsCorrect        bool      `gorm:"type:tinyint(1)" json:"is_correct"`
+	Position         int       `gorm:"not null;default:0" json:"position"` // order of the answer within its question
 	CreationDate     time.Time `gorm:"type:date" json:"creation_date"`
 	LastModifiedDate time.Time `gorm:"type:date" json:"last_modified_date"`
 }
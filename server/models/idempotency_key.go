@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyKey is the response to a POST sent with an Idempotency-Key header, replayed when the client
+// retries it, see middleware.Idempotency
+type IdempotencyKey struct {
+	Key          string    `gorm:"column:idempotency_key;primaryKey;type:varchar(200)" json:"key"` // the client's key prefixed with its caller
+	RequestHash  string    `gorm:"type:char(64);not null" json:"request_hash"`                     // of the method, path and body
+	StatusCode   int       `gorm:"not null;default:0" json:"status_code"`                          // 0 while the first request is handled
+	ContentType  string    `gorm:"type:varchar(100)" json:"content_type"`
+	ResponseBody []byte    `json:"-"`
+	CreatedAt    time.Time `gorm:"index;not null" json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Media is an uploaded file, stored once per content hash and served by GET /media/{id}
+type Media struct {
+	ID           int       `gorm:"primaryKey" json:"id"`
+	SHA256       string    `gorm:"type:char(64);uniqueIndex;not null" json:"sha256"`
+	ContentType  string    `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	FileName     string    `gorm:"type:varchar(255)" json:"file_name"` // name of the first upload
+	StorageKey   string    `gorm:"type:varchar(255);not null" json:"-"`
+	CreationDate time.Time `json:"creation_date"`
+	URL          string    `gorm:"-" json:"url"` // download path, filled in by the controllers
+}
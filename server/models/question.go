@@ -5,12 +5,15 @@ import "time"
 type Question struct {
 	ID                  int       `gorm:"primaryKey" json:"id"`
 	QuizID              int       `gorm:"not null" json:"quiz_id"`
-	Text                string    `gorm:"type:varchar(350);not null" json:"text"`
+	Position            int       `gorm:"not null;default:0" json:"position"` // order of the question within its quiz
+	Text                string    `gorm:"type:text;not null" json:"text"`
 	Type                string    `gorm:"type:varchar(30)" json:"type"`
-	HintExplanation     string    `gorm:"type:varchar(200)" json:"hint_explanation"`
+	HintExplanation     string    `gorm:"type:text" json:"hint_explanation"`
 	DifficultyLevel     string    `gorm:"type:varchar(10)" json:"difficulty_level"`
 	Points              float64   `gorm:"type:decimal(10,2)" json:"points"`
 	MultiChoiceAnsLimit int       `gorm:"type:int" json:"multi_choice_ans_limit"`
+	MediaID             *int      `gorm:"index" json:"media_id"`       // attached image, audio or file, see Media
+	AudioMediaID        *int      `gorm:"index" json:"audio_media_id"` // MP3, OGG Vorbis or WAV Media played on request
 	CreationDate        time.Time `gorm:"type:date" json:"creation_date"`
 	LastModifiedDate    time.Time `gorm:"type:date" json:"last_modified_date"`
 }
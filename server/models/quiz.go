@@ -15,6 +15,8 @@ type Quiz struct {
 	Points           int       `gorm:"not null" json:"points"`
 	DifficultyLevel  string    `gorm:"not null" json:"difficulty_level"`
 	HintExplanation  string    `gorm:"not null" json:"hint_explanation"`
-	QuestionCount    int       `gorm:"not null" json:"question_count"`
+	QuestionCount    int       `gorm:"not null" json:"question_count"` // derived from the quiz's questions, see controllers.updateQuestionCount
 	IsActive         bool      `gorm:"type:tinyint(1)" json:"is_active"`
+	Soundtrack       string    `gorm:"type:varchar(2083)" json:"soundtrack"`
+	MediaID          *int      `gorm:"index" json:"media_id"` // uploaded content, see Media
 }
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// QuizPack is an installed quiz pack, see the packs package
+type QuizPack struct {
+	Name        string    `gorm:"primaryKey;type:varchar(64)" json:"name"`
+	Version     string    `gorm:"type:varchar(30);not null" json:"version"`
+	Title       string    `gorm:"type:varchar(100);not null" json:"title"`
+	Description string    `gorm:"type:varchar(300)" json:"description"`
+	InstalledAt time.Time `json:"installed_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// QuizPackQuiz maps a quiz of an installed pack, by its key in the pack, to the quiz installed for it
+type QuizPackQuiz struct {
+	Pack   string `gorm:"primaryKey;type:varchar(64)" json:"pack"`
+	Key    string `gorm:"column:quiz_key;primaryKey;type:varchar(64)" json:"key"`
+	QuizID int    `gorm:"not null;uniqueIndex" json:"quiz_id"`
+}
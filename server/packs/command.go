@@ -0,0 +1,90 @@
+package packs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"text/tabwriter"
+)
+
+// IsCommand reports whether the positional arguments are a packs command, e.g. "packs install world-capitals"
+func IsCommand(args []string) bool {
+	return len(args) > 0 && args[0] == "packs"
+}
+
+// RunCommand runs a packs command, writing its output to w:
+//
+//	packs list                          the sample and installed packs with their versions
+//	packs install <path|sample>...      install or update packs from directories, zip archives, manifests or samples
+//	packs uninstall <name>...           remove installed packs and their quizzes
+func RunCommand(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: packs list | packs install <path or sample name>... | packs uninstall <name>...")
+	}
+	switch args[1] {
+	case "list":
+		return list(ctx, w)
+	case "install":
+		if len(args) < 3 {
+			return errors.New("usage: packs install <path or sample name>...")
+		}
+		for _, source := range args[2:] {
+			p, err := openSource(source)
+			if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			result, err := Install(ctx, p)
+			if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			fmt.Fprintf(w, "%s\n", result)
+		}
+		return nil
+	case "uninstall":
+		if len(args) < 3 {
+			return errors.New("usage: packs uninstall <name>...")
+		}
+		for _, name := range args[2:] {
+			removed, err := Uninstall(ctx, name)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			fmt.Fprintf(w, "uninstalled %s, removed %d quizzes\n", name, removed)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown packs command %q, expected list, install or uninstall", args[1])
+}
+
+// openSource reads a pack from a path, or the sample pack of that name when there is no such path
+func openSource(source string) (*Pack, error) {
+	p, err := Open(source)
+	if errors.Is(err, fs.ErrNotExist) {
+		if sample, sampleErr := Sample(source); !errors.Is(sampleErr, fs.ErrNotExist) {
+			return sample, sampleErr
+		}
+	}
+	return p, err
+}
+
+func list(ctx context.Context, w io.Writer) error {
+	listings, err := List(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSAMPLE\tINSTALLED\tTITLE")
+	for _, l := range listings {
+		sample, installed := "-", "-"
+		if l.SampleVersion != "" {
+			sample = l.SampleVersion
+		}
+		if l.InstalledVersion != "" {
+			installed = l.InstalledVersion
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", l.Name, sample, installed, l.Title)
+	}
+	return tw.Flush()
+}
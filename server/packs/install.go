@@ -0,0 +1,435 @@
+package packs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"letsquiz/config"
+	"letsquiz/logger"
+	"letsquiz/server/database"
+	"letsquiz/server/models"
+	"letsquiz/server/storage"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotInstalled is returned when uninstalling a pack that is not installed
+	ErrNotInstalled = errors.New("pack not installed")
+	// ErrMediaRejected is wrapped by install errors for media files the server does not accept
+	ErrMediaRejected = errors.New("media rejected")
+)
+
+// Result summarizes the quizzes an install created, updated and removed
+type Result struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previous_version,omitempty"` // empty for a first install
+	Created         int    `json:"created"`
+	Updated         int    `json:"updated"`
+	Removed         int    `json:"removed"`
+}
+
+func (r Result) String() string {
+	if r.PreviousVersion == "" {
+		return fmt.Sprintf("installed %s %s with %d quizzes", r.Name, r.Version, r.Created)
+	}
+	return fmt.Sprintf("updated %s from %s to %s: %d quizzes created, %d updated, %d removed",
+		r.Name, r.PreviousVersion, r.Version, r.Created, r.Updated, r.Removed)
+}
+
+// Installed returns the installed packs ordered by name
+func Installed(ctx context.Context) ([]models.QuizPack, error) {
+	var installed []models.QuizPack
+	err := database.DB.WithContext(ctx).Order("name").Find(&installed).Error
+	return installed, err
+}
+
+// Listing is a pack shipped with the server, installed, or both
+type Listing struct {
+	Name             string `json:"name"`
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	SampleVersion    string `json:"sample_version,omitempty"`    // version shipped with the server, empty when it is no sample
+	InstalledVersion string `json:"installed_version,omitempty"` // empty when it is not installed
+}
+
+// List returns the sample and installed packs ordered by name
+func List(ctx context.Context) ([]Listing, error) {
+	samples, err := Samples()
+	if err != nil {
+		return nil, err
+	}
+	installed, err := Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var listings []Listing
+	for _, p := range samples {
+		listings = append(listings, Listing{Name: p.Name, Title: p.Title, Description: p.Description, SampleVersion: p.Version})
+	}
+	for _, p := range installed {
+		i := sort.Search(len(listings), func(i int) bool { return listings[i].Name >= p.Name })
+		if i == len(listings) || listings[i].Name != p.Name {
+			listings = append(listings[:i], append([]Listing{{Name: p.Name, Title: p.Title, Description: p.Description}}, listings[i:]...)...)
+		}
+		listings[i].InstalledVersion = p.Version
+	}
+	return listings, nil
+}
+
+// Install installs p, or updates it in place when a version of it is installed. Quizzes are matched by key
+// and keep their IDs, questions and answers are matched by position, and whatever the new version no longer
+// has is removed.
+func Install(ctx context.Context, p *Pack) (Result, error) {
+	// Media are stored first and deduplicated by hash like uploads, so a failed install leaves no broken references
+	media, err := storeMedia(ctx, p)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Name: p.Name, Version: p.Version}
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		var installed models.QuizPack
+		found := tx.Limit(1).Find(&installed, "name = ?", p.Name)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected > 0 {
+			result.PreviousVersion = installed.Version
+		} else {
+			installed = models.QuizPack{Name: p.Name, InstalledAt: now}
+		}
+
+		var mapped []models.QuizPackQuiz
+		if err := tx.Where("pack = ?", p.Name).Find(&mapped).Error; err != nil {
+			return err
+		}
+		quizIDs := map[string]int{}
+		for _, m := range mapped {
+			quizIDs[m.Key] = m.QuizID
+		}
+
+		for _, quiz := range p.Quizzes {
+			quizID, updated, err := installQuiz(tx, p.Name, quiz, quizIDs[quiz.Key], media, now)
+			if err != nil {
+				return fmt.Errorf("quiz %q: %w", quiz.Key, err)
+			}
+			if err := installQuestions(tx, quizID, quiz.Questions, media, now); err != nil {
+				return fmt.Errorf("quiz %q: %w", quiz.Key, err)
+			}
+			if updated {
+				result.Updated++
+			} else {
+				result.Created++
+			}
+			delete(quizIDs, quiz.Key)
+		}
+
+		// The quizzes left are no longer in the pack
+		for key, quizID := range quizIDs {
+			if err := removeQuiz(tx, p.Name, key, quizID); err != nil {
+				return err
+			}
+			result.Removed++
+		}
+
+		installed.Version = p.Version
+		installed.Title = p.Title
+		installed.Description = p.Description
+		installed.UpdatedAt = now
+		return tx.Save(&installed).Error
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	logger.InfoContext(ctx, "Quiz pack installed", "pack", p.Name, "version", p.Version, "previousVersion", result.PreviousVersion,
+		"created", result.Created, "updated", result.Updated, "removed", result.Removed)
+	return result, nil
+}
+
+// Uninstall removes the quizzes of an installed pack along with their questions and answers,
+// returning how many quizzes were removed
+func Uninstall(ctx context.Context, name string) (int, error) {
+	var removed int
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		found := tx.Delete(&models.QuizPack{}, "name = ?", name)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected == 0 {
+			return ErrNotInstalled
+		}
+		var mapped []models.QuizPackQuiz
+		if err := tx.Where("pack = ?", name).Find(&mapped).Error; err != nil {
+			return err
+		}
+		for _, m := range mapped {
+			if err := removeQuiz(tx, name, m.Key, m.QuizID); err != nil {
+				return err
+			}
+		}
+		removed = len(mapped)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	logger.InfoContext(ctx, "Quiz pack uninstalled", "pack", name, "quizzes", removed)
+	return removed, nil
+}
+
+// installQuiz creates the quiz of a pack, or updates the one installed for it by an earlier version,
+// and returns its ID and whether it was updated
+func installQuiz(tx *gorm.DB, pack string, quiz Quiz, quizID int, media map[string]int, now time.Time) (int, bool, error) {
+	var row models.Quiz
+	if quizID != 0 {
+		// The installed quiz may have been deleted since, it is then created again
+		if err := tx.Limit(1).Find(&row, quizID).Error; err != nil {
+			return 0, false, err
+		}
+	}
+	updated := row.ID != 0
+	if !updated {
+		row = models.Quiz{CreationDate: now}
+	}
+
+	categoryID, err := categoryID(tx, quiz.Category)
+	if err != nil {
+		return 0, false, err
+	}
+	row.Title = quiz.Title
+	row.Description = quiz.Description
+	row.ContentURL = quiz.ContentURL
+	row.CategoryID = categoryID
+	row.LastModifiedDate = now
+	row.TimeLimitInMins = quiz.TimeLimitInMins
+	row.Points = quiz.Points
+	row.DifficultyLevel = quiz.DifficultyLevel
+	row.HintExplanation = quiz.HintExplanation
+	row.QuestionCount = len(quiz.Questions)
+	row.IsActive = quiz.Active()
+	row.Soundtrack = quiz.Soundtrack
+	row.MediaID = mediaRef(media, quiz.Media)
+	if err := tx.Save(&row).Error; err != nil {
+		return 0, false, err
+	}
+	if !updated {
+		if err := tx.Save(&models.QuizPackQuiz{Pack: pack, Key: quiz.Key, QuizID: row.ID}).Error; err != nil {
+			return 0, false, err
+		}
+	}
+	return row.ID, updated, nil
+}
+
+// installQuestions replaces the questions of a quiz with those of the pack, updating them in place by position
+func installQuestions(tx *gorm.DB, quizID int, questions []Question, media map[string]int, now time.Time) error {
+	var existing []models.Question
+	if err := tx.Where("quiz_id = ?", quizID).Order("position, id").Find(&existing).Error; err != nil {
+		return err
+	}
+	for i, question := range questions {
+		row := models.Question{QuizID: quizID, CreationDate: now}
+		if i < len(existing) {
+			row = existing[i]
+		}
+		row.Position = i
+		row.Text = question.Text
+		row.Type = question.QuestionType()
+		row.HintExplanation = question.HintExplanation
+		row.DifficultyLevel = question.DifficultyLevel
+		row.Points = question.Points
+		row.MultiChoiceAnsLimit = question.AnswerLimit()
+		row.MediaID = mediaRef(media, question.Media)
+		row.LastModifiedDate = now
+		if err := tx.Save(&row).Error; err != nil {
+			return err
+		}
+		if err := installAnswers(tx, row.ID, question.Answers, now); err != nil {
+			return err
+		}
+	}
+
+	if len(existing) > len(questions) {
+		for _, extra := range existing[len(questions):] {
+			if err := tx.Where("question_id = ?", extra.ID).Delete(&models.Answer{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&extra).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// installAnswers replaces the answers of a question with those of the pack, updating them in place by position
+func installAnswers(tx *gorm.DB, questionID int, answers []Answer, now time.Time) error {
+	var existing []models.Answer
+	if err := tx.Where("question_id = ?", questionID).Order("position, id").Find(&existing).Error; err != nil {
+		return err
+	}
+	for i, answer := range answers {
+		row := models.Answer{QuestionID: questionID, CreationDate: now}
+		if i < len(existing) {
+			row = existing[i]
+		}
+		row.Position = i
+		row.Text = answer.Text
+		row.IsCorrect = answer.Correct
+		row.LastModifiedDate = now
+		if err := tx.Save(&row).Error; err != nil {
+			return err
+		}
+	}
+	if len(existing) > len(answers) {
+		for _, extra := range existing[len(answers):] {
+			if err := tx.Delete(&extra).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeQuiz deletes a quiz installed by a pack with its questions and answers
+func removeQuiz(tx *gorm.DB, pack, key string, quizID int) error {
+	var questions []models.Question
+	if err := tx.Where("quiz_id = ?", quizID).Find(&questions).Error; err != nil {
+		return err
+	}
+	for _, question := range questions {
+		if err := tx.Where("question_id = ?", question.ID).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("quiz_id = ?", quizID).Delete(&models.Question{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&models.Quiz{}, quizID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.QuizPackQuiz{}, "pack = ? AND quiz_key = ?", pack, key).Error
+}
+
+// categoryID returns the ID of the category with the given name, creating it when there is none
+func categoryID(tx *gorm.DB, name string) (int, error) {
+	var category models.Category
+	if err := tx.Where("name = ?", name).Limit(1).Find(&category).Error; err != nil {
+		return 0, err
+	}
+	if category.ID == 0 {
+		category.Name = name
+		if err := tx.Create(&category).Error; err != nil {
+			return 0, err
+		}
+	}
+	return category.ID, nil
+}
+
+func mediaRef(media map[string]int, name string) *int {
+	if name == "" {
+		return nil
+	}
+	id := media[name]
+	return &id
+}
+
+// storeMedia stores the media files the pack refers to, and returns their media IDs by path
+func storeMedia(ctx context.Context, p *Pack) (map[string]int, error) {
+	media := map[string]int{}
+	var names []string
+	for _, quiz := range p.Quizzes {
+		names = append(names, quiz.Media)
+		for _, question := range quiz.Questions {
+			names = append(names, question.Media)
+		}
+	}
+
+	db := database.DB.WithContext(ctx)
+	for _, name := range names {
+		if _, stored := media[name]; name == "" || stored {
+			continue
+		}
+		data, err := p.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > config.DbConfig.MaxMediaBytes {
+			return nil, fmt.Errorf("%w: %q is larger than max_media_bytes (%d)", ErrMediaRejected, name, config.DbConfig.MaxMediaBytes)
+		}
+		contentType := contentType(name, data)
+		if !mediaTypeAllowed(contentType) {
+			return nil, fmt.Errorf("%w: %q is a %s, which is not in media_allowed_types", ErrMediaRejected, name, contentType)
+		}
+
+		hash := sha256.Sum256(data)
+		sum := hex.EncodeToString(hash[:])
+		var row models.Media
+		if err := db.Where("sha256 = ?", sum).Limit(1).Find(&row).Error; err != nil {
+			return nil, err
+		}
+		if row.ID == 0 {
+			row = models.Media{
+				SHA256:       sum,
+				ContentType:  contentType,
+				Size:         int64(len(data)),
+				FileName:     path.Base(name),
+				StorageKey:   "sha256/" + sum[:2] + "/" + sum,
+				CreationDate: time.Now().UTC(),
+			}
+		}
+		exists, err := storage.Media.Exists(ctx, row.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("media %q: %w", name, err)
+		}
+		if !exists {
+			if err := storage.Media.Put(ctx, row.StorageKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+				return nil, fmt.Errorf("media %q: %w", name, err)
+			}
+		}
+		if row.ID == 0 {
+			if err := db.Create(&row).Error; err != nil {
+				return nil, fmt.Errorf("media %q: %w", name, err)
+			}
+		}
+		media[name] = row.ID
+	}
+	return media, nil
+}
+
+// contentType detects the type of a media file from its first bytes, falling back to its extension for
+// formats http.DetectContentType does not recognize, such as MP3 files without an ID3 tag
+func contentType(name string, data []byte) string {
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	switch detected {
+	case "application/ogg":
+		return "audio/ogg"
+	case "audio/wave":
+		return "audio/wav"
+	case "application/octet-stream":
+		if byExtension, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(name))); err == nil {
+			return byExtension
+		}
+	}
+	return detected
+}
+
+func mediaTypeAllowed(contentType string) bool {
+	for _, allowed := range config.DbConfig.MediaAllowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
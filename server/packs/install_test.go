@@ -0,0 +1,193 @@
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"letsquiz/server/database"
+	"letsquiz/server/database/databasetest"
+	"letsquiz/server/models"
+	"letsquiz/server/storage"
+)
+
+// openPacks opens the test database with media stored in its directory, and a quiz of a user's own
+func openPacks(t *testing.T) models.Quiz {
+	t.Helper()
+	databasetest.Open(t)
+	if err := storage.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	own := models.Quiz{Title: "Rivers"}
+	if err := database.DB.Create(&own).Error; err != nil {
+		t.Fatal(err)
+	}
+	return own
+}
+
+// testPack parses a version of the "capitals" pack, whose media is a map
+func testPack(t *testing.T, version string, quizzes ...Quiz) *Pack {
+	t.Helper()
+	manifest, err := json.Marshal(Pack{Name: "capitals", Version: version, Title: "Capitals", Quizzes: quizzes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := fstest.MapFS{"map.png": {Data: []byte("\x89PNG\r\n\x1a\nthe map")}}
+	p, err := Parse(manifest, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// question is a single choice question whose first answer is correct
+func question(text string, answers ...string) Question {
+	q := Question{Text: text}
+	for n, answer := range answers {
+		q.Answers = append(q.Answers, Answer{Text: answer, Correct: n == 0})
+	}
+	return q
+}
+
+// count returns the number of records of model matching the conditions
+func count(t *testing.T, model interface{}, conds ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	db := database.DB.Model(model)
+	if len(conds) > 0 {
+		db = db.Where(conds[0], conds[1:]...)
+	}
+	if err := db.Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// installedQuiz loads the quiz installed for key, with its questions and their answers by position
+func installedQuiz(t *testing.T, key string) (models.Quiz, []models.Question, [][]models.Answer) {
+	t.Helper()
+	var mapped models.QuizPackQuiz
+	var quiz models.Quiz
+	if err := database.DB.First(&mapped, "pack = ? AND quiz_key = ?", "capitals", key).Error; err != nil {
+		t.Fatalf("quiz %q: %v", key, err)
+	}
+	if err := database.DB.First(&quiz, mapped.QuizID).Error; err != nil {
+		t.Fatalf("quiz %q: %v", key, err)
+	}
+	var questions []models.Question
+	database.DB.Where("quiz_id = ?", quiz.ID).Order("position").Find(&questions)
+	answers := make([][]models.Answer, len(questions))
+	for n, q := range questions {
+		database.DB.Where("question_id = ?", q.ID).Order("position").Find(&answers[n])
+	}
+	return quiz, questions, answers
+}
+
+var (
+	europe = Quiz{Key: "europe", Title: "Capitals of Europe", Category: "Geography", Media: "map.png", Questions: []Question{
+		question("Capital of Portugal?", "Lisbon", "Porto", "Faro"),
+		question("Capital of Spain?", "Madrid", "Seville"),
+	}}
+	asia   = Quiz{Key: "asia", Title: "Capitals of Asia", Category: "Geography", Questions: []Question{question("Capital of Japan?", "Tokyo", "Osaka")}}
+	africa = Quiz{Key: "africa", Title: "Capitals of Africa", Category: "Geography", Questions: []Question{question("Capital of Kenya?", "Nairobi", "Mombasa")}}
+)
+
+func TestInstall(t *testing.T) {
+	own := openPacks(t)
+	ctx := context.Background()
+
+	result, err := Install(ctx, testPack(t, "1.0.0", europe, asia))
+	if err != nil || result != (Result{Name: "capitals", Version: "1.0.0", Created: 2}) {
+		t.Fatalf("Install() = %+v, %v, want both quizzes created", result, err)
+	}
+	quiz, questions, answers := installedQuiz(t, "europe")
+	var media models.Media
+	database.DB.First(&media)
+	if quiz.Title != "Capitals of Europe" || quiz.QuestionCount != 2 || !quiz.IsActive || quiz.MediaID == nil || *quiz.MediaID != media.ID {
+		t.Errorf("quiz = %+v, want Capitals of Europe, active with 2 questions and the map %d", quiz, media.ID)
+	}
+	if len(questions) != 2 || len(answers[0]) != 3 || answers[0][0].Text != "Lisbon" || !answers[0][0].IsCorrect || answers[1][1].Text != "Seville" {
+		t.Errorf("questions %+v with answers %+v, want those of the pack in order", questions, answers)
+	}
+	if n := count(t, &models.Category{}); n != 1 {
+		t.Errorf("%d categories, want Geography created once", n)
+	}
+
+	// The new version renames the first question and drops an answer of it, drops the second question
+	// and the asia quiz, and adds africa; what it keeps is updated in place
+	edited := europe
+	edited.Title = "European capitals"
+	edited.Media = ""
+	edited.Questions = []Question{question("Capital of Portugal, the country?", "Lisbon", "Porto")}
+	result, err = Install(ctx, testPack(t, "2.0.0", edited, africa))
+	want := Result{Name: "capitals", Version: "2.0.0", PreviousVersion: "1.0.0", Created: 1, Updated: 1, Removed: 1}
+	if err != nil || result != want {
+		t.Fatalf("reinstall = %+v, %v, want %+v", result, err, want)
+	}
+	if got := result.String(); got != "updated capitals from 1.0.0 to 2.0.0: 1 quizzes created, 1 updated, 1 removed" {
+		t.Errorf("String() = %q", got)
+	}
+	updated, updatedQuestions, updatedAnswers := installedQuiz(t, "europe")
+	if updated.ID != quiz.ID || updated.Title != "European capitals" || updated.QuestionCount != 1 || updated.MediaID != nil {
+		t.Errorf("quiz = %+v, want quiz %d renamed with 1 question and no media", updated, quiz.ID)
+	}
+	if len(updatedQuestions) != 1 || updatedQuestions[0].ID != questions[0].ID || updatedQuestions[0].Text != "Capital of Portugal, the country?" {
+		t.Errorf("questions = %+v, want question %d renamed", updatedQuestions, questions[0].ID)
+	}
+	if len(updatedAnswers[0]) != 2 || updatedAnswers[0][0].ID != answers[0][0].ID || updatedAnswers[0][1].ID != answers[0][1].ID {
+		t.Errorf("answers = %+v, want the first two kept", updatedAnswers[0])
+	}
+	installedQuiz(t, "africa")
+	for _, c := range []struct {
+		model interface{}
+		want  int64
+	}{
+		{&models.Quiz{}, 3}, {&models.Question{}, 2}, {&models.Answer{}, 4}, {&models.QuizPackQuiz{}, 2},
+	} {
+		if got := count(t, c.model); got != c.want {
+			t.Errorf("%d %T, want %d: those of europe, africa and the user's own", got, c.model, c.want)
+		}
+	}
+	var pack models.QuizPack
+	if database.DB.First(&pack, "name = ?", "capitals"); pack.Version != "2.0.0" || !pack.UpdatedAt.After(pack.InstalledAt) {
+		t.Errorf("installed pack = %+v, want version 2.0.0 updated after it was installed", pack)
+	}
+
+	// An installed quiz deleted since is created again
+	database.DB.Delete(&models.Quiz{}, updated.ID)
+	if result, err = Install(ctx, testPack(t, "2.0.1", edited, africa)); err != nil || result.Created != 1 || result.Updated != 1 {
+		t.Errorf("reinstall = %+v, %v, want the deleted quiz created again", result, err)
+	}
+	if recreated, _, _ := installedQuiz(t, "europe"); recreated.ID == updated.ID {
+		t.Errorf("europe installed as quiz %d, want a new quiz", recreated.ID)
+	}
+	if count(t, &models.Quiz{}, "id = ?", own.ID) != 1 {
+		t.Errorf("the user's own quiz was removed")
+	}
+}
+
+func TestUninstall(t *testing.T) {
+	own := openPacks(t)
+	ctx := context.Background()
+	if _, err := Install(ctx, testPack(t, "1.0.0", europe, asia)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Uninstall(ctx, "capitals")
+	if err != nil || removed != 2 {
+		t.Fatalf("Uninstall() = %d, %v, want both quizzes removed", removed, err)
+	}
+	if n := count(t, &models.Quiz{}); n != 1 || count(t, &models.Quiz{}, "id = ?", own.ID) != 1 {
+		t.Errorf("%d quizzes left, want only the user's own", n)
+	}
+	for _, model := range []interface{}{&models.Question{}, &models.Answer{}, &models.QuizPack{}, &models.QuizPackQuiz{}} {
+		if n := count(t, model); n != 0 {
+			t.Errorf("%d %T left, want none", n, model)
+		}
+	}
+	if _, err := Uninstall(ctx, "capitals"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Uninstall() again = %v, want ErrNotInstalled", err)
+	}
+}
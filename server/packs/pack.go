@@ -0,0 +1,313 @@
+// Package packs defines quiz packs, a portable format to ship quizzes with their questions, answers and media,
+// and installs them into the database.
+//
+// A pack is a directory, or a zip archive of one, holding a pack.json manifest and the media files it refers to:
+//
+//	{
+//	  "name": "world-capitals",
+//	  "version": "1.0.0",
+//	  "title": "World Capitals",
+//	  "quizzes": [{
+//	    "key": "europe",
+//	    "title": "Capitals of Europe",
+//	    "category": "Geography",
+//	    "media": "europe.png",
+//	    "questions": [{
+//	      "text": "What is the capital of Portugal?",
+//	      "answers": [{"text": "Lisbon", "correct": true}, {"text": "Porto"}]
+//	    }]
+//	  }]
+//	}
+//
+// Installing a pack whose name is installed already updates it in place: its quizzes, matched by key,
+// keep their IDs, and quizzes no longer in the pack are removed.
+package packs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Manifest is the name of the file describing a pack
+const Manifest = "pack.json"
+
+// Question types, as used by the quiz editor
+const (
+	SingleChoice   = "single"
+	MultipleChoice = "multiple"
+)
+
+// Pack is a quiz pack, as described by its manifest
+type Pack struct {
+	Name        string `json:"name"`    // identifies the pack, lowercase letters, digits and dashes
+	Version     string `json:"version"` // recorded when installed, shown when listing packs
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Quizzes     []Quiz `json:"quizzes"`
+
+	files fs.FS // holds the media files, nil for a manifest without any
+}
+
+// Quiz is a quiz of a pack
+type Quiz struct {
+	Key             string     `json:"key"` // identifies the quiz within its pack across versions
+	Title           string     `json:"title"`
+	Description     string     `json:"description,omitempty"`
+	Category        string     `json:"category"` // created when no category has this name
+	ContentURL      string     `json:"content_url,omitempty"`
+	TimeLimitInMins int        `json:"time_limit_in_mins,omitempty"`
+	Points          int        `json:"points,omitempty"`
+	DifficultyLevel string     `json:"difficulty_level,omitempty"`
+	HintExplanation string     `json:"hint_explanation,omitempty"`
+	IsActive        *bool      `json:"is_active,omitempty"` // true when omitted
+	Soundtrack      string     `json:"soundtrack,omitempty"`
+	Media           string     `json:"media,omitempty"` // path of a file in the pack
+	Questions       []Question `json:"questions"`
+}
+
+// Question is a question of a pack's quiz
+type Question struct {
+	Text                string   `json:"text"`
+	Type                string   `json:"type,omitempty"` // SingleChoice when omitted
+	HintExplanation     string   `json:"hint_explanation,omitempty"`
+	DifficultyLevel     string   `json:"difficulty_level,omitempty"`
+	Points              float64  `json:"points,omitempty"`
+	MultiChoiceAnsLimit int      `json:"multi_choice_ans_limit,omitempty"` // the number of correct answers when omitted
+	Media               string   `json:"media,omitempty"`                  // path of a file in the pack
+	Answers             []Answer `json:"answers"`
+}
+
+// Answer is an answer to a pack's question
+type Answer struct {
+	Text    string `json:"text"`
+	Correct bool   `json:"correct,omitempty"`
+}
+
+// Active reports whether the quiz is installed active
+func (q Quiz) Active() bool {
+	return q.IsActive == nil || *q.IsActive
+}
+
+// QuestionType returns the type of the question, SingleChoice when omitted
+func (q Question) QuestionType() string {
+	if q.Type == "" {
+		return SingleChoice
+	}
+	return q.Type
+}
+
+// AnswerLimit returns how many answers may be chosen: one for a single choice question, otherwise
+// multi_choice_ans_limit or, when omitted, the number of correct answers
+func (q Question) AnswerLimit() int {
+	if q.QuestionType() == SingleChoice {
+		return 1
+	}
+	if q.MultiChoiceAnsLimit > 0 {
+		return q.MultiChoiceAnsLimit
+	}
+	limit := 0
+	for _, answer := range q.Answers {
+		if answer.Correct {
+			limit++
+		}
+	}
+	return limit
+}
+
+// ReadFile reads a media file of the pack
+func (p *Pack) ReadFile(name string) ([]byte, error) {
+	if p.files == nil {
+		return nil, fmt.Errorf("media %q: %w", name, fs.ErrNotExist)
+	}
+	return fs.ReadFile(p.files, name)
+}
+
+// Parse parses a manifest, files holding the media it refers to. files may be nil for a pack without media.
+func Parse(manifest []byte, files fs.FS) (*Pack, error) {
+	var p Pack
+	decoder := json.NewDecoder(bytes.NewReader(manifest))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%s: %w", Manifest, err)
+	}
+	p.files = files
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Read reads the pack whose manifest is at the root of files
+func Read(files fs.FS) (*Pack, error) {
+	manifest, err := fs.ReadFile(files, Manifest)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(manifest, files)
+}
+
+// ReadZip reads a pack from a zip archive, with the manifest at its root or in its single top-level directory
+func ReadZip(data []byte) (*Pack, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a zip archive: %w", err)
+	}
+	var files fs.FS = archive
+	if _, err := fs.Stat(archive, Manifest); err != nil {
+		matches, _ := fs.Glob(archive, "*/"+Manifest)
+		if len(matches) != 1 {
+			return nil, fmt.Errorf("no %s at the root of the archive", Manifest)
+		}
+		if files, err = fs.Sub(archive, path.Dir(matches[0])); err != nil {
+			return nil, err
+		}
+	}
+	return Read(files)
+}
+
+// Open reads the pack in a directory, in a zip archive, or described by a manifest file, whose media are
+// then looked up next to it
+func Open(name string) (*Pack, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return Read(os.DirFS(name))
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".zip") {
+		return ReadZip(data)
+	}
+	return Parse(data, os.DirFS(filepath.Dir(name)))
+}
+
+// Load reads a pack uploaded as a zip archive or as a manifest without media, telling them apart by content
+func Load(data []byte) (*Pack, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return ReadZip(data)
+	}
+	return Parse(data, nil)
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Validate checks the pack against the limits of the database and the quiz editor, reporting every problem
+func (p *Pack) Validate() error {
+	var errs []error
+	if !namePattern.MatchString(p.Name) {
+		errs = append(errs, fmt.Errorf("name %q must be 1 to 64 lowercase letters, digits and dashes", p.Name))
+	}
+	if p.Version == "" || len(p.Version) > 30 {
+		errs = append(errs, errors.New("version is required, at most 30 characters"))
+	}
+	if p.Title == "" || len(p.Title) > 100 {
+		errs = append(errs, errors.New("title is required, at most 100 characters"))
+	}
+	if len(p.Description) > 300 {
+		errs = append(errs, errors.New("description must be at most 300 characters"))
+	}
+	if len(p.Quizzes) == 0 {
+		errs = append(errs, errors.New("quizzes: a pack needs at least one quiz"))
+	}
+
+	keys := map[string]bool{}
+	for i, quiz := range p.Quizzes {
+		at := fmt.Sprintf("quizzes[%d]", i)
+		if !namePattern.MatchString(quiz.Key) {
+			errs = append(errs, fmt.Errorf("%s: key %q must be 1 to 64 lowercase letters, digits and dashes", at, quiz.Key))
+		} else if keys[quiz.Key] {
+			errs = append(errs, fmt.Errorf("%s: key %q is used by another quiz", at, quiz.Key))
+		}
+		keys[quiz.Key] = true
+		if quiz.Title == "" || len(quiz.Title) > 100 {
+			errs = append(errs, fmt.Errorf("%s: title is required, at most 100 characters", at))
+		}
+		if len(quiz.Description) > 300 {
+			errs = append(errs, fmt.Errorf("%s: description must be at most 300 characters", at))
+		}
+		if quiz.Category == "" || len(quiz.Category) > 70 {
+			errs = append(errs, fmt.Errorf("%s: category is required, at most 70 characters", at))
+		}
+		if quiz.TimeLimitInMins < 0 || quiz.Points < 0 {
+			errs = append(errs, fmt.Errorf("%s: time_limit_in_mins and points must not be negative", at))
+		}
+		errs = append(errs, p.checkMedia(at, quiz.Media)...)
+		if len(quiz.Questions) == 0 {
+			errs = append(errs, fmt.Errorf("%s: questions: a quiz needs at least one question", at))
+		}
+		for j, question := range quiz.Questions {
+			errs = append(errs, p.validateQuestion(fmt.Sprintf("%s.questions[%d]", at, j), question)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pack) validateQuestion(at string, question Question) []error {
+	var errs []error
+	if strings.TrimSpace(question.Text) == "" {
+		errs = append(errs, fmt.Errorf("%s: text is required", at))
+	}
+	questionType := question.QuestionType()
+	if questionType != SingleChoice && questionType != MultipleChoice {
+		errs = append(errs, fmt.Errorf("%s: type %q must be %q or %q", at, question.Type, SingleChoice, MultipleChoice))
+	}
+	if len(question.DifficultyLevel) > 10 {
+		errs = append(errs, fmt.Errorf("%s: difficulty_level must be at most 10 characters", at))
+	}
+	if question.Points < 0 || question.MultiChoiceAnsLimit < 0 {
+		errs = append(errs, fmt.Errorf("%s: points and multi_choice_ans_limit must not be negative", at))
+	}
+	errs = append(errs, p.checkMedia(at, question.Media)...)
+
+	correct := 0
+	for k, answer := range question.Answers {
+		if strings.TrimSpace(answer.Text) == "" {
+			errs = append(errs, fmt.Errorf("%s.answers[%d]: text is required", at, k))
+		}
+		if answer.Correct {
+			correct++
+		}
+	}
+	switch {
+	case len(question.Answers) < 2:
+		errs = append(errs, fmt.Errorf("%s: answers: a question needs at least two answers", at))
+	case correct == 0:
+		errs = append(errs, fmt.Errorf("%s: answers: no answer is marked correct", at))
+	case questionType == SingleChoice && correct > 1:
+		errs = append(errs, fmt.Errorf("%s: answers: a %q question has exactly one correct answer, %d are marked correct", at, SingleChoice, correct))
+	case question.MultiChoiceAnsLimit > len(question.Answers):
+		errs = append(errs, fmt.Errorf("%s: multi_choice_ans_limit %d exceeds the %d answers", at, question.MultiChoiceAnsLimit, len(question.Answers)))
+	}
+	return errs
+}
+
+// checkMedia checks that a media path refers to a file in the pack
+func (p *Pack) checkMedia(at, name string) []error {
+	if name == "" {
+		return nil
+	}
+	if !fs.ValidPath(name) {
+		return []error{fmt.Errorf("%s: media %q must be a relative path within the pack", at, name)}
+	}
+	if p.files == nil {
+		return []error{fmt.Errorf("%s: media %q is not in the pack, install the pack's directory or zip archive instead of its manifest", at, name)}
+	}
+	info, err := fs.Stat(p.files, name)
+	if err != nil || info.IsDir() {
+		return []error{fmt.Errorf("%s: media %q is not in the pack", at, name)}
+	}
+	return nil
+}
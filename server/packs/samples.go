@@ -0,0 +1,45 @@
+package packs
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+// samples holds the packs shipped with the server, one directory per pack named after it
+//
+//go:embed samples
+var samples embed.FS
+
+// Samples returns the packs shipped with the server, ordered by name
+func Samples() ([]*Pack, error) {
+	entries, err := samples.ReadDir("samples")
+	if err != nil {
+		return nil, err
+	}
+	var packs []*Pack
+	for _, entry := range entries {
+		p, err := Sample(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	return packs, nil
+}
+
+// Sample returns the pack shipped with the server under name, the error wraps fs.ErrNotExist when there is none
+func Sample(name string) (*Pack, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("sample pack %q: %w", name, fs.ErrNotExist)
+	}
+	files, err := fs.Sub(samples, "samples/"+name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := Read(files)
+	if err != nil {
+		return nil, fmt.Errorf("sample pack %q: %w", name, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,87 @@
+package quizfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	aikenOption = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+	aikenAnswer = regexp.MustCompile(`^ANSWER:\s*(.*)$`)
+)
+
+// ParseAiken reads single choice questions in Moodle's Aiken format, options lettered in order and
+// the right one given by the ANSWER line:
+//
+//	What is the capital of Portugal?
+//	A. Porto
+//	B) Lisbon
+//	ANSWER: B
+//
+// Aiken has no categories, feedback or points.
+func ParseAiken(text string) ([]Question, []Diagnostic) {
+	var questions []Question
+	var diagnostics []Diagnostic
+	var question *Question
+	skipping := false // the rest of a question that could not be mapped, up to its ANSWER or a blank line
+	skip := func(line int, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...), Skipped: true})
+		question, skipping = nil, true
+	}
+
+	for i, raw := range strings.Split(text, "\n") {
+		n := i + 1
+		line := strings.TrimSpace(raw)
+		answer := aikenAnswer.FindStringSubmatch(line)
+		if skipping {
+			skipping = line != "" && answer == nil
+			continue
+		}
+		option := aikenOption.FindStringSubmatch(line)
+
+		switch {
+		case line == "":
+			if question != nil {
+				skip(question.Line, "no ANSWER line after the question")
+				skipping = false
+			}
+		case question == nil && answer != nil:
+			diagnostics = append(diagnostics, Diagnostic{Line: n, Message: "ANSWER line without a question", Skipped: true})
+		case question == nil && option != nil:
+			skip(n, "option %s. without a question", option[1])
+		case question == nil:
+			question = &Question{Line: n, Text: line, Type: SingleChoice, Points: DefaultPoints}
+		case option != nil:
+			if expected := string(rune('A' + len(question.Answers))); option[1] != expected {
+				skip(n, "option %s. is out of order, expected %s.", option[1], expected)
+				continue
+			}
+			question.Answers = append(question.Answers, Answer{Text: strings.TrimSpace(option[2])})
+		case answer != nil:
+			letter := strings.TrimSpace(answer[1])
+			switch {
+			case len(question.Answers) < 2:
+				skip(question.Line, "a question needs at least two options")
+				skipping = false
+				continue
+			case len(letter) != 1 || letter[0] < 'A' || int(letter[0]-'A') >= len(question.Answers):
+				skip(n, "ANSWER %q is not one of the options A to %c", letter, rune('A'+len(question.Answers)-1))
+				skipping = false
+				continue
+			}
+			question.Answers[letter[0]-'A'].Correct = true
+			questions = append(questions, *question)
+			question = nil
+		case len(question.Answers) == 0:
+			// The question's text continues
+			question.Text += "\n" + line
+		default:
+			skip(n, "expected an option such as \"%c. ...\" or the ANSWER line", rune('A'+len(question.Answers)))
+		}
+	}
+	if question != nil {
+		diagnostics = append(diagnostics, Diagnostic{Line: question.Line, Message: "no ANSWER line after the question", Skipped: true})
+	}
+	return questions, diagnostics
+}
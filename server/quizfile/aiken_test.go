@@ -0,0 +1,114 @@
+package quizfile
+
+import "testing"
+
+func TestParseAiken(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		want        []Question
+		diagnostics []wantDiagnostic
+	}{
+		{
+			name: "questions",
+			text: "What is the capital of Portugal?\n" +
+				"A. Porto\n" +
+				"B) Lisbon\n" +
+				"C. Faro\n" +
+				"ANSWER: B\n" +
+				"\n" +
+				"Which river flows\n" +
+				"through Lisbon?\n" +
+				"A) Tagus\n" +
+				"B) Douro\n" +
+				"ANSWER:A\n" +
+				"Which city is further north?\n" +
+				"A. Porto\n" +
+				"B. Lisbon\n" +
+				"ANSWER: A\n",
+			want: []Question{
+				{Line: 1, Text: "What is the capital of Portugal?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}, {Text: "Faro"}}},
+				{Line: 7, Text: "Which river flows\nthrough Lisbon?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Tagus", Correct: true}, {Text: "Douro"}}},
+				{Line: 12, Text: "Which city is further north?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Porto", Correct: true}, {Text: "Lisbon"}}},
+			},
+		},
+		{
+			name: "malformed ANSWER lines",
+			text: "What is the capital of Portugal?\n" +
+				"A. Porto\n" +
+				"B. Lisbon\n" +
+				"ANSWER: C\n" +
+				"\n" +
+				"Which river flows through Lisbon?\n" +
+				"A. Tagus\n" +
+				"B. Douro\n" +
+				"ANSWER: A or B\n" +
+				"Which city is further north?\n" +
+				"A. Porto\n" +
+				"B. Lisbon\n" +
+				"ANSWER:\n" +
+				"\n" +
+				"ANSWER: A\n" +
+				"\n" +
+				"Which is the capital?\n" +
+				"A. Lisbon\n" +
+				"B. Porto\n" +
+				"ANSWER: A\n",
+			want: []Question{
+				{Line: 17, Text: "Which is the capital?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Lisbon", Correct: true}, {Text: "Porto"}}},
+			},
+			diagnostics: []wantDiagnostic{
+				{4, true, `ANSWER "C" is not one of the options A to B`},
+				{9, true, `ANSWER "A or B" is not one of the options A to B`},
+				{13, true, `ANSWER "" is not one of the options A to B`},
+				{15, true, "ANSWER line without a question"},
+			},
+		},
+		{
+			name: "skipped questions",
+			text: "What is the capital of Portugal?\n" +
+				"A. Lisbon\n" +
+				"C. Porto\n" +
+				"D. Faro\n" +
+				"ANSWER: A\n" +
+				"\n" +
+				"Which river flows through Lisbon?\n" +
+				"A. Tagus\n" +
+				"ANSWER: A\n" +
+				"\n" +
+				"Which city is further north?\n" +
+				"A. Porto\n" +
+				"B. Lisbon\n" +
+				"\n" +
+				"A. Faro\n" +
+				"\n" +
+				"Which is the capital?\n" +
+				"A. Lisbon\n" +
+				"B. Porto\n" +
+				"the capital is on the coast\n" +
+				"ANSWER: A\n" +
+				"\n" +
+				"Which is the largest city?\n" +
+				"A. Lisbon\n" +
+				"B. Porto\n",
+			diagnostics: []wantDiagnostic{
+				{3, true, "option C. is out of order, expected B."},
+				{7, true, "a question needs at least two options"},
+				{11, true, "no ANSWER line after the question"},
+				{15, true, "option A. without a question"},
+				{20, true, `expected an option such as "C. ..."`},
+				{23, true, "no ANSWER line after the question"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions, diagnostics := ParseAiken(tt.text)
+			checkParsed(t, questions, diagnostics, tt.want, tt.diagnostics)
+		})
+	}
+}
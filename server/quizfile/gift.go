@@ -0,0 +1,343 @@
+package quizfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// giftItem is a question, or other item, of a GIFT file: the lines up to the next blank line
+type giftItem struct {
+	lines    []int  // line numbers in the file of the item's lines
+	text     string // the item's lines joined by newlines
+	category string // category set by the last $CATEGORY before the item
+}
+
+// lineAt returns the line number in the file of an offset in the item's text
+func (item giftItem) lineAt(offset int) int {
+	return item.lines[strings.Count(item.text[:offset], "\n")]
+}
+
+// ParseGIFT reads questions in Moodle's GIFT format:
+//
+//	// Comments are ignored
+//	$CATEGORY: $course$/Geography/Capitals
+//
+//	::Q1:: What is the capital of Portugal? {=Lisbon ~Porto#Porto is the largest city in the north. ~Faro}
+//
+//	Lisbon is on the Atlantic coast. {T}
+//
+//	Which are capitals? {~%50%Lisbon ~%50%Bern ~%-100%Porto ####Porto is not a capital.}
+//
+// Multiple choice questions with one right answer map to single choice questions, and those whose answers are
+// weighted by percentages to multiple choice questions with the positively weighted answers correct. True/false
+// questions offer True and False, and missing word questions keep a blank where the answers were. Feedback goes
+// into the question's hint and categories into the question's category. Matching, short answer, numerical and
+// essay questions are skipped.
+func ParseGIFT(text string) ([]Question, []Diagnostic) {
+	var items []giftItem
+	var current giftItem
+	var lines []string
+	category := ""
+	flush := func() {
+		if len(lines) > 0 {
+			current.text = strings.Join(lines, "\n")
+			current.category = category
+			items = append(items, current)
+		}
+		current, lines = giftItem{}, nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+			// A comment, also within an item
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			flush()
+			category = strings.TrimSpace(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+		default:
+			current.lines = append(current.lines, i+1)
+			lines = append(lines, line)
+		}
+	}
+	flush()
+
+	var questions []Question
+	var diagnostics []Diagnostic
+	for _, item := range items {
+		question, itemDiagnostics := parseGIFTItem(item)
+		diagnostics = append(diagnostics, itemDiagnostics...)
+		if question != nil {
+			questions = append(questions, *question)
+		}
+	}
+	return questions, diagnostics
+}
+
+// parseGIFTItem maps an item to a question, returning nil with the reason when it cannot be mapped
+func parseGIFTItem(item giftItem) (*Question, []Diagnostic) {
+	var diagnostics []Diagnostic
+	skip := func(line int, format string, args ...interface{}) (*Question, []Diagnostic) {
+		return nil, append(diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...), Skipped: true})
+	}
+	note := func(line int, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	s := item.text
+
+	// An optional ::title:: names the question in Moodle's question bank, letsquiz has no such name
+	start := 0
+	if strings.HasPrefix(strings.TrimSpace(s), "::") {
+		open := strings.Index(s, "::") + 2
+		end := indexUnescaped(s[open:], "::")
+		if end < 0 {
+			return skip(item.lineAt(0), "the question title is not closed with ::")
+		}
+		start = open + end + 2
+	}
+
+	open := indexUnescaped(s[start:], "{")
+	if open < 0 {
+		return skip(item.lineAt(start), "no answers between { and }, descriptions are not questions")
+	}
+	open += start
+	end := indexUnescaped(s[open+1:], "}")
+	if end < 0 {
+		return skip(item.lineAt(open), "the answers opened with { are not closed with }")
+	}
+	end += open + 1
+	if indexUnescaped(s[end+1:], "{") >= 0 {
+		return skip(item.lineAt(end), "only one set of answers in { } is supported per question")
+	}
+
+	before, isHTML := stripFormat(s[start:open])
+	if isHTML {
+		note(item.lineAt(start), "HTML formatting is kept as is, the player shows Markdown")
+	}
+	text := unescape(before)
+	if after := strings.TrimSpace(s[end+1:]); after != "" {
+		// A missing word question, the answers fill the blank
+		text = strings.TrimSpace(text) + " _____ " + unescape(after)
+	}
+	question := Question{
+		Line:     item.lineAt(0),
+		Category: item.category,
+		Text:     strings.TrimSpace(text),
+		Points:   DefaultPoints,
+	}
+	if question.Text == "" {
+		return skip(item.lineAt(start), "the question has no text")
+	}
+
+	answers := s[open+1 : end]
+	var hints []string
+	if general := indexUnescaped(answers, "####"); general >= 0 {
+		if feedback := strings.TrimSpace(unescape(answers[general+4:])); feedback != "" {
+			hints = append(hints, feedback)
+		}
+		answers = answers[:general]
+	}
+	trimmed := strings.TrimSpace(answers)
+	switch {
+	case trimmed == "":
+		return skip(item.lineAt(open), "essay questions are not supported, the player only offers answers to choose from")
+	case strings.HasPrefix(trimmed, "#"):
+		return skip(item.lineAt(open), "numerical questions are not supported, the player only offers answers to choose from")
+	}
+
+	// True/false: {T}, {FALSE}, with feedback for a wrong and a right answer, e.g. {T#wrong feedback#right feedback}
+	parts := splitUnescaped(trimmed, "#")
+	switch strings.ToUpper(strings.TrimSpace(parts[0])) {
+	case "T", "TRUE", "F", "FALSE":
+		right := strings.HasPrefix(strings.ToUpper(strings.TrimSpace(parts[0])), "T")
+		question.Type = SingleChoice
+		question.Answers = []Answer{{Text: "True", Correct: right}, {Text: "False", Correct: !right}}
+		wrongAnswer, rightAnswer := "False", "True"
+		if !right {
+			wrongAnswer, rightAnswer = rightAnswer, wrongAnswer
+		}
+		for i, feedback := range parts[1:] {
+			feedback = strings.TrimSpace(unescape(feedback))
+			switch {
+			case feedback == "":
+			case i == 0:
+				hints = append(hints, wrongAnswer+": "+feedback)
+			case i == 1:
+				hints = append(hints, rightAnswer+": "+feedback)
+			default:
+				note(item.lineAt(open), "true/false questions have feedback for a wrong and a right answer, %q is dropped", feedback)
+			}
+		}
+		question.HintExplanation = strings.Join(hints, "\n")
+		return &question, diagnostics
+	}
+
+	// Multiple choice: answers marked = are right and ~ wrong, or weighted with a percentage such as ~%50%
+	var markers []int
+	for i := 0; i < len(answers); i++ {
+		switch answers[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			markers = append(markers, i)
+		}
+	}
+	if len(markers) == 0 {
+		return skip(item.lineAt(open), "no answers marked with = or ~")
+	}
+	if strings.TrimSpace(answers[:markers[0]]) != "" {
+		return skip(item.lineAt(open), "text before the first answer, answers start with = or ~")
+	}
+
+	type weighted struct {
+		Answer
+		weight   float64
+		feedback string
+		line     int
+	}
+	var choices []weighted
+	wrong, full := 0, 0
+	for i, marker := range markers {
+		stop := len(answers)
+		if i+1 < len(markers) {
+			stop = markers[i+1]
+		}
+		line := item.lineAt(open + 1 + marker)
+		body := strings.TrimSpace(answers[marker+1 : stop])
+		if indexUnescaped(body, "->") >= 0 {
+			return skip(line, "matching questions are not supported, the player only offers answers to choose from")
+		}
+		choice := weighted{line: line}
+		if answers[marker] == '=' {
+			choice.weight = 100
+		} else {
+			wrong++
+		}
+		if strings.HasPrefix(body, "%") {
+			percent := strings.Index(body[1:], "%")
+			if percent < 0 {
+				return skip(line, "the weight %q is not closed with %%", body)
+			}
+			weight, err := strconv.ParseFloat(body[1:percent+1], 64)
+			if err != nil {
+				return skip(line, "the weight %q is not a percentage", body[:percent+2])
+			}
+			choice.weight = weight
+			body = body[percent+2:]
+		}
+		if hash := indexUnescaped(body, "#"); hash >= 0 {
+			choice.feedback = strings.TrimSpace(unescape(body[hash+1:]))
+			body = body[:hash]
+		}
+		answerText, _ := stripFormat(body)
+		choice.Text = strings.TrimSpace(unescape(answerText))
+		if choice.Text == "" {
+			return skip(line, "an answer has no text")
+		}
+		if choice.weight >= 100 {
+			full++
+		}
+		choices = append(choices, choice)
+	}
+	if wrong == 0 {
+		return skip(question.Line, "short answer questions are not supported, the player only offers answers to choose from")
+	}
+
+	// With a fully weighted answer one answer is right, partial credit for others cannot be kept. Without one,
+	// every positively weighted answer is part of the right combination.
+	correct := 0
+	for i := range choices {
+		choice := &choices[i]
+		if full > 0 {
+			choice.Correct = choice.weight >= 100
+			if choice.weight > 0 && choice.weight < 100 {
+				note(choice.line, "partial credit is not supported, %q (%g%%) counts as a wrong answer", choice.Text, choice.weight)
+			}
+		} else {
+			choice.Correct = choice.weight > 0
+		}
+		if choice.Correct {
+			correct++
+		}
+		question.Answers = append(question.Answers, choice.Answer)
+		if choice.feedback != "" {
+			hints = append(hints, choice.Text+": "+choice.feedback)
+		}
+	}
+	switch {
+	case correct == 0:
+		return skip(question.Line, "no answer is right, mark one with = or weight it with a positive percentage")
+	case len(question.Answers) < 2:
+		return skip(question.Line, "a question needs at least two answers")
+	case full > 1:
+		note(question.Line, "any of the %d answers marked = was right, now all of them have to be chosen", full)
+	}
+	question.Type = SingleChoice
+	if correct > 1 {
+		question.Type = MultipleChoice
+	}
+	question.HintExplanation = strings.Join(hints, "\n")
+	return &question, diagnostics
+}
+
+// stripFormat removes a [html], [moodle], [plain] or [markdown] marker from the start of s,
+// reporting whether it was [html]
+func stripFormat(s string) (string, bool) {
+	trimmed := strings.TrimSpace(s)
+	for _, marker := range []string{"[html]", "[moodle]", "[plain]", "[markdown]"} {
+		if strings.HasPrefix(trimmed, marker) {
+			return trimmed[len(marker):], marker == "[html]"
+		}
+	}
+	return s, false
+}
+
+// indexUnescaped returns the index of the first instance of sub in s not preceded by a backslash, or -1
+func indexUnescaped(s, sub string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitUnescaped splits s around the instances of sep not preceded by a backslash
+func splitUnescaped(s, sep string) []string {
+	var parts []string
+	for {
+		i := indexUnescaped(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+len(sep):]
+	}
+}
+
+// unescape resolves GIFT's escapes: \~ \= \# \{ \} \: \\ and \n for a new line
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch next := s[i+1]; next {
+			case '~', '=', '#', '{', '}', ':', '\\':
+				b.WriteByte(next)
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
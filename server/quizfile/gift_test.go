@@ -0,0 +1,174 @@
+package quizfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wantDiagnostic is a diagnostic expected at a line, its message containing the given text
+type wantDiagnostic struct {
+	line    int
+	skipped bool
+	message string
+}
+
+// checkParsed compares what a parser read with the questions and diagnostics expected
+func checkParsed(t *testing.T, questions []Question, diagnostics []Diagnostic, want []Question, wantDiagnostics []wantDiagnostic) {
+	t.Helper()
+	if !reflect.DeepEqual(questions, want) {
+		t.Errorf("questions =\n%+v\nwant\n%+v", questions, want)
+	}
+	if len(diagnostics) != len(wantDiagnostics) {
+		t.Fatalf("diagnostics = %v, want %d", diagnostics, len(wantDiagnostics))
+	}
+	for i, d := range diagnostics {
+		w := wantDiagnostics[i]
+		if d.Line != w.line || d.Skipped != w.skipped || !strings.Contains(d.Message, w.message) {
+			t.Errorf("diagnostic %d = %+v, want line %d skipped %v containing %q", i, d, w.line, w.skipped, w.message)
+		}
+	}
+}
+
+func TestParseGIFT(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		want        []Question
+		diagnostics []wantDiagnostic
+	}{
+		{
+			name: "categories",
+			text: "$CATEGORY: $course$/Geography/Capitals\n" +
+				"\n" +
+				"::Q1:: What is the capital of Portugal? {=Lisbon ~Porto ~Faro}\n" +
+				"$CATEGORY: Rivers\n" +
+				"Which river flows through Lisbon? {=Tagus ~Douro}\n",
+			want: []Question{
+				{Line: 3, Category: "$course$/Geography/Capitals", Text: "What is the capital of Portugal?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Lisbon", Correct: true}, {Text: "Porto"}, {Text: "Faro"}}},
+				{Line: 5, Category: "Rivers", Text: "Which river flows through Lisbon?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Tagus", Correct: true}, {Text: "Douro"}}},
+			},
+		},
+		{
+			name: "escapes",
+			text: `Is 1 \= 1 \~ \# \{ \} \: \\ true? {=Yes \= right ~No \~ wrong#it \# is}`,
+			want: []Question{
+				{Line: 1, Text: `Is 1 = 1 ~ # { } : \ true?`, Type: SingleChoice, Points: DefaultPoints, HintExplanation: "No ~ wrong: it # is",
+					Answers: []Answer{{Text: "Yes = right", Correct: true}, {Text: "No ~ wrong"}}},
+			},
+		},
+		{
+			name: "weighted answers",
+			text: "Which are capitals? {~%50%Lisbon ~%50%Bern ~%-100%Porto ####Porto is not a capital.}\n" +
+				"\n" +
+				"Which is the capital? {=Lisbon ~%50%Porto ~Faro}\n",
+			want: []Question{
+				{Line: 1, Text: "Which are capitals?", Type: MultipleChoice, Points: DefaultPoints, HintExplanation: "Porto is not a capital.",
+					Answers: []Answer{{Text: "Lisbon", Correct: true}, {Text: "Bern", Correct: true}, {Text: "Porto"}}},
+				{Line: 3, Text: "Which is the capital?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Lisbon", Correct: true}, {Text: "Porto"}, {Text: "Faro"}}},
+			},
+			diagnostics: []wantDiagnostic{{3, false, "partial credit is not supported"}},
+		},
+		{
+			name: "comments",
+			text: "// Geography, first term\n" +
+				"What is the capital\n" +
+				"// the question goes on\n" +
+				"of Portugal? {\n" +
+				"  =Lisbon // not a comment within the answers' line\n" +
+				"  // a comment among the answers\n" +
+				"  ~Porto\n" +
+				"}\n",
+			want: []Question{
+				{Line: 2, Text: "What is the capital\nof Portugal?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Lisbon // not a comment within the answers' line", Correct: true}, {Text: "Porto"}}},
+			},
+		},
+		{
+			name: "true false and missing word",
+			text: "Lisbon is the capital of Portugal. {T#Yes it is.#Right.}\n" +
+				"\n" +
+				"\n" +
+				"Porto is the capital of Portugal. {FALSE}\n" +
+				"\n" +
+				"The capital of Portugal is {=Lisbon ~Porto} on the Atlantic.\n",
+			want: []Question{
+				{Line: 1, Text: "Lisbon is the capital of Portugal.", Type: SingleChoice, Points: DefaultPoints, HintExplanation: "False: Yes it is.\nTrue: Right.",
+					Answers: []Answer{{Text: "True", Correct: true}, {Text: "False"}}},
+				{Line: 4, Text: "Porto is the capital of Portugal.", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "True"}, {Text: "False", Correct: true}}},
+				{Line: 6, Text: "The capital of Portugal is _____ on the Atlantic.", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Lisbon", Correct: true}, {Text: "Porto"}}},
+			},
+		},
+		{
+			name: "skipped questions point at their line",
+			text: "Describe Lisbon. {}\n" +
+				"\n" +
+				"How many bridges cross the Tagus in Lisbon? {#2}\n" +
+				"\n" +
+				"Match the cities. {\n" +
+				"  =Lisbon -> Portugal\n" +
+				"  =Bern -> Switzerland\n" +
+				"}\n" +
+				"\n" +
+				"Which is the capital? {\n" +
+				"  =Lisbon\n" +
+				"  ~\n" +
+				"}\n" +
+				"\n" +
+				"A description without answers.\n" +
+				"\n" +
+				"Which is the capital? {=Lisbon ~Porto\n" +
+				"\n" +
+				"Name the capital. {=Lisbon =Lisboa}\n",
+			diagnostics: []wantDiagnostic{
+				{1, true, "essay questions are not supported"},
+				{3, true, "numerical questions are not supported"},
+				{6, true, "matching questions are not supported"},
+				{12, true, "an answer has no text"},
+				{15, true, "descriptions are not questions"},
+				{17, true, "not closed with }"},
+				{19, true, "short answer questions are not supported"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions, diagnostics := ParseGIFT(tt.text)
+			checkParsed(t, questions, diagnostics, tt.want, tt.diagnostics)
+		})
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	tests := []struct {
+		format string
+		text   string
+	}{
+		{GIFT, "\ufeff// Exported on Windows\r\n$CATEGORY: Capitals\r\n\r\nWhat is the capital of Portugal? {\r\n=Lisbon\r\n~Porto\r\n}\r\n"},
+		{Aiken, "\ufeff\r\n\r\n\r\nWhat is the capital of Portugal?\r\nA. Lisbon\r\nB. Porto\r\nANSWER: A\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			questions, diagnostics, err := Parse(tt.format, strings.NewReader(tt.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(diagnostics) != 0 {
+				t.Errorf("diagnostics = %v, want none", diagnostics)
+			}
+			if len(questions) != 1 {
+				t.Fatalf("questions = %+v, want one", questions)
+			}
+			q := questions[0]
+			want := []Answer{{Text: "Lisbon", Correct: true}, {Text: "Porto"}}
+			if q.Line != 4 || q.Text != "What is the capital of Portugal?" || !reflect.DeepEqual(q.Answers, want) {
+				t.Errorf("question = %+v, want line 4 without carriage returns", q)
+			}
+		})
+	}
+}
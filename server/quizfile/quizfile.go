@@ -0,0 +1,104 @@
+// Package quizfile reads questions from the text formats other quiz tools and spreadsheets use, so existing
+// question banks can be imported. Questions that cannot be mapped to the question types of letsquiz are skipped
+// with a diagnostic pointing at their line. Quizzes are written back as spreadsheets by WriteSheet.
+package quizfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats of the files Parse reads
+const (
+	GIFT  = "gift"  // Moodle GIFT, see ParseGIFT
+	Aiken = "aiken" // Moodle Aiken, see ParseAiken
+	CSV   = "csv"   // spreadsheet saved as comma separated values, see ParseSheet
+	TSV   = "tsv"   // spreadsheet saved as tab separated values, see ParseSheet
+)
+
+// Question types, as used by the quiz editor
+const (
+	SingleChoice   = "single"
+	MultipleChoice = "multiple"
+)
+
+// DefaultPoints is the score of an imported question, GIFT and Aiken have no points
+const DefaultPoints = 1
+
+// Formats lists the formats Parse reads
+var Formats = []string{GIFT, Aiken, CSV, TSV}
+
+// ErrUnknownFormat is returned by Parse for formats it does not read
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Question is a question read from a file, with its answers
+type Question struct {
+	Line            int      `json:"line"`               // where the question starts in the file
+	Category        string   `json:"category,omitempty"` // category path the question was filed under, empty when none
+	Text            string   `json:"text"`
+	Type            string   `json:"type"`
+	HintExplanation string   `json:"hint_explanation,omitempty"` // the question's feedback
+	DifficultyLevel string   `json:"difficulty_level,omitempty"` // easy, medium or hard, only spreadsheets have one
+	Points          float64  `json:"points"`
+	Answers         []Answer `json:"answers"`
+}
+
+// Answer is an answer to a question read from a file
+type Answer struct {
+	Text    string `json:"text"`
+	Correct bool   `json:"correct"`
+}
+
+// Diagnostic reports something in a file that could not be mapped
+type Diagnostic struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped"` // the question was not imported, otherwise only part of it was lost
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+// Parse reads the questions of a file in the given format
+func Parse(format string, r io.Reader) ([]Question, []Diagnostic, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	text := strings.TrimPrefix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\ufeff")
+	switch strings.ToLower(format) {
+	case GIFT:
+		questions, diagnostics := ParseGIFT(text)
+		return questions, diagnostics, nil
+	case Aiken:
+		questions, diagnostics := ParseAiken(text)
+		return questions, diagnostics, nil
+	case CSV:
+		questions, diagnostics := ParseSheet(text, ',')
+		return questions, diagnostics, nil
+	case TSV:
+		questions, diagnostics := ParseSheet(text, '\t')
+		return questions, diagnostics, nil
+	}
+	return nil, nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownFormat, format, strings.Join(Formats, ", "))
+}
+
+// SplitCategory maps a category path such as "$course$/Geography/Capitals" to a category name and quiz
+// title, the first and last of its names. Moodle's context prefix is dropped.
+func SplitCategory(path string) (category, quiz string) {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		name = strings.TrimSpace(name)
+		if name == "" || (len(names) == 0 && strings.HasPrefix(name, "$") && strings.HasSuffix(name, "$")) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "", ""
+	}
+	return names[0], names[len(names)-1]
+}
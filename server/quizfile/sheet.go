@@ -0,0 +1,277 @@
+package quizfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Columns of a spreadsheet before its answers, in the order WriteSheet writes them. Answer columns are
+// numbered from 1, "answer 1", "answer 2" and so on, as many as the questions need.
+var sheetColumns = []string{"text", "type", "difficulty", "points", "hint", "correct"}
+
+// Difficulty levels, as offered by the quiz editor
+var difficultyLevels = []string{"easy", "medium", "hard"}
+
+// ParseSheet reads questions from a spreadsheet saved as CSV, comma ',', or TSV, comma '\t'. The first
+// row names the columns, in any order, and every other row is a question:
+//
+//	text,type,difficulty,points,hint,correct,answer 1,answer 2,answer 3
+//	What is the capital of Portugal?,single,easy,10,It is on the coast.,2,Porto,Lisbon,Faro
+//	Which are planets?,multiple,medium,20,,1;3,Mars,Moon,Venus
+//
+// Only text, correct and the answers are required. The type is single or multiple, by default single
+// with one correct answer and multiple with more. Difficulty is easy, medium or hard, and points default
+// to DefaultPoints. Correct lists the numbers of the right answers separated by semicolons, each once. A row that
+// does not validate is skipped with all of its errors, blank rows are ignored.
+func ParseSheet(text string, comma rune) ([]Question, []Diagnostic) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1 // trailing empty answer cells may be left out
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, []Diagnostic{{Line: 1, Message: "the file is empty, the first row names the columns", Skipped: true}}
+	} else if err != nil {
+		return nil, []Diagnostic{sheetError(err)}
+	}
+	columns, answerColumns, diagnostics := sheetHeader(header)
+	for _, required := range []string{"text", "correct"} {
+		if _, ok := columns[required]; !ok {
+			return nil, append(diagnostics, Diagnostic{Line: 1, Message: fmt.Sprintf("no %s column, the first row names the columns", required), Skipped: true})
+		}
+	}
+	if len(answerColumns) < 2 {
+		return nil, append(diagnostics, Diagnostic{Line: 1, Message: "fewer than two answer columns, name them answer 1, answer 2 and so on", Skipped: true})
+	}
+
+	var questions []Question
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// The reader cannot find the start of the next row reliably after a quoting error
+			diagnostics = append(diagnostics, sheetError(err))
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		question, errs := sheetQuestion(record, columns, answerColumns)
+		if len(errs) > 0 {
+			diagnostics = append(diagnostics, Diagnostic{Line: line, Message: strings.Join(errs, "; "), Skipped: true})
+			continue
+		}
+		question.Line = line
+		questions = append(questions, question)
+	}
+	return questions, diagnostics
+}
+
+// sheetHeader maps the names in the header row to their columns, and returns the answer columns in order
+func sheetHeader(header []string) (map[string]int, []int, []Diagnostic) {
+	var diagnostics []Diagnostic
+	columns := map[string]int{}
+	answers := map[int]int{}
+	for i, name := range header {
+		// "Answer_1", "answer 1" and "answer1" name the same column
+		key := strings.ToLower(strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '_' }), ""))
+		if rest, ok := strings.CutPrefix(key, "answer"); ok {
+			if n, err := strconv.Atoi(rest); err == nil && n > 0 {
+				if _, dup := answers[n]; dup {
+					diagnostics = append(diagnostics, Diagnostic{Line: 1, Message: fmt.Sprintf("the column %q appears twice, the first one is read", name)})
+				} else {
+					answers[n] = i
+				}
+				continue
+			}
+		}
+		known := false
+		for _, column := range sheetColumns {
+			known = known || key == column
+		}
+		if _, dup := columns[key]; !known || dup {
+			diagnostics = append(diagnostics, Diagnostic{Line: 1, Message: fmt.Sprintf("the column %q is ignored", name)})
+			continue
+		}
+		columns[key] = i
+	}
+
+	// Answers are numbered from 1 without gaps, answer 1 is offered first
+	var answerColumns []int
+	for n := 1; ; n++ {
+		i, ok := answers[n]
+		if !ok {
+			break
+		}
+		answerColumns = append(answerColumns, i)
+		delete(answers, n)
+	}
+	var ignored []int
+	for n := range answers {
+		ignored = append(ignored, n)
+	}
+	sort.Ints(ignored)
+	for _, n := range ignored {
+		diagnostics = append(diagnostics, Diagnostic{Line: 1, Message: fmt.Sprintf("the column for answer %d is ignored, there is no column for answer %d", n, len(answerColumns)+1)})
+	}
+	return columns, answerColumns, diagnostics
+}
+
+// sheetQuestion maps a row to a question, returning every reason it is invalid
+func sheetQuestion(record []string, columns map[string]int, answerColumns []int) (Question, []string) {
+	cell := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	get := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return cell(i)
+		}
+		return ""
+	}
+
+	var errs []string
+	question := Question{
+		Text:            get("text"),
+		Type:            strings.ToLower(get("type")),
+		DifficultyLevel: strings.ToLower(get("difficulty")),
+		HintExplanation: get("hint"),
+		Points:          DefaultPoints,
+	}
+	if question.Text == "" {
+		errs = append(errs, "the text is empty")
+	}
+	if question.Type != "" && question.Type != SingleChoice && question.Type != MultipleChoice {
+		errs = append(errs, fmt.Sprintf("the type %q is not %s or %s", get("type"), SingleChoice, MultipleChoice))
+	}
+	if question.DifficultyLevel != "" {
+		known := false
+		for _, level := range difficultyLevels {
+			known = known || question.DifficultyLevel == level
+		}
+		if !known {
+			errs = append(errs, fmt.Sprintf("the difficulty %q is not one of %s", get("difficulty"), strings.Join(difficultyLevels, ", ")))
+		}
+	}
+	if points := get("points"); points != "" {
+		value, err := strconv.ParseFloat(points, 64)
+		if err != nil || value < 0 {
+			errs = append(errs, fmt.Sprintf("the points %q are not a number of at least 0", points))
+		}
+		question.Points = value
+	}
+
+	// Answers are read up to the last one given, blank cells between them are mistakes
+	last := 0
+	for n, i := range answerColumns {
+		if cell(i) != "" {
+			last = n + 1
+		}
+	}
+	for n, i := range answerColumns[:last] {
+		if cell(i) == "" {
+			errs = append(errs, fmt.Sprintf("answer %d is empty", n+1))
+		}
+		question.Answers = append(question.Answers, Answer{Text: cell(i)})
+	}
+	if last < 2 {
+		errs = append(errs, "a question needs at least two answers")
+	}
+
+	correct, listed := 0, 0
+	for _, marker := range strings.Split(get("correct"), ";") {
+		marker = strings.TrimSpace(marker)
+		if marker == "" {
+			continue
+		}
+		listed++
+		n, err := strconv.Atoi(marker)
+		switch {
+		case err != nil || n < 1:
+			errs = append(errs, fmt.Sprintf("correct %q is not an answer number", marker))
+		case n > len(question.Answers):
+			errs = append(errs, fmt.Sprintf("correct %d has no answer", n))
+		case question.Answers[n-1].Correct:
+			errs = append(errs, fmt.Sprintf("correct %d is listed twice", n))
+		default:
+			question.Answers[n-1].Correct = true
+			correct++
+		}
+	}
+	switch {
+	case listed == 0:
+		// An empty cell, or only separators such as ";"
+		errs = append(errs, "no correct answer, list the right answers' numbers in correct")
+	case question.Type == SingleChoice && correct > 1:
+		errs = append(errs, fmt.Sprintf("a single choice question has one correct answer, not %d", correct))
+	}
+	if question.Type == "" {
+		question.Type = SingleChoice
+		if correct > 1 {
+			question.Type = MultipleChoice
+		}
+	}
+	return question, errs
+}
+
+// sheetError reports a row the CSV reader could not read, which ends the import
+func sheetError(err error) Diagnostic {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Diagnostic{Line: parseErr.Line, Message: fmt.Sprintf("%v, the rest of the file is not read", parseErr.Err), Skipped: true}
+	}
+	return Diagnostic{Line: 1, Message: err.Error(), Skipped: true}
+}
+
+// WriteSheet writes questions as a spreadsheet in the layout ParseSheet reads, CSV with comma ',' or
+// TSV with comma '\t', with as many answer columns as the question with the most answers
+func WriteSheet(w io.Writer, comma rune, questions []Question) error {
+	answers := 2
+	for _, question := range questions {
+		if len(question.Answers) > answers {
+			answers = len(question.Answers)
+		}
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	header := append([]string{}, sheetColumns...)
+	for n := 1; n <= answers; n++ {
+		header = append(header, fmt.Sprintf("answer %d", n))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, question := range questions {
+		var correct []string
+		for n, answer := range question.Answers {
+			if answer.Correct {
+				correct = append(correct, strconv.Itoa(n+1))
+			}
+		}
+		record := []string{
+			question.Text,
+			question.Type,
+			question.DifficultyLevel,
+			strconv.FormatFloat(question.Points, 'f', -1, 64),
+			question.HintExplanation,
+			strings.Join(correct, ";"),
+		}
+		for _, answer := range question.Answers {
+			record = append(record, answer.Text)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,122 @@
+package quizfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSheet(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		comma       rune
+		want        []Question
+		diagnostics []wantDiagnostic
+	}{
+		{
+			name: "questions",
+			text: "text,type,difficulty,points,hint,correct,answer 1,answer 2,answer 3\n" +
+				"What is the capital of Portugal?,single,easy,10,It is on the coast.,2,Porto,Lisbon,Faro\n" +
+				"\n" +
+				"Which are planets?,,medium,,,1;3,Mars,Moon,Venus\n" +
+				"\"Which river flows through\nLisbon?\",,,2.5,,1,Tagus,Douro\n",
+			comma: ',',
+			want: []Question{
+				{Line: 2, Text: "What is the capital of Portugal?", Type: SingleChoice, DifficultyLevel: "easy", Points: 10, HintExplanation: "It is on the coast.",
+					Answers: []Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}, {Text: "Faro"}}},
+				{Line: 4, Text: "Which are planets?", Type: MultipleChoice, DifficultyLevel: "medium", Points: DefaultPoints,
+					Answers: []Answer{{Text: "Mars", Correct: true}, {Text: "Moon"}, {Text: "Venus", Correct: true}}},
+				{Line: 5, Text: "Which river flows through\nLisbon?", Type: SingleChoice, Points: 2.5,
+					Answers: []Answer{{Text: "Tagus", Correct: true}, {Text: "Douro"}}},
+			},
+		},
+		{
+			name:  "columns in any order, tab separated",
+			text:  "Answer_2\tcorrect\tText\tanswer1\tnotes\n" + "Lisbon\t2\tWhat is the capital of Portugal?\tPorto\tchecked\n",
+			comma: '\t',
+			want: []Question{
+				{Line: 2, Text: "What is the capital of Portugal?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}}},
+			},
+			diagnostics: []wantDiagnostic{{1, false, `the column "notes" is ignored`}},
+		},
+		{
+			name: "invalid rows",
+			text: "text,type,difficulty,points,hint,correct,answer 1,answer 2,answer 3\n" +
+				"Q,,easy,2,,;,a,b\n" +
+				"Q,,,,,,a,b\n" +
+				"Q,,,,,0,a,b\n" +
+				"Q,,,,,4,a,b,c\n" +
+				"Q,,,,,x,a,b\n" +
+				"Q,,,,,1;1,a,b\n" +
+				",,,,,1,a,b\n" +
+				"Q,single,,,,1;2,a,b\n" +
+				"Q,essay,hardest,-1,,1,a,,c\n" +
+				"Q,,,,,1,a\n" +
+				"What is the capital of Portugal?,,,,,2,Porto,Lisbon\n",
+			comma: ',',
+			want: []Question{
+				{Line: 12, Text: "What is the capital of Portugal?", Type: SingleChoice, Points: DefaultPoints,
+					Answers: []Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}}},
+			},
+			diagnostics: []wantDiagnostic{
+				{2, true, "no correct answer"},
+				{3, true, "no correct answer"},
+				{4, true, `correct "0" is not an answer number`},
+				{5, true, "correct 4 has no answer"},
+				{6, true, `correct "x" is not an answer number`},
+				{7, true, "correct 1 is listed twice"},
+				{8, true, "the text is empty"},
+				{9, true, "a single choice question has one correct answer, not 2"},
+				{10, true, `the type "essay" is not single or multiple; the difficulty "hardest" is not one of easy, medium, hard; the points "-1" are not a number of at least 0; answer 2 is empty`},
+				{11, true, "a question needs at least two answers"},
+			},
+		},
+		{
+			name:        "no correct column",
+			text:        "text,answer 1,answer 2\nQ,a,b\n",
+			comma:       ',',
+			diagnostics: []wantDiagnostic{{1, true, "no correct column"}},
+		},
+		{
+			name:        "unclosed quote",
+			text:        "text,correct,answer 1,answer 2\nQ,1,a,b\n\"Q,1,a,b\n",
+			comma:       ',',
+			want:        []Question{{Line: 2, Text: "Q", Type: SingleChoice, Points: DefaultPoints, Answers: []Answer{{Text: "a", Correct: true}, {Text: "b"}}}},
+			diagnostics: []wantDiagnostic{{3, true, "the rest of the file is not read"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions, diagnostics := ParseSheet(tt.text, tt.comma)
+			checkParsed(t, questions, diagnostics, tt.want, tt.diagnostics)
+		})
+	}
+}
+
+func TestWriteSheetReadsBack(t *testing.T) {
+	questions := []Question{
+		{Text: "What is the capital of Portugal?", Type: SingleChoice, DifficultyLevel: "easy", Points: 10, HintExplanation: "It is on the coast, by the Tagus.",
+			Answers: []Answer{{Text: "Porto"}, {Text: "Lisbon", Correct: true}, {Text: "Faro"}}},
+		{Text: "Which are planets?\nPick all of them.", Type: MultipleChoice, DifficultyLevel: "medium", Points: 0.5,
+			Answers: []Answer{{Text: "Mars", Correct: true}, {Text: "Moon"}, {Text: "Venus", Correct: true}, {Text: `"Pluto"`}}},
+		{Text: "Is Lisbon the capital?", Type: SingleChoice, Points: DefaultPoints,
+			Answers: []Answer{{Text: "Yes", Correct: true}, {Text: "No"}}},
+	}
+	for _, comma := range []rune{',', '\t'} {
+		var sheet strings.Builder
+		if err := WriteSheet(&sheet, comma, questions); err != nil {
+			t.Fatal(err)
+		}
+		read, diagnostics := ParseSheet(sheet.String(), comma)
+		if len(diagnostics) != 0 {
+			t.Errorf("diagnostics reading back %q = %v, want none", comma, diagnostics)
+		}
+		want := append([]Question(nil), questions...)
+		want[0].Line, want[1].Line, want[2].Line = 2, 3, 5 // the second question's text spans two lines
+		if !reflect.DeepEqual(read, want) {
+			t.Errorf("read back with %q =\n%+v\nwant\n%+v", comma, read, want)
+		}
+	}
+}
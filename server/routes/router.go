@@ -1,14 +1,24 @@
 package routes
 
 import (
+	"letsquiz/server/metrics"
+	"letsquiz/tracing"
 	"net/http"
+	"reflect"
+	"runtime"
 	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("letsquiz/server/routes")
+
 type Route struct {
 	Method  string
 	Pattern string
 	Handler http.HandlerFunc
+	name    string // handler function name, used as the span name
 }
 
 type Router struct {
@@ -20,7 +30,8 @@ func NewRouter() *Router {
 }
 
 func (r *Router) Handle(method, pattern string, handler http.HandlerFunc) {
-	r.routes = append(r.routes, Route{Method: method, Pattern: pattern, Handler: handler})
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	r.routes = append(r.routes, Route{Method: method, Pattern: pattern, Handler: handler, name: name})
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
@@ -30,7 +41,13 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 		if match, params := matchPattern(route.Pattern, req.URL.Path); match {
 			req = setParams(req, params)
-			route.Handler(w, req)
+			metrics.SetRoute(req, route.Pattern)
+
+			// Name the server span after the route pattern and trace the handler in a child span
+			trace.SpanFromContext(req.Context()).SetName(req.Method + " " + route.Pattern)
+			ctx, span := tracer.Start(req.Context(), route.name, trace.WithAttributes(semconv.HTTPRoute(route.Pattern)))
+			route.Handler(w, req.WithContext(ctx))
+			span.End()
 			return
 		}
 	}
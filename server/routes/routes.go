@@ -19,20 +19,24 @@ func RegisterRoutes(router *Router) {
 
 	router.Handle("GET", "/quizzes", controllers.GetQuizzes)
 	router.Handle("POST", "/quizzes", controllers.CreateQuiz)
+	router.Handle("POST", "/quizzes/import", controllers.ImportQuizzes)
 	router.Handle("GET", "/quizzes/{id}", controllers.GetQuizByID)
 	router.Handle("PUT", "/quizzes/{id}", controllers.UpdateQuiz)
 	router.Handle("GET", "/quizzes/{id}/questions", controllers.GetQuestionsByQuizID) // Added route to fetch questions by quiz ID
+	router.Handle("GET", "/quizzes/{id}/export", controllers.ExportQuiz)
 
 	router.Handle("GET", "/questions", controllers.GetQuestions)
 	router.Handle("POST", "/questions", controllers.CreateQuestion)
 	router.Handle("GET", "/questions/{id}", controllers.GetQuestionByID)
 	router.Handle("PUT", "/questions/{id}", controllers.UpdateQuestion)
+	router.Handle("DELETE", "/questions/{id}", controllers.DeleteQuestion)
 	router.Handle("GET", "/questions/{id}/answers", controllers.GetAnswersByQuestionID) // Added route to fetch answers by question ID
 
 	router.Handle("GET", "/answers", controllers.GetAnswers)
 	router.Handle("POST", "/answers", controllers.CreateAnswer)
 	router.Handle("GET", "/answers/{id}", controllers.GetAnswerByID)
 	router.Handle("PUT", "/answers/{id}", controllers.UpdateAnswer)
+	router.Handle("DELETE", "/answers/{id}", controllers.DeleteAnswer)
 
 	router.Handle("GET", "/attempts", controllers.GetUserQuizAttempts)
 	router.Handle("POST", "/attempts", controllers.CreateUserQuizAttempt)
@@ -49,6 +53,13 @@ func RegisterRoutes(router *Router) {
 	router.Handle("GET", "/leaderboards/{id}", controllers.GetLeaderboardByID)
 	router.Handle("PUT", "/leaderboards/{id}", controllers.UpdateLeaderboard)
 
+	router.Handle("POST", "/media", controllers.UploadMedia)
+	router.Handle("GET", "/media/{id}", controllers.GetMedia)
+
+	router.Handle("GET", "/packs", controllers.GetPacks)
+	router.Handle("POST", "/packs", controllers.InstallPack)
+	router.Handle("DELETE", "/packs/{name}", controllers.UninstallPack)
+
 	router.Handle("GET", "/feedbacks", controllers.GetFeedbacks)
 	router.Handle("POST", "/feedbacks", controllers.CreateFeedback)
 	router.Handle("GET", "/feedbacks/{id}", controllers.GetFeedbackByID)
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "media")
+	s, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "sha256/ab/ab12"
+	if exists, err := s.Exists(ctx, key); exists || err != nil {
+		t.Errorf("Exists() before Put = %v, %v, want false", exists, err)
+	}
+	if _, err := s.Open(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Open() before Put = %v, want fs.ErrNotExist", err)
+	}
+
+	for _, content := range []string{"first", "replaced"} {
+		if err := s.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+			t.Fatalf("Put(%q) = %v", content, err)
+		}
+		object, err := s.Open(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Objects are served with range requests, so reading from an offset must work
+		object.Seek(1, io.SeekStart)
+		data, _ := io.ReadAll(object)
+		object.Close()
+		if string(data) != content[1:] || object.ModTime().IsZero() {
+			t.Errorf("object from offset 1 = %q modified %v, want %q", data, object.ModTime(), content[1:])
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sha256", "ab", "ab12")); err != nil {
+		t.Errorf("object not kept below the directory by its key: %v", err)
+	}
+	if exists, err := s.Exists(ctx, key); !exists || err != nil {
+		t.Errorf("Exists() after Put = %v, %v, want true", exists, err)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := s.Exists(ctx, key); exists {
+		t.Errorf("Exists() after Delete = true")
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalPutIncomplete(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "clip", strings.NewReader("short"), 10, "audio/ogg"); err == nil {
+		t.Errorf("Put() of 5 of 10 bytes = nil, want an error")
+	}
+	if exists, _ := s.Exists(ctx, "clip"); exists {
+		t.Errorf("an incomplete object is stored")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left %d files behind, want the temporary file removed", len(entries))
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"letsquiz/config"
+	"time"
+)
+
+// Object is a stored file opened for reading. It is seekable so it can be served with range requests.
+type Object interface {
+	io.ReadSeekCloser
+	ModTime() time.Time
+}
+
+// Storage keeps uploaded media files by key, e.g. "sha256/ab/ab12..."
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any object stored there
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object stored under key, the error wraps fs.ErrNotExist when there is none
+	Open(ctx context.Context, key string) (Object, error)
+	// Exists reports whether an object is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the object stored under key, deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Media stores uploaded media, set up by Connect
+var Media Storage
+
+// Connect sets up Media with the backend selected by media_storage
+func Connect() error {
+	var err error
+	switch config.DbConfig.MediaStorage {
+	case config.MediaStorageS3:
+		Media, err = NewS3(S3Options{
+			Endpoint:        config.DbConfig.S3Endpoint,
+			Region:          config.DbConfig.S3Region,
+			Bucket:          config.DbConfig.S3Bucket,
+			AccessKeyID:     config.DbConfig.S3AccessKeyID,
+			SecretAccessKey: config.DbConfig.S3SecretAccessKey,
+			PathStyle:       config.DbConfig.S3PathStyle,
+		})
+	case config.MediaStorageLocal:
+		Media, err = NewLocal(config.DbConfig.MediaDir)
+	default:
+		err = fmt.Errorf("unsupported media storage %q", config.DbConfig.MediaStorage)
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package termimage
+
+// terminalCellSize returns the usual cell size, the terminal cannot be asked on this platform
+func terminalCellSize() cellSize {
+	return defaultCellSize
+}
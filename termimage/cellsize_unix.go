@@ -0,0 +1,18 @@
+//go:build unix
+
+package termimage
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminalCellSize asks the terminal for its size in pixels, which not every terminal reports
+func terminalCellSize() cellSize {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return defaultCellSize
+	}
+	return cellSize{width: int(ws.Xpixel) / int(ws.Col), height: int(ws.Ypixel) / int(ws.Row)}
+}
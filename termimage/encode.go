@@ -0,0 +1,177 @@
+package termimage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// kittyChunkSize is the largest payload the kitty protocol accepts in one escape sequence
+const kittyChunkSize = 4096
+
+// kitty transmits img as PNG and displays it over cols by rows cells without moving the cursor
+func kitty(img image.Image, id, cols, rows int) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	payload := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if id < 1 {
+		id = 1
+	}
+
+	var out strings.Builder
+	for first := true; first || payload != ""; first = false {
+		chunk := payload
+		if len(chunk) > kittyChunkSize {
+			chunk = chunk[:kittyChunkSize]
+		}
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&out, "\x1b_Ga=T,f=100,t=d,q=2,C=1,i=%d,p=1,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&out, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return out.String()
+}
+
+// iterm2 sends img as an inline file, scaled down first as the terminal would otherwise receive it in full
+func iterm2(img image.Image, cols, rows int, cell cellSize) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scale(img, cols*cell.width, rows*cell.height)); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		buf.Len(), cols, rows, base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// sixel encodes img scaled to width by height pixels as a sixel image, dithered to a fixed 256 color palette
+func sixel(img image.Image, width, height int) string {
+	scaled := scale(img, width, height)
+	paletted := image.NewPaletted(scaled.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, image.Point{})
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\x1bPq\"1;1;%d;%d", width, height)
+	for i, c := range paletted.Palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+
+	// Each band covers six rows of pixels, drawn once per color used in it
+	row := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		used := make(map[uint8]bool)
+		for y := top; y < top+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				used[paletted.ColorIndexAt(x, y)] = true
+			}
+		}
+		first := true
+		for index := 0; index < len(paletted.Palette); index++ {
+			if !used[uint8(index)] {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if paletted.ColorIndexAt(x, top+dy) == uint8(index) {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+			}
+			if !first {
+				out.WriteByte('$') // Return to the start of the band to draw the next color
+			}
+			first = false
+			out.WriteString("#" + strconv.Itoa(index))
+			writeRunLength(&out, row)
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\x1b\\")
+	return out.String()
+}
+
+// writeRunLength writes sixel data, compressing repeated characters
+func writeRunLength(out *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			out.WriteString("!" + strconv.Itoa(n))
+			out.WriteByte(row[i])
+		} else {
+			out.WriteString(string(row[i:j]))
+		}
+		i = j
+	}
+}
+
+// halfBlocks approximates img with upper half blocks, each cell showing two pixels with its foreground and background colors
+func halfBlocks(img image.Image, cols, rows int) string {
+	scaled := scale(img, cols, rows*2)
+	lines := make([]string, rows)
+	for y := 0; y < rows; y++ {
+		var line strings.Builder
+		for x := 0; x < cols; x++ {
+			line.WriteString(lipgloss.NewStyle().
+				Foreground(hexColor(scaled.RGBAAt(x, y*2))).
+				Background(hexColor(scaled.RGBAAt(x, y*2+1))).
+				Render("▀"))
+		}
+		lines[y] = line.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func hexColor(c color.RGBA) lipgloss.Color {
+	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+}
+
+// scale resizes img to width by height pixels, averaging the pixels each one covers.
+// Transparent pixels are blended over black, as terminals have no notion of transparency.
+func scale(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := img.At(sx, sy).RGBA() // premultiplied, so transparency darkens
+					r, g, b, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), n+1
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xff})
+		}
+	}
+	return out
+}
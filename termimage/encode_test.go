@@ -0,0 +1,265 @@
+package termimage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/png"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var (
+	red  = color.RGBA{R: 0xff, A: 0xff}
+	blue = color.RGBA{B: 0xff, A: 0xff}
+)
+
+// halves is a width by height image, red on the left half and blue on the right
+func halves(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.SetRGBA(x, y, red)
+			} else {
+				img.SetRGBA(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// noise is a width by height image of random pixels, which PNG cannot compress
+func noise(width, height int) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	rng.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+// decodePNG decodes a base64 PNG payload
+func decodePNG(t *testing.T, payload string) image.Image {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatalf("payload is not base64: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("payload is not a PNG: %v", err)
+	}
+	return img
+}
+
+// samePixels reports whether two images have the same size and colors
+func samePixels(a, b image.Image) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			ar, ag, ab, aa := a.At(a.Bounds().Min.X+x, a.Bounds().Min.Y+y).RGBA()
+			br, bg, bb, ba := b.At(b.Bounds().Min.X+x, b.Bounds().Min.Y+y).RGBA()
+			if ar != br || ag != bg || ab != bb || aa != ba {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestFit(t *testing.T) {
+	cell := cellSize{width: 10, height: 20}
+	tests := []struct {
+		name               string
+		width, height      int
+		cols, maxRows      int
+		wantCols, wantRows int
+	}{
+		{"square", 100, 100, 10, 10, 10, 5},
+		{"wide", 400, 100, 20, 10, 20, 3},
+		{"tall, limited by rows", 100, 400, 20, 10, 5, 10},
+		{"tiny", 1, 1000, 20, 2, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, rows := fit(image.Rect(0, 0, tt.width, tt.height), tt.cols, tt.maxRows, cell)
+			if cols != tt.wantCols || rows != tt.wantRows {
+				t.Errorf("fit() = %d by %d cells, want %d by %d", cols, rows, tt.wantCols, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestKitty(t *testing.T) {
+	img := halves(4, 2)
+	out := kitty(img, 3, 4, 2)
+	prefix := "\x1b_Ga=T,f=100,t=d,q=2,C=1,i=3,p=1,c=4,r=2,m=0;"
+	if !strings.HasPrefix(out, prefix) || !strings.HasSuffix(out, "\x1b\\") {
+		t.Fatalf("kitty() = %q, want one sequence starting %q", out, prefix)
+	}
+	if got := decodePNG(t, strings.TrimSuffix(strings.TrimPrefix(out, prefix), "\x1b\\")); !samePixels(got, img) {
+		t.Errorf("kitty() transmits another image")
+	}
+}
+
+func TestKittyChunks(t *testing.T) {
+	img := noise(64, 64)
+	out := kitty(img, 0, 8, 4)
+	sequences := regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\").FindAllStringSubmatch(out, -1)
+	if len(sequences) < 2 || strings.Join(flatten(sequences), "") != out {
+		t.Fatalf("kitty() = %d sequences, want the image in several chunks and nothing else", len(sequences))
+	}
+	var payload strings.Builder
+	for i, s := range sequences {
+		want := "m=1"
+		if i == len(sequences)-1 {
+			want = "m=0"
+		}
+		if i == 0 {
+			want = "a=T,f=100,t=d,q=2,C=1,i=1,p=1,c=8,r=4," + want
+		}
+		if s[1] != want {
+			t.Errorf("chunk %d control data = %q, want %q", i, s[1], want)
+		}
+		if len(s[2]) > kittyChunkSize {
+			t.Errorf("chunk %d carries %d bytes, want at most %d", i, len(s[2]), kittyChunkSize)
+		}
+		payload.WriteString(s[2])
+	}
+	if !samePixels(decodePNG(t, payload.String()), img) {
+		t.Errorf("chunks put together are another image")
+	}
+}
+
+func flatten(matches [][]string) []string {
+	all := make([]string, len(matches))
+	for i, m := range matches {
+		all[i] = m[0]
+	}
+	return all
+}
+
+func TestITerm2(t *testing.T) {
+	cell := cellSize{width: 2, height: 4}
+	out := iterm2(halves(40, 20), 3, 2, cell)
+	m := regexp.MustCompile("^\x1b]1337;File=inline=1;size=([0-9]+);width=3;height=2;preserveAspectRatio=1:([A-Za-z0-9+/=]+)\a$").FindStringSubmatch(out)
+	if m == nil {
+		t.Fatalf("iterm2() = %q, want an inline file of 3 by 2 cells", out)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(m[2]); strconv.Itoa(len(data)) != m[1] {
+		t.Errorf("size = %s, want the %d bytes sent", m[1], len(data))
+	}
+	// The image is scaled down to the pixels of the cells it covers
+	if got, want := decodePNG(t, m[2]), halves(6, 8); !samePixels(got, want) {
+		t.Errorf("iterm2() sends a %v image, want 6 by 8 pixels of red and blue halves", got.Bounds())
+	}
+}
+
+func TestSixel(t *testing.T) {
+	out := sixel(halves(2, 6), 2, 6)
+	r, b := color.Palette(palette.Plan9).Index(red), color.Palette(palette.Plan9).Index(blue)
+	var header strings.Builder
+	header.WriteString("\x1bPq\"1;1;2;6")
+	for i, c := range palette.Plan9 {
+		cr, cg, cb, _ := c.RGBA()
+		fmt.Fprintf(&header, "#%d;2;%d;%d;%d", i, cr*100/0xffff, cg*100/0xffff, cb*100/0xffff)
+	}
+	// One band of six rows, each color drawn over the columns it fills
+	band := fmt.Sprintf("#%d~?$#%d?~-", r, b)
+	if b < r {
+		band = fmt.Sprintf("#%d?~$#%d~?-", b, r)
+	}
+	if want := header.String() + band + "\x1b\\"; out != want {
+		t.Errorf("sixel() ends %q, want %q", out[len(header.String()):], want[len(header.String()):])
+	}
+
+	// Images taller than a band are drawn in several, the last one partial
+	if bands := strings.Count(sixel(halves(2, 8), 2, 8), "-"); bands != 2 {
+		t.Errorf("an image 8 pixels tall is drawn in %d bands, want 2", bands)
+	}
+}
+
+func TestWriteRunLength(t *testing.T) {
+	tests := []struct{ row, want string }{
+		{"~~~~~~?", "!6~?"},
+		{"~~~?", "~~~?"},
+		{"?~?~", "?~?~"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		writeRunLength(&out, []byte(tt.row))
+		if out.String() != tt.want {
+			t.Errorf("writeRunLength(%q) = %q, want %q", tt.row, out.String(), tt.want)
+		}
+	}
+}
+
+func TestHalfBlocks(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(profile)
+
+	// The top row is red, the bottom one blue, so each cell is red over blue
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.SetRGBA(0, 0, red)
+	img.SetRGBA(1, 0, red)
+	img.SetRGBA(0, 1, blue)
+	img.SetRGBA(1, 1, blue)
+	out := halfBlocks(img, 2, 1)
+	cell := lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Background(lipgloss.Color("#0000ff")).Render("▀")
+	if out != cell+cell {
+		t.Errorf("halfBlocks() = %q, want two cells %q", out, cell)
+	}
+	if !strings.Contains(cell, "38;2;255;0;0") || !strings.Contains(cell, "48;2;0;0;255") {
+		t.Errorf("cell = %q, want a red foreground over a blue background", cell)
+	}
+
+	lines := strings.Split(halfBlocks(halves(8, 8), 4, 2), "\n")
+	if len(lines) != 2 || lipgloss.Width(lines[0]) != 4 || lipgloss.Width(lines[1]) != 4 {
+		t.Errorf("halfBlocks() of 4 by 2 cells = %q", lines)
+	}
+}
+
+func TestRender(t *testing.T) {
+	img := halves(100, 100)
+	for _, protocol := range []string{ProtocolKitty, ProtocolITerm2, ProtocolSixel, ProtocolHalfBlocks} {
+		t.Run(protocol, func(t *testing.T) {
+			lines := strings.Split(Render(img, protocol, 1, 10, 3), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("Render() = %d lines, want 3", len(lines))
+			}
+			for i, line := range lines {
+				if protocol == ProtocolHalfBlocks {
+					if w := lipgloss.Width(line); w != 6 {
+						t.Errorf("line %d is %d cells wide, want 6", i, w)
+					}
+				} else if blank := strings.Repeat(" ", 6); !strings.HasSuffix(line, blank) || (i < 2 && line != blank) {
+					t.Errorf("line %d = %q, want 6 blank cells, the image drawn from the last line", i, line)
+				}
+			}
+			if protocol != ProtocolHalfBlocks && (!strings.HasPrefix(lines[2], "\x1b7\x1b[2A") || !strings.HasSuffix(lines[2], "\x1b8"+strings.Repeat(" ", 6))) {
+				t.Errorf("last line = %q, want the image drawn from the first line with the cursor saved and restored", lines[2])
+			}
+		})
+	}
+	if out := Render(img, ProtocolNone, 1, 10, 3); out != "" {
+		t.Errorf("Render() with %q = %q, want nothing", ProtocolNone, out)
+	}
+	if out := Render(nil, ProtocolKitty, 1, 10, 3); out != "" {
+		t.Errorf("Render() of no image = %q, want nothing", out)
+	}
+}
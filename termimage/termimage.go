@@ -0,0 +1,140 @@
+// Package termimage renders images inline in the terminal, with the kitty, iTerm2 or sixel graphics
+// protocols where the terminal supports them and with colored half blocks everywhere else.
+package termimage
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"  // Register the GIF decoder
+	_ "image/jpeg" // Register the JPEG decoder
+	_ "image/png"  // Register the PNG decoder
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Supported values for the image_protocol setting
+const (
+	ProtocolAuto       = "auto"
+	ProtocolKitty      = "kitty"
+	ProtocolITerm2     = "iterm2"
+	ProtocolSixel      = "sixel"
+	ProtocolHalfBlocks = "halfblocks"
+	ProtocolNone       = "none" // images are not shown
+)
+
+// Protocols lists every value accepted for the image_protocol setting
+var Protocols = []string{ProtocolAuto, ProtocolKitty, ProtocolITerm2, ProtocolSixel, ProtocolHalfBlocks, ProtocolNone}
+
+// Resolve returns the protocol to use for a configured value, detecting it for ProtocolAuto
+func Resolve(configured string) string {
+	if configured == "" || configured == ProtocolAuto {
+		return Detect()
+	}
+	return configured
+}
+
+// Detect picks the best protocol the terminal supports, judging by the environment it sets.
+// Terminal multiplexers get half blocks, as they do not pass graphics through reliably.
+func Detect() string {
+	return detect(os.Getenv)
+}
+
+func detect(getenv func(string) string) string {
+	term := getenv("TERM")
+	switch {
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen"):
+		return ProtocolHalfBlocks
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty":
+		return ProtocolKitty
+	}
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "mintty":
+		return ProtocolITerm2
+	case "ghostty":
+		return ProtocolKitty
+	}
+	if getenv("KONSOLE_VERSION") != "" || strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel") {
+		return ProtocolSixel
+	}
+	return ProtocolHalfBlocks
+}
+
+// Decode reads a PNG, JPEG or GIF image
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Render draws img with protocol in at most cols columns and maxRows rows, keeping its aspect ratio.
+// The result is a block of lines of equal width, so it can be laid out like text. id identifies the
+// image to protocols that keep images on screen, drawing another image with the same id replaces it.
+func Render(img image.Image, protocol string, id, cols, maxRows int) string {
+	if img == nil || cols < 1 || maxRows < 1 || img.Bounds().Empty() || protocol == ProtocolNone {
+		return ""
+	}
+	cell := terminalCellSize()
+	cols, rows := fit(img.Bounds(), cols, maxRows, cell)
+	switch protocol {
+	case ProtocolKitty:
+		return place(kitty(img, id, cols, rows), cols, rows)
+	case ProtocolITerm2:
+		return place(iterm2(img, cols, rows, cell), cols, rows)
+	case ProtocolSixel:
+		return place(sixel(img, cols*cell.width, rows*cell.height), cols, rows)
+	default:
+		return halfBlocks(img, cols, rows)
+	}
+}
+
+// Clear returns the sequence removing the images drawn by Render, for protocols whose images stay on
+// screen until they are deleted. Other protocols draw into the cells, which are erased by new text.
+func Clear(protocol string) string {
+	if protocol == ProtocolKitty {
+		return "\x1b_Ga=d,d=A,q=2\x1b\\"
+	}
+	return ""
+}
+
+// cellSize is the size of a terminal cell in pixels
+type cellSize struct {
+	width, height int
+}
+
+// defaultCellSize is assumed when the terminal does not report its size in pixels
+var defaultCellSize = cellSize{width: 10, height: 20}
+
+// fit scales an image of the given bounds to at most cols by maxRows cells, keeping its aspect ratio
+func fit(bounds image.Rectangle, cols, maxRows int, cell cellSize) (int, int) {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	rows := int(math.Ceil(float64(cols*cell.width) * h / w / float64(cell.height)))
+	if rows > maxRows {
+		cols = int(math.Round(float64(maxRows*cell.height) * w / h / float64(cell.width)))
+		rows = maxRows
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	return cols, rows
+}
+
+// place reserves cols by rows blank cells for an image drawn by a graphics protocol sequence.
+// The image is drawn from the last line, moving the cursor up to the first, so that painting the
+// blank lines above it cannot erase it. The cursor is restored afterwards, wherever the protocol left it.
+func place(sequence string, cols, rows int) string {
+	blank := strings.Repeat(" ", cols)
+	lines := make([]string, rows)
+	for i := range lines {
+		lines[i] = blank
+	}
+	up := ""
+	if rows > 1 {
+		up = "\x1b[" + strconv.Itoa(rows-1) + "A"
+	}
+	lines[rows-1] = "\x1b7" + up + sequence + "\x1b8" + blank
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,42 @@
+package termimage
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"kitty window", map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, ProtocolKitty},
+		{"kitty TERM", map[string]string{"TERM": "xterm-kitty"}, ProtocolKitty},
+		{"ghostty TERM", map[string]string{"TERM": "xterm-ghostty"}, ProtocolKitty},
+		{"ghostty TERM_PROGRAM", map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "ghostty"}, ProtocolKitty},
+		{"iTerm2", map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "iTerm.app"}, ProtocolITerm2},
+		{"WezTerm", map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, ProtocolITerm2},
+		{"mintty", map[string]string{"TERM": "xterm", "TERM_PROGRAM": "mintty"}, ProtocolITerm2},
+		{"Konsole", map[string]string{"TERM": "xterm-256color", "KONSOLE_VERSION": "230804"}, ProtocolSixel},
+		{"foot", map[string]string{"TERM": "foot-extra"}, ProtocolSixel},
+		{"mlterm", map[string]string{"TERM": "mlterm"}, ProtocolSixel},
+		{"sixel TERM", map[string]string{"TERM": "xterm-sixel"}, ProtocolSixel},
+		{"tmux in kitty", map[string]string{"TERM": "tmux-256color", "TMUX": "/tmp/tmux-1000/default,1,0", "KITTY_WINDOW_ID": "1"}, ProtocolHalfBlocks},
+		{"screen in iTerm2", map[string]string{"TERM": "screen-256color", "TERM_PROGRAM": "iTerm.app"}, ProtocolHalfBlocks},
+		{"plain xterm", map[string]string{"TERM": "xterm-256color"}, ProtocolHalfBlocks},
+		{"empty environment", map[string]string{}, ProtocolHalfBlocks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detect(func(name string) string { return tt.env[name] }); got != tt.want {
+				t.Errorf("detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	for _, protocol := range Protocols[1:] {
+		if got := Resolve(protocol); got != protocol {
+			t.Errorf("Resolve(%q) = %q, want it kept", protocol, got)
+		}
+	}
+}
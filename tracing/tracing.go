@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported values for the trace exporter setting
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// Options configures the tracer provider
+type Options struct {
+	ServiceName  string
+	Exporter     string // one of the Exporter* constants
+	File         string // output file when Exporter is "file"
+	OTLPEndpoint string // collector URL, e.g. http://localhost:4318; selects OTLP when Exporter is empty
+}
+
+// ShutdownFunc flushes pending spans and releases exporter resources
+type ShutdownFunc func(context.Context) error
+
+// Init installs the global tracer provider and the W3C trace context propagator.
+// With no exporter configured only the propagator is installed, so incoming
+// trace context is still forwarded but no spans are recorded.
+func Init(opts Options) (ShutdownFunc, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporterName := opts.Exporter
+	if exporterName == ExporterNone && opts.OTLPEndpoint != "" {
+		exporterName = ExporterOTLP
+	}
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+	switch exporterName {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		if opts.File == "" {
+			return nil, fmt.Errorf("trace exporter %q requires a trace file", ExporterFile)
+		}
+		file, fileErr := os.OpenFile(opts.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if fileErr != nil {
+			return nil, fileErr
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case ExporterOTLP:
+		if opts.OTLPEndpoint == "" {
+			return nil, fmt.Errorf("trace exporter %q requires an OTLP endpoint", ExporterOTLP)
+		}
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(opts.OTLPEndpoint))
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %q", exporterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns a named tracer from the global tracer provider
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
@@ -3,9 +3,13 @@ package views
 import (
 	"github.com/charmbracelet/lipgloss"
 	"letsquiz/logger"
+	"letsquiz/markdown"
 	"letsquiz/models"
+	"letsquiz/outbox"
 )
 
+var previewTitle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#04B575"))
+
 // ViewDynamicQuizForm renders the form for the current quiz question
 func ViewDynamicQuizForm(m models.DynamicQuizModel) string {
 	logger.Info("Rendering Dynamic Quiz Form View", "CurrentFormGroup", m.CurrentFormGroup)
@@ -14,8 +18,52 @@ func ViewDynamicQuizForm(m models.DynamicQuizModel) string {
 	formView := m.QuestionForms[m.CurrentFormGroup].View()
 	logger.Info("Form view rendered", "CurrentFormGroup", m.CurrentFormGroup)
 
+	// Show the question as it will be rendered in the player beside or below the form
+	_, previewWidth, sideBySide := m.PreviewLayout()
+	previewHeight := m.WindowHeight - 16 // Keep long previews inside the boundary
+	if !sideBySide {
+		previewHeight /= 3
+	}
+	preview := lipgloss.NewStyle().
+		Width(previewWidth).
+		MaxHeight(previewHeight).
+		Align(lipgloss.Left).
+		Render(previewTitle.Render("Preview") + "\n\n" + markdown.Render(m.PreviewMarkdown(), previewWidth))
+	if sideBySide {
+		formView = lipgloss.JoinHorizontal(lipgloss.Top, formView, "  ", preview)
+	} else {
+		formView = lipgloss.JoinVertical(lipgloss.Left, formView, "", preview)
+	}
+
+	// Ask before restoring a draft, saving answer changes or deleting a question, in place of the form
+	footerMessage := "Press Ctrl+Right Arrow to proceed, Ctrl+Left Arrow to go back, Esc to quit.\n" +
+		"Alt+Shift+N adds a question after this one, Alt+Shift+I before it, Alt+Shift+D duplicates it, " +
+		"Alt+Shift+X deletes it and Alt+Shift+Left/Right Arrow moves it."
+	prompt := ""
+	switch {
+	case m.PendingRestore != "":
+		prompt = m.PendingRestore
+		footerMessage = "Press y to keep editing the draft and save it, d to discard it and load the saved quiz."
+	case m.PendingDelete != "":
+		prompt = m.PendingDelete
+		footerMessage = "Press y to delete this question, n to keep it."
+	case m.PendingSave != "":
+		prompt = m.PendingSave
+		footerMessage = "Press y to save these changes and proceed, n to keep editing."
+	case m.DraftUnsynced:
+		footerMessage = "Not saved to the backend yet, the draft is kept and saved once the backend is reachable.\n" + footerMessage
+	}
+	if pending := outbox.StatusHint(); pending != "" && prompt == "" {
+		footerMessage += "\n" + pending
+	}
+	if prompt != "" {
+		formView = lipgloss.NewStyle().
+			Width(m.WindowWidth - 24).
+			Align(lipgloss.Left).
+			Render(markdown.Render(prompt, m.WindowWidth-24))
+	}
+
 	// Add footer message
-	footerMessage := "Press Ctrl+Right Arrow to proceed, Ctrl+Left Arrow to go back, Esc to quit."
 	footerStyle := lipgloss.NewStyle().
 		Width(m.WindowWidth - 20). // Adjust width for boundary
 		Render(footerMessage)
@@ -7,6 +7,7 @@ import (
 	"letsquiz/logger"
 	"letsquiz/models"
 	"os"
+	"strings"
 )
 
 const (
@@ -49,27 +50,35 @@ func ViewEditQuestionnaire(m models.EditQuestionnaireModel) string {
 	// Apply the styles to the table
 	m.Table.SetStyles(style)
 
-	// Render the button with the appropriate focus style
-	var createButtonView string
-	if m.Focused == "button" {
-		focusedStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("229")).
-			Background(lipgloss.Color("57")).
-			Bold(true).
-			Render
-		createButtonView = focusedStyle(fmt.Sprintf("[ %s ]", m.Buttons[0].Label))
-	} else {
-		normalStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render
-		createButtonView = normalStyle(fmt.Sprintf("[ %s ]", m.Buttons[0].Label))
+	// Render the buttons, the one under the cursor highlighted while the buttons are focused
+	focusedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(true).
+		Render
+	normalStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Render
+	var buttonViews []string
+	for i, button := range m.Buttons {
+		if m.Focused == "button" && i == m.Cursor {
+			buttonViews = append(buttonViews, focusedStyle(fmt.Sprintf("[ %s ]", button.Label)))
+		} else {
+			buttonViews = append(buttonViews, normalStyle(fmt.Sprintf("[ %s ]", button.Label)))
+		}
 	}
 
-	// Render the button above the table
-	view := "\n" + createButtonView + "\n\n" + m.Table.View()
+	// Render the buttons above the table
+	view := "\n" + strings.Join(buttonViews, "  ") + "\n\n" + m.Table.View()
+	if m.Focused == "table" {
+		view += "\n" + normalStyle("enter edit • x export as CSV • X export as TSV")
+	}
+	if m.Status != "" {
+		view += "\n" + m.Status
+	}
 
 	// Add footer message
-	footerMessage := "Press esc to quit, alt+end to mute/unmute."
+	footerMessage := footerHint()
 	footerStyle := lipgloss.NewStyle().
 		Align(lipgloss.Right).
 		Width(m.WindowWidth - 10). // Adjusted width for boundary
@@ -0,0 +1,96 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"letsquiz/logger"
+	"letsquiz/models"
+)
+
+// ViewImportQuiz renders the import form, the preview of an import, or the quizzes an import created with the
+// lines it could not map
+func ViewImportQuiz(m models.ImportQuizModel) string {
+	logger.Info("Rendering Import Quiz View", "importing", m.Importing)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#04B575"))
+	skippedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87"))
+	noteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+
+	header := headerStyle.Render("Import questions from Moodle GIFT, Aiken or a CSV/TSV spreadsheet")
+	var body strings.Builder
+	footerMessage := "Press esc to return to the quiz list."
+	switch {
+	case m.Importing:
+		body.WriteString("Importing...")
+	case m.Err != nil:
+		body.WriteString(skippedStyle.Render(m.Err.Error()))
+		footerMessage = "Press enter to import another file, esc to return to the quiz list."
+	case m.Result != nil:
+		fmt.Fprintf(&body, "Imported %d questions into %d quizzes, skipped %d.\n", m.Result.Imported, len(m.Result.Quizzes), m.Result.Skipped)
+		for _, quiz := range m.Result.Quizzes {
+			fmt.Fprintf(&body, "  %s (%s): %d questions\n", quiz.Title, quiz.Category, quiz.Questions)
+		}
+		if len(m.Result.Diagnostics) > 0 {
+			body.WriteString("\nLines that could not be mapped:\n")
+		}
+		// Keep the diagnostics within the window boundary
+		shown := len(m.Result.Diagnostics)
+		if room := m.WindowHeight - 24 - len(m.Result.Quizzes); shown > room {
+			shown = room
+			if shown < 1 {
+				shown = 1
+			}
+		}
+		for _, diagnostic := range m.Result.Diagnostics[:shown] {
+			line := fmt.Sprintf("  line %d: %s", diagnostic.Line, diagnostic.Message)
+			if diagnostic.Skipped {
+				body.WriteString(skippedStyle.Render(line+" (skipped)") + "\n")
+			} else {
+				body.WriteString(noteStyle.Render(line) + "\n")
+			}
+		}
+		if more := len(m.Result.Diagnostics) - shown; more > 0 {
+			fmt.Fprintf(&body, "  ... and %d more, see the log\n", more)
+		}
+		footerMessage = "Press enter to import another file, esc to return to the quiz list."
+	case m.Preview != nil:
+		fmt.Fprintf(&body, "%d questions can be imported into %d quizzes, %d will be skipped:\n", m.Preview.Imported, len(m.Preview.Quizzes), m.Preview.Skipped)
+		for _, quiz := range m.Preview.Quizzes {
+			fmt.Fprintf(&body, "  %s (%s): %d questions\n", quiz.Title, quiz.Category, quiz.Questions)
+		}
+		body.WriteString("\n" + m.Table.View())
+		footerMessage = "Press enter to import, esc to choose another file. Nothing is imported yet."
+		if m.Preview.Imported == 0 {
+			footerMessage = "Nothing can be imported, fix the rows in the file. Press esc to choose another file."
+		}
+	default:
+		body.WriteString(m.Form.View())
+		footerMessage = "Press esc to return to the quiz list."
+	}
+
+	footerStyle := lipgloss.NewStyle().
+		Width(m.WindowWidth - 20). // Adjusted width for boundary
+		Render(footerMessage + "\n" + footerHint())
+
+	content := lipgloss.JoinVertical(lipgloss.Top, header, "", body.String(), "", footerStyle)
+
+	// Create the window boundary
+	windowBoundary := lipgloss.NewStyle().
+		Width(m.WindowWidth-20).   // Adjusted width for the outer boundary
+		Height(m.WindowHeight-10). // Adjusted height for the outer boundary
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("#04B575")).
+		Padding(1, 1, 1, 1). // Adding padding to ensure the content is within the boundary
+		Margin(1, 1, 1, 1).  // Adding margin to ensure the boundary doesn't exceed the window size
+		Align(lipgloss.Left).
+		Render(content)
+
+	// Render the final view with the window boundary
+	return lipgloss.NewStyle().
+		Width(m.WindowWidth).
+		Height(m.WindowHeight).
+		Align(lipgloss.Center).
+		Render(windowBoundary)
+}
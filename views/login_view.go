@@ -83,7 +83,7 @@ func ViewLogin(m common.Model) string {
 		bannerStyle,
 		lipgloss.NewStyle().Height(1).Render(""),
 		buttonRowStyle,
-		lipgloss.NewStyle().Width(m.WindowWidth-4).Align(lipgloss.Center).Render("Press esc to quit, alt+end to mute/unmute."),
+		lipgloss.NewStyle().Width(m.WindowWidth-4).Align(lipgloss.Center).Render(footerHint()),
 	)
 
 	windowBoundary := lipgloss.NewStyle().
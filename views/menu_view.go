@@ -76,7 +76,7 @@ func ViewMenu(m common.Model) string {
 	logger.Info("Button column rendered")
 
 	// Add footer message
-	footerMessage := "Press esc to quit, alt+end to mute/unmute."
+	footerMessage := footerHint()
 	footerStyle := lipgloss.NewStyle().
 		Align(lipgloss.Right).
 		Width(m.WindowWidth - 8). // Adjusted width for boundary
@@ -0,0 +1,75 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"letsquiz/logger"
+	"letsquiz/models"
+)
+
+// ViewPendingOperations renders the changes waiting for the backend and those it rejected, with the selected one's last error
+func ViewPendingOperations(m models.PendingOperationsModel) string {
+	logger.Info("Rendering Pending Operations View", "pending", len(m.Operations), "cursor", m.Cursor)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#04B575"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87"))
+
+	header := headerStyle.Render("Changes waiting for the backend")
+	var body strings.Builder
+	footerMessage := "Press up/down to select a change, r to retry now, d to discard it, esc to return to the menu."
+	if len(m.Operations) == 0 {
+		body.WriteString("All changes have been sent to the backend.")
+		footerMessage = "Press esc to return to the menu."
+	}
+	for i, op := range m.Operations {
+		line := fmt.Sprintf("%s  %s %s", op.QueuedAt.Local().Format("Jan 2 15:04:05"), op.Method, op.Description)
+		if !op.RejectedAt.IsZero() {
+			line += "  (rejected by the backend)"
+		} else if op.Attempts > 0 {
+			line += fmt.Sprintf("  (%d attempts)", op.Attempts)
+		}
+		if i == m.Cursor {
+			body.WriteString(selectedStyle.Render("> "+line) + "\n")
+			if op.LastError != "" {
+				body.WriteString(errorStyle.Render("    "+op.LastError) + "\n")
+			}
+		} else {
+			body.WriteString("  " + line + "\n")
+		}
+	}
+	if m.PendingDiscard != "" {
+		footerMessage = "Discard this change? It is never sent, changes after it that depend on it fail. Press y to discard it, n to keep it."
+		for _, op := range m.Operations {
+			if op.Key == m.PendingDiscard && !op.RejectedAt.IsZero() {
+				footerMessage = "Discard this rejected change? The backend will not take it as it is. Press y to discard it, n to keep it."
+			}
+		}
+	}
+
+	footerStyle := lipgloss.NewStyle().
+		Width(m.WindowWidth - 20). // Adjusted width for boundary
+		Render(footerMessage + "\n" + footerHint())
+
+	content := lipgloss.JoinVertical(lipgloss.Top, header, "", body.String(), "", footerStyle)
+
+	// Create the window boundary
+	windowBoundary := lipgloss.NewStyle().
+		Width(m.WindowWidth-20).   // Adjusted width for the outer boundary
+		Height(m.WindowHeight-10). // Adjusted height for the outer boundary
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("#04B575")).
+		Padding(1, 1, 1, 1). // Adding padding to ensure the content is within the boundary
+		Margin(1, 1, 1, 1).  // Adding margin to ensure the boundary doesn't exceed the window size
+		Align(lipgloss.Left).
+		Render(content)
+
+	// Render the final view with the window boundary
+	return lipgloss.NewStyle().
+		Width(m.WindowWidth).
+		Height(m.WindowHeight).
+		Align(lipgloss.Center).
+		Render(windowBoundary)
+}
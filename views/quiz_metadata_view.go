@@ -14,7 +14,7 @@ func ViewQuizMetadata(m models.QuizMetadataModel) string {
 	formView := m.Form.View()
 
 	// Add footer message
-	footerMessage := "Press esc to quit, alt+end to mute/unmute."
+	footerMessage := footerHint()
 
 	footerStyle := lipgloss.NewStyle().
 		Width(m.WindowWidth - 20). // Adjusted width for boundary
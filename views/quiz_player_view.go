@@ -0,0 +1,102 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"letsquiz/logger"
+	"letsquiz/models"
+	"letsquiz/music"
+	"letsquiz/termimage"
+)
+
+// ViewQuizPlayer renders the quiz selection, the current question, its feedback or the final score
+func ViewQuizPlayer(m models.QuizPlayerModel) string {
+	logger.Info("Rendering Quiz Player View", "phase", m.Phase)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#04B575"))
+	var header, body string
+	switch {
+	case m.Err != nil:
+		body = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Render("Cannot start a quiz: " + m.Err.Error())
+	case m.Phase == models.PlayerLoading:
+		body = "Loading..."
+	case m.Phase == models.PlayerSelect:
+		body = m.Form.View()
+	default:
+		header = headerStyle.Render(fmt.Sprintf("%s   Score: %d/%d%s", m.Quiz.Title, m.Score, m.MaxScore, timeLeft(m)))
+		switch m.Phase {
+		case models.PlayerQuestion:
+			body = m.Form.View()
+			if clip := clipHint(m); clip != "" {
+				body = clip + "\n\n" + body
+			}
+			body = m.QuestionView + "\n\n" + body
+			if m.ImageView != "" {
+				body = m.ImageView + "\n\n" + body
+			}
+		case models.PlayerFeedback:
+			body = m.Feedback + "\n\nPress enter to continue."
+		case models.PlayerFinished:
+			body = m.Feedback + "\n\nPress enter to return to the menu."
+		}
+	}
+
+	// Images drawn by a graphics protocol stay on screen until they are removed
+	if m.Phase != models.PlayerQuestion {
+		body = termimage.Clear(m.ImageProtocol) + body
+	}
+
+	// Add footer message
+	footerStyle := lipgloss.NewStyle().
+		Width(m.WindowWidth - 20). // Adjusted width for boundary
+		Render(footerHint())
+
+	content := lipgloss.JoinVertical(lipgloss.Top, header, "", body, "", footerStyle)
+
+	// Create the window boundary
+	windowBoundary := lipgloss.NewStyle().
+		Width(m.WindowWidth-20).   // Adjusted width for the outer boundary
+		Height(m.WindowHeight-10). // Adjusted height for the outer boundary
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("#04B575")).
+		Padding(1, 1, 1, 1). // Adding padding to ensure the content is within the boundary
+		Margin(1, 1, 1, 1).  // Adding margin to ensure the boundary doesn't exceed the window size
+		Align(lipgloss.Center).
+		Render(content)
+
+	// Render the final view with the window boundary
+	return lipgloss.NewStyle().
+		Width(m.WindowWidth).
+		Height(m.WindowHeight).
+		Align(lipgloss.Center).
+		Render(windowBoundary)
+}
+
+// timeLeft formats the remaining time of a timed quiz
+func timeLeft(m models.QuizPlayerModel) string {
+	if m.Deadline.IsZero() || m.Phase == models.PlayerFinished {
+		return ""
+	}
+	remaining := int(m.Remaining.Seconds())
+	return fmt.Sprintf("   Time left: %02d:%02d", remaining/60, remaining%60)
+}
+
+// clipHint tells the player how to play the current question's audio clip
+func clipHint(m models.QuizPlayerModel) string {
+	if m.Questions[m.Current].Question.AudioMediaID == nil {
+		return ""
+	}
+	switch left := m.ClipPlaysLeft(); {
+	case m.ClipStatus != "":
+		return m.ClipStatus
+	case music.ClipPlaying():
+		return "♪ Playing the audio clip..."
+	case left == 0:
+		return "♪ The audio clip cannot be played again."
+	case left < 0:
+		return fmt.Sprintf("♪ Press %s to play the audio clip.", models.KeyPlayClip)
+	default:
+		return fmt.Sprintf("♪ Press %s to play the audio clip (%d plays left).", models.KeyPlayClip, left)
+	}
+}
@@ -0,0 +1,89 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"letsquiz/logger"
+	"letsquiz/models"
+)
+
+// ViewSetup renders the quiz packs with their sample and installed versions, and the outcome of the last change
+func ViewSetup(m models.SetupModel) string {
+	logger.Info("Rendering Setup View", "packs", len(m.Packs), "cursor", m.Cursor)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#04B575"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	descriptionStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87"))
+
+	header := headerStyle.Render("Setup: quiz packs")
+	var body strings.Builder
+	if len(m.Packs) == 0 && !m.Busy && m.Err == nil {
+		body.WriteString("No quiz packs are shipped with the backend or installed.\n")
+	}
+	for i, pack := range m.Packs {
+		var versions []string
+		if pack.SampleVersion != "" {
+			versions = append(versions, "sample "+pack.SampleVersion)
+		}
+		if pack.InstalledVersion != "" {
+			versions = append(versions, "installed "+pack.InstalledVersion)
+		} else {
+			versions = append(versions, "not installed")
+		}
+		line := fmt.Sprintf("%-20s %-30s %s", pack.Name, pack.Title, strings.Join(versions, ", "))
+		if i == m.Cursor {
+			body.WriteString(selectedStyle.Render("> "+line) + "\n")
+			if pack.Description != "" {
+				body.WriteString(descriptionStyle.Render("    "+pack.Description) + "\n")
+			}
+		} else {
+			body.WriteString("  " + line + "\n")
+		}
+	}
+	if m.EnteringPath {
+		body.WriteString("\n" + m.PathInput.View() + "\n")
+	}
+	if m.Status != "" {
+		body.WriteString("\n" + m.Status + "\n")
+	}
+	if m.Err != nil {
+		body.WriteString("\n" + errorStyle.Render(m.Err.Error()) + "\n")
+	}
+
+	footerMessage := "Press up/down to select a pack, enter to install or update the sample, u to uninstall it, f to install a pack from a file, r to refresh, esc to return to the menu."
+	switch {
+	case m.EnteringPath:
+		footerMessage = "Enter the path of a pack directory, zip archive or pack.json and press enter to install it, esc to cancel."
+	case m.PendingUninstall != "":
+		footerMessage = fmt.Sprintf("Uninstall %s? Its quizzes are deleted with their questions and answers. Press y to uninstall it, n to keep it.", m.PendingUninstall)
+	case m.Busy:
+		footerMessage = "Working..."
+	}
+
+	footerStyle := lipgloss.NewStyle().
+		Width(m.WindowWidth - 20). // Adjusted width for boundary
+		Render(footerMessage + "\n" + footerHint())
+
+	content := lipgloss.JoinVertical(lipgloss.Top, header, "", body.String(), "", footerStyle)
+
+	// Create the window boundary
+	windowBoundary := lipgloss.NewStyle().
+		Width(m.WindowWidth-20).   // Adjusted width for the outer boundary
+		Height(m.WindowHeight-10). // Adjusted height for the outer boundary
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("#04B575")).
+		Padding(1, 1, 1, 1). // Adding padding to ensure the content is within the boundary
+		Margin(1, 1, 1, 1).  // Adding margin to ensure the boundary doesn't exceed the window size
+		Align(lipgloss.Left).
+		Render(content)
+
+	// Render the final view with the window boundary
+	return lipgloss.NewStyle().
+		Width(m.WindowWidth).
+		Height(m.WindowHeight).
+		Align(lipgloss.Center).
+		Render(windowBoundary)
+}
@@ -0,0 +1,15 @@
+package views
+
+import (
+	"letsquiz/music"
+	"letsquiz/outbox"
+)
+
+// footerHint is the status bar shown at the bottom of the screens: the music keys, and the changes
+// waiting for the backend when there are any
+func footerHint() string {
+	if pending := outbox.StatusHint(); pending != "" {
+		return music.FooterHint() + " " + pending
+	}
+	return music.FooterHint()
+}